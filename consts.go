@@ -1,21 +1,14 @@
 package main
 
-import "github.com/gdamore/tcell"
+import "time"
 
 const (
 	// Game parameters
-	TickRate      = 30 // Milliseconds between ticks
-	MaxIterations = 1000
-	// Map characters
-	MapPlayer     = 'p'
-	MapSquirrel   = 's'
-	MapWaterLight = 'w'
-	MapWaterHeavy = 'W'
-	MapWall       = '#'
-	MapFire       = 'f'
-	// Growth chances (per game tick)
-	GrowthChanceSeed    = 0.010 // Seed to sapling
-	GrowthChanceSapling = 0.005 // Sapling to adult
+	TickRate            = 30 // Default milliseconds between ticks
+	DefaultVisionRadius = 100
+	MaxIterations       = 1000
+	// Growth chances (per game tick), and other rules for the normal difficulty
+	GrowthChanceSeed    = 0.010 // Seed to sapling, and sapling to adult
 	FireSpawnChance     = 0.005 // Chance per update for fire to randomly spawn on an available tile
 	FireSpreadChance    = 0.100 // Chance per update for each fire to spread to a random adjacent tile
 	FireBurnoutHalflife = 200   // The age at which the chance (but not cumulative chance) for fire to burn out becomes 50%
@@ -24,6 +17,34 @@ const (
 	MaxHitPointsSquirrel = 1
 	DamageFire           = 1
 	FireWeightedDistance = 20
+	// Fire animation
+	FireAnimationPhases = 2 // Number of animation frames, i.e. fire symbol keys
+	FireAnimationPeriod = 4 // Game update ticks per animation frame
+	// HUD
+	HudSideWidth          = 24 // Width of the side panel, including borders
+	HudBottomHeight       = 5  // Height of the bottom panel, including borders
+	HudSideMinScreenWidth = 80 // Screens narrower than this get a bottom panel instead of a side panel
+	HudBarWidth           = 10 // Width of the hitpoint bar
+	HudBottomBarWidth     = 5  // Width of the hitpoint bar in the bottom panel, which has less room per column
+	// Autopilot
+	AutopilotFireDistance  = 3   // Steps away fire has to come for the autopilot to deal with it
+	AutopilotRetreatHealth = 0.5 // Share of maximum hitpoints at or below which the autopilot runs from fire instead of fighting it
+	// Local multiplayer
+	MaxLocalPlayers = 2 // Players that can share a terminal, one per set of keys
+	SplitScreenGap  = 1 // Columns between the views of a split screen
+	// Network play
+	NetMaxPlayers        = 8  // Players a server takes at once
+	NetMaxNameLength     = 8  // Longer player names are cut short, to fit the HUD
+	NetActionQueue       = 4  // Actions a player can send ahead of the ticks that perform them
+	NetSendBuffer        = 64 // Messages waiting to be written to a client, beyond which it is dropped as too slow
+	NetReconnectAttempts = 5  // Times a client tries to reconnect after losing the connection, before giving up
+	// Spectators
+	SpectatorPanStep       = 3  // Tiles the free camera moves per key press
+	SpectatorMinimapWidth  = 24 // Largest size of the minimap, which shrinks the world to fit
+	SpectatorMinimapHeight = 10
+	// Statistics
+	ForestCoverSampleRate = 10 // Game update ticks between samples of forest cover
+	SparklineWidth        = 40 // Maximum width of the forest cover graph on the results screen
 	// Actors
 	ActorPlayer = iota
 	ActorSquirrel
@@ -49,6 +70,7 @@ const (
 	KeyFireType2
 	KeyBurnt
 	KeyFirebreak
+	KeyPlayer2
 	// Directions
 	DirUp
 	DirRight
@@ -78,13 +100,192 @@ const (
 	// Title menu states
 	MainMenuPageOrder
 	NewGamePageOrder
-	// DifficultyPageOrder
+	ControlsPageOrder
+	HighScoresPageOrder
+	DifficultyPageOrder
+	CustomDifficultyPageOrder
+	PlayersPageOrder
+	// Pause menu pages
+	PausePageMain
+	PausePageSettings
+	PausePageControls
+	// Reasons for the game ending
+	EndCauseNone
+	EndCauseQuit
+	EndCauseBurned
+	// Results screen choices
+	ResultsRetry
+	ResultsPickMap
+	ResultsQuit
+	// HUD layouts
+	HudLayoutSide
+	HudLayoutBottom
+	// Player actions
+	ActionNone
+	ActionMoveUp
+	ActionMoveRight
+	ActionMoveDown
+	ActionMoveLeft
+	ActionChopUp
+	ActionChopRight
+	ActionChopDown
+	ActionChopLeft
+	ActionChopOmni
+	ActionDigUp
+	ActionDigRight
+	ActionDigDown
+	ActionDigLeft
+	ActionDigOmni
+	ActionAutopilot
+	ActionHint
+	ActionScreenshot
+	// Actions of player two, in the same order as those of player one
+	ActionPlayer2MoveUp
+	ActionPlayer2MoveRight
+	ActionPlayer2MoveDown
+	ActionPlayer2MoveLeft
+	ActionPlayer2ChopUp
+	ActionPlayer2ChopRight
+	ActionPlayer2ChopDown
+	ActionPlayer2ChopLeft
+	ActionPlayer2ChopOmni
+	ActionPlayer2DigUp
+	ActionPlayer2DigRight
+	ActionPlayer2DigDown
+	ActionPlayer2DigLeft
+	ActionPlayer2DigOmni
+	// Options on the players page of the title menu
+	PlayersOptionMode
+	PlayersOptionCamera
+)
+
+const (
+	// Control presets
+	PresetDefault = "default"
+	PresetVi      = "vi"
+	PresetNumpad  = "numpad"
+	// Files
+	ConfigDirName     = "skogshuggare"
+	ControlsFileName  = "controls.json"
+	SettingsFileName  = "settings.json"
+	MapsDirName       = "kartor" // Directory of the built-in maps, and of the user's maps in the user config directory
+	SaveFileName      = "save.json"
+	HighScoreFileName = "highscores.json"
+	TilesFileName     = "tiles.json"
+	// Maps
+	MapFileExtension  = ".karta"
+	MapMetadataPrefix = ";" // Lines at the top of a map file starting with this are metadata
+	MapMaxWidth       = 400 // Largest map read, in tiles, so that a stray long line doesn't build a huge world
+	MapMaxHeight      = 200
+	MapSourceUser     = "user"
+	MapSourceBuiltin  = "built-in"
+	MapPreviewMargin  = 2 // Columns between the map list and the preview, and between the preview and the screen edge
+	// High scores
+	HighScoreLimit       = 10               // Number of scores kept per map and game mode
+	HighScoreLockTimeout = 5 * time.Second  // How long to wait for another instance to finish writing high scores
+	HighScoreLockStale   = 30 * time.Second // Lock files older than this were left behind by a crashed instance
+	// Title menu
+	TitleMenuAnimationPeriod = 100 * time.Millisecond // Time between frames of the header animation
+	TitleMenuDemoDelay       = 30 * time.Second       // Time the main menu waits for input before the autopilot plays a demo
+	// Game modes
+	GameModeClassic = "classic"
+	GameModeCoop    = "coop"   // Several players on one terminal, scoring together until all of them burn
+	GameModeVersus  = "versus" // Several players on one terminal, until only one of them is left standing
+	// Cameras for several players
+	CameraSplit  = "split"  // A view for each player, side by side
+	CameraShared = "shared" // One view fitting every player, who can't walk out of it
+	CameraOwn    = "own"    // Only the player's own view, for network games where every player has a screen
+	// Difficulties
+	DifficultyEasy   = "easy"
+	DifficultyNormal = "normal"
+	DifficultyHard   = "hard"
+	DifficultyCustom = "custom"
+	// Settings
+	VisionRadiusStep = 5 // How much the vision radius changes per step in the settings menu
+	// Simulation
+	SimCommand      = "sim"  // Subcommand for running games without a screen
+	SimDefaultTicks = 2000   // Ticks each simulated game runs for, unless the player burns first
+	SimDefaultSeeds = 10     // Seeds each map and parameter set is simulated with
+	SimWait         = "Wait" // Script step for doing nothing for a tick
+	SimFormatCSV    = "csv"
+	SimFormatJSON   = "json"
+	// Network play
+	ServeCommand         = "serve"    // Subcommand for running a game that clients join over the network
+	JoinCommand          = "join"     // Subcommand for joining a served game
+	SpectateCommand      = "spectate" // Subcommand for watching a served game
+	ServeDefaultAddress  = ":7777"
+	ServeDefaultTickRate = 200 // Milliseconds between ticks of a served game, which keeps time instead of waiting for input
+	NetWriteTimeout      = 5 * time.Second
+	NetReconnectDelay    = time.Second
+	NetCommandJoin       = "join"
+	NetCommandAction     = "action"
+	NetCommandLeave      = "leave"
+	NetCommandSpectate   = "spectate"
+	NetUnixPrefix        = "unix:" // Addresses starting with this are paths of Unix sockets instead of TCP addresses
+	// Web
+	WebDefaultHost       = "127.0.0.1" // Host listened on for an address without one, so that only this computer can play
+	WebMaxCommandBytes   = 4096        // Larger messages from a page are refused, since no command is that long
+	WebReadHeaderTimeout = 10 * time.Second
+	// Recordings and screenshots
+	CastVersion          = 2               // Version of the asciinema cast format recorded
+	CastIdleTimeLimit    = 2.0             // Seconds that longer pauses, e.g. in the title menu between games, are shortened to when played back
+	ScreenshotFilePrefix = "skogshuggare-" // Followed by the time the screenshot was taken
+	ScreenshotTimeFormat = "20060102-150405"
+	ScreenshotAnsiExt    = ".ans"
+	ScreenshotHtmlExt    = ".html"
+	ScreenshotForeground = "#cccccc" // Colours of the HTML page where the terminal's default colours are drawn
+	ScreenshotBackground = "#000000"
+	// SSH
+	SshCommand         = "ssh" // Subcommand for serving the game to SSH clients
+	SshDefaultAddress  = ":2222"
+	SshHostKeyFileName = "ssh_host_ed25519_key" // Generated in the user config directory if missing
+	SshTerminal        = "xterm-256color"       // Terminal assumed for sessions that don't name theirs
+	SshEndedRepeat     = 100 * time.Millisecond // How often a session that is gone tells the screen, until the game has ended
+	// Bot actions
+	BotActionMove = "move"
+	BotActionChop = "chop"
+	BotActionDig  = "dig"
+	BotActionWait = "wait"
+	BotActionQuit = "quit"
 )
 
 var (
-	treeGrowingStages = map[int]GrowthInfo{ // For a given state (key), gives the next growth state (value.newState) and chance [0-1] of the next growth stage (value.chance)
-		TreeStateSeed:    {newState: TreeStateSapling, chance: GrowthChanceSeed},
-		TreeStateSapling: {newState: TreeStateAdult, chance: GrowthChanceSeed},
+	treeGrowingStages = map[int]int{ // For a given state (key), gives the next growth state (value). The chance of growing is part of the ruleset.
+		TreeStateSeed:    TreeStateSapling,
+		TreeStateSapling: TreeStateAdult,
+	}
+
+	rulesetPresets = map[string]Ruleset{ // Custom starts out as normal
+		DifficultyEasy:   {FireSpawnChance: 0.002, FireSpreadChance: 0.050, FireBurnoutHalflife: 100, GrowthChanceSeed: 0.015, MaxHitPointsPlayer: 5, DamageFire: 1},
+		DifficultyNormal: {FireSpawnChance: FireSpawnChance, FireSpreadChance: FireSpreadChance, FireBurnoutHalflife: FireBurnoutHalflife, GrowthChanceSeed: GrowthChanceSeed, MaxHitPointsPlayer: MaxHitPointsPlayer, DamageFire: DamageFire},
+		DifficultyHard:   {FireSpawnChance: 0.010, FireSpreadChance: 0.150, FireBurnoutHalflife: 300, GrowthChanceSeed: 0.007, MaxHitPointsPlayer: 2, DamageFire: 1},
+	}
+
+	rulesetFields = []RulesetField{ // Editable rules for the custom difficulty, in menu order
+		{label: "Fire spawn chance", step: 0.001, min: 0, max: 1, decimals: 3},
+		{label: "Fire spread chance", step: 0.01, min: 0, max: 1, decimals: 2},
+		{label: "Fire burnout halflife", step: 10, min: 10, max: 10000, decimals: 0},
+		{label: "Tree growth chance", step: 0.001, min: 0, max: 1, decimals: 3},
+		{label: "Max hitpoints", step: 1, min: 1, max: 99, decimals: 0},
+		{label: "Fire damage", step: 1, min: 0, max: 99, decimals: 0},
+	}
+
+	treeStateNames = map[int]string{ // For describing trees to the player
+		TreeStateSeed:      "Seed",
+		TreeStateSapling:   "Sapling",
+		TreeStateAdult:     "Adult tree",
+		TreeStateTrunk:     "Felled tree trunk",
+		TreeStateStump:     "Stump",
+		TreeStateStumpling: "Sapling stump",
+	}
+
+	harvestedStateNames = map[int]string{ // For the results screen, keyed by the state a tree was chopped from
+		TreeStateAdult:     "Adult trees felled",
+		TreeStateTrunk:     "Trunks cut up",
+		TreeStateStump:     "Stumps removed",
+		TreeStateSapling:   "Saplings cut",
+		TreeStateStumpling: "Sapling stumps removed",
+		TreeStateSeed:      "Seeds removed",
 	}
 
 	treeHarvestingStages = map[int]int{ // For a given state (key), gives the next harvesting state (value)
@@ -96,24 +297,225 @@ var (
 		TreeStateStump:     TreeStateRemoved,
 	}
 
-	symbols = map[int]Symbol{ // Color options are listed at https://github.com/gdamore/tcell/blob/master/color.go
-		KeyPlayer:        {char: '@', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorIndianRed)},
-		KeySquirrel:      {char: 'ơ', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorRosyBrown)},
-		KeyWall:          {char: '#', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorWhite)},
-		KeyTreeSeed:      {char: '.', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorKhaki)},
-		KeyTreeSapling:   {char: '┃', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorDarkKhaki)},
-		KeyTreeTrunk:     {char: '█', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorSaddleBrown)},
-		KeyTreeLeaves:    {char: '▓', aboveActor: true, style: tcell.StyleDefault.Foreground(tcell.ColorForestGreen)},
-		KeyTreeStump:     {char: '▄', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorSaddleBrown)},
-		KeyTreeStumpling: {char: '╻', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorDarkKhaki)},
-		KeyGrassLight:    {char: '\'', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorGreenYellow)},
-		KeyGrassHeavy:    {char: '"', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorGreenYellow)},
-		KeyWaterLight:    {char: ' ', aboveActor: false, style: tcell.StyleDefault.Background(tcell.ColorCornflowerBlue)},
-		KeyWaterHeavy:    {char: '~', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorMediumBlue).Background(tcell.ColorCornflowerBlue)},
-		KeyFireType1:     {char: '▓', aboveActor: true, style: tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorOrangeRed)},
-		KeyFireType2:     {char: '▓', aboveActor: true, style: tcell.StyleDefault.Foreground(tcell.ColorOrangeRed).Background(tcell.ColorOrange)},
-		KeyBurnt:         {char: '▓', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorDarkSlateGray).Background(tcell.ColorDarkGray)},
-		KeyFirebreak:     {char: '▓', aboveActor: false, style: tcell.StyleDefault.Foreground(tcell.ColorSandyBrown)},
+	actionOrder = []int{ActionMoveUp, ActionMoveRight, ActionMoveDown, ActionMoveLeft, ActionChopUp, ActionChopRight, ActionChopDown, ActionChopLeft, ActionChopOmni, ActionDigUp, ActionDigRight, ActionDigDown, ActionDigLeft, ActionDigOmni, ActionAutopilot, ActionHint, ActionScreenshot,
+		ActionPlayer2MoveUp, ActionPlayer2MoveRight, ActionPlayer2MoveDown, ActionPlayer2MoveLeft, ActionPlayer2ChopUp, ActionPlayer2ChopRight, ActionPlayer2ChopDown, ActionPlayer2ChopLeft, ActionPlayer2ChopOmni,
+		ActionPlayer2DigUp, ActionPlayer2DigRight, ActionPlayer2DigDown, ActionPlayer2DigLeft, ActionPlayer2DigOmni}
+
+	actionNames = map[int]string{ // Names used for actions in the controls file
+		ActionMoveUp:     "MoveUp",
+		ActionMoveRight:  "MoveRight",
+		ActionMoveDown:   "MoveDown",
+		ActionMoveLeft:   "MoveLeft",
+		ActionChopUp:     "ChopUp",
+		ActionChopRight:  "ChopRight",
+		ActionChopDown:   "ChopDown",
+		ActionChopLeft:   "ChopLeft",
+		ActionChopOmni:   "ChopOmni",
+		ActionDigUp:      "DigUp",
+		ActionDigRight:   "DigRight",
+		ActionDigDown:    "DigDown",
+		ActionDigLeft:    "DigLeft",
+		ActionDigOmni:    "DigOmni",
+		ActionAutopilot:  "Autopilot",
+		ActionHint:       "Hint",
+		ActionScreenshot: "Screenshot",
+
+		ActionPlayer2MoveUp:    "Player2MoveUp",
+		ActionPlayer2MoveRight: "Player2MoveRight",
+		ActionPlayer2MoveDown:  "Player2MoveDown",
+		ActionPlayer2MoveLeft:  "Player2MoveLeft",
+		ActionPlayer2ChopUp:    "Player2ChopUp",
+		ActionPlayer2ChopRight: "Player2ChopRight",
+		ActionPlayer2ChopDown:  "Player2ChopDown",
+		ActionPlayer2ChopLeft:  "Player2ChopLeft",
+		ActionPlayer2ChopOmni:  "Player2ChopOmni",
+		ActionPlayer2DigUp:     "Player2DigUp",
+		ActionPlayer2DigRight:  "Player2DigRight",
+		ActionPlayer2DigDown:   "Player2DigDown",
+		ActionPlayer2DigLeft:   "Player2DigLeft",
+		ActionPlayer2DigOmni:   "Player2DigOmni",
+	}
+
+	botActions = map[string]map[int]int{ // Player actions for each bot action and direction
+		BotActionMove: {DirUp: ActionMoveUp, DirRight: ActionMoveRight, DirDown: ActionMoveDown, DirLeft: ActionMoveLeft},
+		BotActionChop: {DirUp: ActionChopUp, DirRight: ActionChopRight, DirDown: ActionChopDown, DirLeft: ActionChopLeft, DirOmni: ActionChopOmni},
+		BotActionDig:  {DirUp: ActionDigUp, DirRight: ActionDigRight, DirDown: ActionDigDown, DirLeft: ActionDigLeft, DirOmni: ActionDigOmni},
+	}
+
+	webKeyNames = map[string]string{ // Names browsers give keys that tcell names differently
+		"Up":    "ArrowUp",
+		"Right": "ArrowRight",
+		"Down":  "ArrowDown",
+		"Left":  "ArrowLeft",
+		"Space": " ",
+		"PgUp":  "PageUp",
+		"PgDn":  "PageDown",
+	}
+
+	directionNames = map[int]string{ // Names used for directions by bots
+		DirUp:    "up",
+		DirRight: "right",
+		DirDown:  "down",
+		DirLeft:  "left",
+		DirOmni:  "omni",
+		DirNone:  "none",
+	}
+
+	endCauseNames = map[int]string{ // Names used for end causes by bots
+		EndCauseQuit:   "quit",
+		EndCauseBurned: "burned",
+	}
+
+	actionLabels = map[int]string{ // Names used for actions on the controls page
+		ActionMoveUp:     "Move up",
+		ActionMoveRight:  "Move right",
+		ActionMoveDown:   "Move down",
+		ActionMoveLeft:   "Move left",
+		ActionChopUp:     "Chop up",
+		ActionChopRight:  "Chop right",
+		ActionChopDown:   "Chop down",
+		ActionChopLeft:   "Chop left",
+		ActionChopOmni:   "Chop all around",
+		ActionDigUp:      "Dig up",
+		ActionDigRight:   "Dig right",
+		ActionDigDown:    "Dig down",
+		ActionDigLeft:    "Dig left",
+		ActionDigOmni:    "Dig all around",
+		ActionAutopilot:  "Autopilot on/off",
+		ActionHint:       "Hint",
+		ActionScreenshot: "Save screenshot",
+
+		ActionPlayer2MoveUp:    "P2 move up",
+		ActionPlayer2MoveRight: "P2 move right",
+		ActionPlayer2MoveDown:  "P2 move down",
+		ActionPlayer2MoveLeft:  "P2 move left",
+		ActionPlayer2ChopUp:    "P2 chop up",
+		ActionPlayer2ChopRight: "P2 chop right",
+		ActionPlayer2ChopDown:  "P2 chop down",
+		ActionPlayer2ChopLeft:  "P2 chop left",
+		ActionPlayer2ChopOmni:  "P2 chop all around",
+		ActionPlayer2DigUp:     "P2 dig up",
+		ActionPlayer2DigRight:  "P2 dig right",
+		ActionPlayer2DigDown:   "P2 dig down",
+		ActionPlayer2DigLeft:   "P2 dig left",
+		ActionPlayer2DigOmni:   "P2 dig all around",
+	}
+
+	harvestedStateOrder = []int{TreeStateAdult, TreeStateTrunk, TreeStateStump, TreeStateSapling, TreeStateStumpling, TreeStateSeed}
+
+	difficultyOrder = []string{DifficultyEasy, DifficultyNormal, DifficultyHard, DifficultyCustom}
+
+	presetOrder = []string{PresetDefault, PresetVi, PresetNumpad}
+
+	multiplayerModeOrder = []string{GameModeCoop, GameModeVersus}
+
+	cameraOrder = []string{CameraSplit, CameraShared}
+
+	optionLabels = map[string]string{ // Names shown for multiplayer modes and cameras
+		GameModeCoop:   "Co-op",
+		GameModeVersus: "Competitive",
+		CameraSplit:    "Split screen",
+		CameraShared:   "Shared camera",
+	}
+
+	playerTileKeys = []int{KeyPlayer, KeyPlayer2} // Tile of each local player, in order
+
+	defaultBindings = map[int][]string{ // Key names are tcell key names (e.g. "Up") or a single character
+		ActionMoveUp:     {"Up"},
+		ActionMoveRight:  {"Right"},
+		ActionMoveDown:   {"Down"},
+		ActionMoveLeft:   {"Left"},
+		ActionChopUp:     {"w"},
+		ActionChopRight:  {"d"},
+		ActionChopDown:   {"s"},
+		ActionChopLeft:   {"a"},
+		ActionChopOmni:   {"q"},
+		ActionDigUp:      {"W"},
+		ActionDigRight:   {"D"},
+		ActionDigDown:    {"S"},
+		ActionDigLeft:    {"A"},
+		ActionDigOmni:    {"Q"},
+		ActionAutopilot:  {"p"},
+		ActionHint:       {"?"},
+		ActionScreenshot: {"F12"},
+
+		ActionPlayer2MoveUp:    {"8"},
+		ActionPlayer2MoveRight: {"6"},
+		ActionPlayer2MoveDown:  {"2"},
+		ActionPlayer2MoveLeft:  {"4"},
+		ActionPlayer2ChopUp:    {"i"},
+		ActionPlayer2ChopRight: {"l"},
+		ActionPlayer2ChopDown:  {"k"},
+		ActionPlayer2ChopLeft:  {"j"},
+		ActionPlayer2ChopOmni:  {"u"},
+		ActionPlayer2DigUp:     {"I"},
+		ActionPlayer2DigRight:  {"L"},
+		ActionPlayer2DigDown:   {"K"},
+		ActionPlayer2DigLeft:   {"J"},
+		ActionPlayer2DigOmni:   {"U"},
+	}
+
+	presetBindings = map[string]map[int][]string{ // Applied on top of defaultBindings
+		PresetDefault: {},
+		PresetVi: {
+			ActionMoveUp:    {"Up", "k"},
+			ActionMoveRight: {"Right", "l"},
+			ActionMoveDown:  {"Down", "j"},
+			ActionMoveLeft:  {"Left", "h"},
+			ActionChopUp:    {"w", "K"},
+			ActionChopRight: {"d", "L"},
+			ActionChopDown:  {"s", "J"},
+			ActionChopLeft:  {"a", "H"},
+			// Player two's chop and dig keys are taken, so player two chops and digs with the numpad instead
+			ActionPlayer2ChopUp:    {"7"},
+			ActionPlayer2ChopRight: {"9"},
+			ActionPlayer2ChopDown:  {"3"},
+			ActionPlayer2ChopLeft:  {"1"},
+			ActionPlayer2ChopOmni:  {"5"},
+			ActionPlayer2DigUp:     {},
+			ActionPlayer2DigRight:  {},
+			ActionPlayer2DigDown:   {},
+			ActionPlayer2DigLeft:   {},
+			ActionPlayer2DigOmni:   {"0"},
+		},
+		PresetNumpad: { // Diagonal keys chop in the direction they are rotated towards
+			ActionMoveUp:    {"Up", "8"},
+			ActionMoveRight: {"Right", "6"},
+			ActionMoveDown:  {"Down", "2"},
+			ActionMoveLeft:  {"Left", "4"},
+			ActionChopUp:    {"w", "7"},
+			ActionChopRight: {"d", "9"},
+			ActionChopDown:  {"s", "3"},
+			ActionChopLeft:  {"a", "1"},
+			ActionChopOmni:  {"q", "5"},
+			ActionDigOmni:   {"Q", "0"},
+			// Player two's move keys are taken, so player two moves with tfgh instead
+			ActionPlayer2MoveUp:    {"t"},
+			ActionPlayer2MoveRight: {"h"},
+			ActionPlayer2MoveDown:  {"g"},
+			ActionPlayer2MoveLeft:  {"f"},
+		},
+	}
+
+	tileKeys = map[string]int{ // Tiles the game refers to directly. Other tiles in tiles.json get keys after these.
+		"player":        KeyPlayer,
+		"squirrel":      KeySquirrel,
+		"wall":          KeyWall,
+		"treeSeed":      KeyTreeSeed,
+		"treeSapling":   KeyTreeSapling,
+		"treeTrunk":     KeyTreeTrunk,
+		"treeLeaves":    KeyTreeLeaves,
+		"treeStump":     KeyTreeStump,
+		"treeStumpling": KeyTreeStumpling,
+		"grassLight":    KeyGrassLight,
+		"grassHeavy":    KeyGrassHeavy,
+		"waterLight":    KeyWaterLight,
+		"waterHeavy":    KeyWaterHeavy,
+		"fire1":         KeyFireType1,
+		"fire2":         KeyFireType2,
+		"burnt":         KeyBurnt,
+		"firebreak":     KeyFirebreak,
+		"player2":       KeyPlayer2,
 	}
 )
 
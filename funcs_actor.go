@@ -1,18 +1,37 @@
 package main
 
+import "sort"
+
 func (game *Game) MoveSquirrel(len int, dir int, squirrelKey int) {
 	game.MoveActor(game.squirrels[squirrelKey], len, dir)
 }
 
-func (game *Game) MovePlayer(len int, dir int) {
-	game.MoveActor(&game.player, len, dir)
+// Moves the player one step, unless another player stands there or it would take them out of a shared camera's view.
+// Returns true if they moved.
+func (game *Game) MovePlayer(player *Actor, dir int) bool {
+	from := player.position
+	if !game.MoveActor(player, 1, dir) {
+		return false
+	}
+	for _, other := range game.StandingPlayers() {
+		if other != player && other.position == player.position { // Players can't walk through each other
+			player.position = from
+			return false
+		}
+	}
+	if game.camera == CameraShared && !game.FitsSharedCamera() {
+		player.position = from
+		return false
+	}
+
+	return true
 }
 
 func (game *Game) MoveActor(actor *Actor, len int, dir int) bool {
 
 	// Determine (potential) new location.
 	if dir == DirRandom {
-		dir = GetRandomDirection()
+		dir = game.GetRandomDirection()
 	}
 
 	deltaX := 0
@@ -63,3 +82,14 @@ func (actor *Actor) IsAdjacentToDestination() bool {
 
 	return false
 }
+
+// Returns the keys of the squirrels in increasing order, for going through them in the same order every time.
+func (game *Game) SquirrelKeys() []int {
+	keys := make([]int, 0, len(game.squirrels))
+	for key := range game.squirrels {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	return keys
+}
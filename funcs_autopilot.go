@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Sets up a game of a random map for the autopilot to play as a demo, which ends on any input or when the player burns.
+func NewDemoGame(screen tcell.Screen, controls *Controls, settings *Settings, seed int64) (Game, error) {
+	mapFiles := FindMaps(settings.MapSources())
+	if len(mapFiles) == 0 {
+		return Game{}, fmt.Errorf("no maps found for a demo")
+	}
+
+	demoSettings := *settings
+	demoSettings.Players = 1
+	game, err := NewGame(screen, controls, &demoSettings, mapFiles[rand.New(rand.NewSource(seed)).Intn(len(mapFiles))], seed)
+	if err != nil {
+		return game, err
+	}
+	game.autopilot = true
+	game.demo = true
+	game.quitToTitle = true
+	game.QueueAutopilotStep()
+
+	return game, nil
+}
+
+// Returns the action the autopilot takes next. Fire that comes close is fought with firebreaks, or run from when
+// the player is hurt or standing in it. Otherwise the autopilot walks to the nearest adult tree and chops it down to nothing.
+func (game *Game) AutopilotAction() int {
+	fire, distance, found := game.NearestFire(game.player.position)
+	if found && distance <= AutopilotFireDistance {
+		health := float64(game.player.hitPointsCurrent) / float64(game.player.hitPointsMax)
+		if distance > 0 && health > AutopilotRetreatHealth {
+			if action, found := game.FirebreakAction(fire); found {
+				return action
+			}
+		}
+		if action, found := game.RetreatAction(); found {
+			return action
+		}
+	}
+
+	return game.ChopAction()
+}
+
+// Returns the fire nearest to the coordinate, counting steps up, right, down and left, and how many steps away it is.
+func (game *Game) NearestFire(coord Coordinate) (Coordinate, int, bool) {
+	var nearest Coordinate
+	nearestDistance, found := 0, false
+	for _, position := range game.world.Coordinates() {
+		if _, isFire := game.world.content[position].(*Fire); !isFire {
+			continue
+		}
+		if distance := Abs(position.x-coord.x) + Abs(position.y-coord.y); !found || distance < nearestDistance {
+			nearest, nearestDistance, found = position, distance, true
+		}
+	}
+
+	return nearest, nearestDistance, found
+}
+
+// Returns the action digging a firebreak between the player and the fire, or putting out fire next to the player.
+func (game *Game) FirebreakAction(fire Coordinate) (int, bool) {
+	dir := DirectionTowards(game.player.position, fire)
+	if game.IsDiggable(Step(game.player.position, dir)) {
+		return botActions[BotActionDig][dir], true
+	}
+
+	for _, dir := range []int{DirUp, DirRight, DirDown, DirLeft} {
+		if _, isFire := game.world.content[Step(game.player.position, dir)].(*Fire); isFire {
+			return botActions[BotActionDig][dir], true
+		}
+	}
+
+	return ActionNone, false
+}
+
+// Returns whether digging at the coordinate makes a new firebreak or puts out fire.
+func (game *Game) IsDiggable(coord Coordinate) bool {
+	switch content := game.world.content[coord].(type) {
+	case Object:
+		return !content.collidable && content.key != KeyFirebreak
+	case *Tree:
+		return false
+	}
+
+	return true
+}
+
+// Returns the move taking the player furthest from fire, if any move takes the player further from it than staying.
+func (game *Game) RetreatAction() (int, bool) {
+	_, bestDistance, _ := game.NearestFire(game.player.position)
+	action, found := ActionNone, false
+	for _, neighbor := range game.PathNeighbors(game.player.position) {
+		if _, distance, _ := game.NearestFire(neighbor); distance > bestDistance {
+			dir := AdjacentDirection(game.player.position, neighbor)
+			action, bestDistance, found = botActions[BotActionMove][dir], distance, true
+		}
+	}
+
+	return action, found
+}
+
+// Returns the action chopping a tree next to the player that is being felled, or walking towards the nearest adult tree.
+// Once felled, a tree is chopped until it is gone. Returns ActionNone if no adult tree can be reached.
+func (game *Game) ChopAction() int {
+	for _, dir := range []int{DirUp, DirRight, DirDown, DirLeft} {
+		if tree, isTree := game.world.content[Step(game.player.position, dir)].(*Tree); isTree && IsFelling(tree.state) {
+			return botActions[BotActionChop][dir]
+		}
+	}
+
+	path := game.FindPathTo(game.player.position, func(coord Coordinate) bool {
+		for _, dir := range []int{DirUp, DirRight, DirDown, DirLeft} {
+			if tree, isTree := game.world.content[Step(coord, dir)].(*Tree); isTree && tree.state == TreeStateAdult {
+				return true
+			}
+		}
+		return false
+	})
+	if dir := AdjacentDirection(game.player.position, path[1]); dir != DirNone {
+		return botActions[BotActionMove][dir]
+	}
+
+	return ActionNone
+}
+
+// Returns whether the tree state is one of the stages of felling an adult tree.
+func IsFelling(state int) bool {
+	for stage := TreeStateAdult; stage != TreeStateRemoved; stage = treeHarvestingStages[stage] {
+		if stage == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handles an action for starting or stopping the autopilot, or for a hint of what it would do.
+func (game *Game) HandleAutopilotAction(action int) {
+	switch action {
+	case ActionAutopilot:
+		game.autopilot = !game.autopilot
+		if game.autopilot {
+			game.AppendToMenuMessages("Autopilot on")
+		} else {
+			game.AppendToMenuMessages("Autopilot off")
+		}
+	case ActionHint:
+		hint := "wait"
+		if action := game.AutopilotAction(); action != ActionNone {
+			hint = actionLabels[action]
+		}
+		game.AppendToMenuMessages("Hint: " + hint)
+	}
+}
+
+// Lets the autopilot take an action, and queues an interrupt for the next one, so that it plays on without input.
+// Returns true if the world should advance.
+func (game *Game) StepAutopilot() bool {
+	game.stepQueued = false
+	if !game.autopilot {
+		return false
+	}
+
+	game.HandleAction(game.AutopilotAction())
+	game.QueueAutopilotStep()
+
+	return true
+}
+
+// Posts an interrupt for the autopilot's next action, unless one is already waiting or the game is paused. When the
+// event queue is full, the interrupt is posted after the next event instead.
+func (game *Game) QueueAutopilotStep() {
+	if game.autopilot && !game.stepQueued && !game.paused {
+		game.stepQueued = game.screen.PostEvent(tcell.NewEventInterrupt(AutopilotStep{})) == nil
+	}
+}
+
+// Returns whether the event ends a demo on the title screen: any key, or any mouse button.
+func EndsDemo(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		return true
+	case *tcell.EventMouse:
+		return ev.Buttons() != tcell.ButtonNone
+	}
+
+	return false
+}
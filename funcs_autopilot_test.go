@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAutopilotAction(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		karta    string
+		health   int
+		expected int
+	}{
+		{"walks to the nearest tree", "#T     p  T#", MaxHitPointsPlayer, ActionMoveRight},
+		{"chops an adult tree", "#pT#", MaxHitPointsPlayer, ActionChopRight},
+		{"ignores distant fire", "#p T    f#", MaxHitPointsPlayer, ActionMoveRight},
+		{"digs a firebreak towards fire", "#p  f#", MaxHitPointsPlayer, ActionDigRight},
+		{"retreats when hurt", "#  p f#", 1, ActionMoveLeft},
+		{"puts out fire it stands next to", "#pf#", MaxHitPointsPlayer, ActionDigRight},
+		{"waits without trees", "#p  #", MaxHitPointsPlayer, ActionNone},
+	} {
+		game, _ := newGoldenGame(t, 80, 24, test.karta)
+		game.player.hitPointsCurrent = test.health
+		if action := game.AutopilotAction(); action != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, actionLabels[test.expected], actionLabels[action])
+		}
+	}
+}
+
+func TestAutopilotFellsTree(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, ""+
+		"#######\n"+
+		"#p   T#\n"+
+		"#######")
+	game.rules.FireSpawnChance = 0
+	for i := 0; i < 20; i++ {
+		game.HandleAction(game.AutopilotAction())
+		game.UpdateWorld()
+	}
+
+	if _, isTree := game.world.content[Coordinate{5, 1}].(*Tree); isTree {
+		t.Errorf("expected the tree to be chopped down to nothing")
+	}
+	if game.player.inventory.wood == 0 {
+		t.Errorf("expected the player to have wood")
+	}
+}
+
+func TestAutopilotHint(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, "#pT#")
+	game.AppendToMenuMessages("first")
+	game.AppendToMenuMessages("second")
+	game.HandleAction(ActionHint)
+
+	messages := game.menu.messages
+	if len(messages) != 2 || messages[0] != "second" || messages[1] != "Hint: Chop right" {
+		t.Errorf("expected the hint after the last message, got %q", messages)
+	}
+	if game.player.inventory.wood != 0 {
+		t.Errorf("expected a hint not to act")
+	}
+}
+
+func TestDirectionTowards(t *testing.T) {
+	from := Coordinate{5, 5}
+	for to, expected := range map[Coordinate]int{
+		{5, 1}: DirUp,
+		{9, 6}: DirRight,
+		{4, 8}: DirDown,
+		{0, 5}: DirLeft,
+		{5, 5}: DirNone,
+	} {
+		if dir := DirectionTowards(from, to); dir != expected {
+			t.Errorf("from %v to %v: expected %s, got %s", from, to, directionNames[expected], directionNames[dir])
+		}
+	}
+}
+
+func TestAutopilotStepPostedOnceQueueHasRoom(t *testing.T) {
+	game, screen := newGoldenGame(t, 80, 24, "#p  #")
+	game.autopilot = true
+	for screen.PostEvent(tcell.NewEventInterrupt(nil)) == nil {
+	}
+
+	game.QueueAutopilotStep()
+	if game.stepQueued {
+		t.Fatalf("expected no step to be queued on a full queue")
+	}
+	game.Update()
+	if !game.stepQueued {
+		t.Errorf("expected the step to be queued after an event made room for it")
+	}
+}
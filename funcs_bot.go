@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Plays the game for a bot, one tick per action. Before each tick an observation is written to output as a line of JSON,
+// and then an action is read from input, also as a line of JSON. Invalid actions are answered with an error, and read again.
+// The game ends when the player burns or quits, after a last observation, or when input ends.
+func (game *Game) RunBot(input io.Reader, output io.Writer) error {
+	lines := bufio.NewScanner(input)
+	encoder := json.NewEncoder(output)
+	for {
+		if err := encoder.Encode(game.Observe()); err != nil {
+			return err
+		}
+		if game.exit {
+			return nil
+		}
+
+		var botAction BotAction
+		var action int
+		for {
+			if !lines.Scan() {
+				return lines.Err()
+			}
+			if strings.TrimSpace(lines.Text()) == "" {
+				continue
+			}
+
+			var err error
+			if botAction, action, err = ParseBotAction(lines.Bytes()); err == nil {
+				break
+			}
+			if err := encoder.Encode(BotError{err.Error()}); err != nil {
+				return err
+			}
+		}
+
+		if botAction.Action == BotActionQuit {
+			game.exit = true
+			game.endCause = EndCauseQuit
+			continue
+		}
+		game.HandleAction(action)
+		game.UpdateWorld()
+	}
+}
+
+// Reads an action from a bot, e.g. {"action": "chop", "dir": "left"}, and returns the player action it stands for.
+// Waiting and quitting are ActionNone.
+func ParseBotAction(data []byte) (BotAction, int, error) {
+	var botAction BotAction
+	if err := json.Unmarshal(data, &botAction); err != nil {
+		return botAction, ActionNone, err
+	}
+	action, err := botAction.PlayerAction()
+
+	return botAction, action, err
+}
+
+// Returns the player action a bot action stands for. Waiting and quitting are ActionNone.
+func (botAction BotAction) PlayerAction() (int, error) {
+	switch botAction.Action {
+	case BotActionWait, BotActionQuit:
+		return ActionNone, nil
+	}
+	actions, isAction := botActions[botAction.Action]
+	if !isAction {
+		return ActionNone, fmt.Errorf("unknown action %q, expected one of %s, %s, %s, %s or %s", botAction.Action, BotActionMove, BotActionChop, BotActionDig, BotActionWait, BotActionQuit)
+	}
+	for dir, action := range actions {
+		if directionNames[dir] == botAction.Dir {
+			return action, nil
+		}
+	}
+
+	return ActionNone, fmt.Errorf("unknown direction %q for %s", botAction.Dir, botAction.Action)
+}
+
+// Returns what the player can see, and how the player is doing.
+func (game *Game) Observe() BotObservation {
+	player := game.player
+	observation := BotObservation{
+		Tick:      game.ticks,
+		Width:     game.world.width,
+		Height:    game.world.height,
+		Player:    BotPlayer{player.position.x, player.position.y, player.hitPointsCurrent, player.hitPointsMax, player.score, player.inventory.wood, player.inventory.seeds},
+		Squirrels: []BotPosition{},
+		Tiles:     []BotTile{},
+		Done:      game.exit,
+		EndCause:  endCauseNames[game.endCause],
+	}
+
+	for _, key := range game.SquirrelKeys() {
+		if position := game.squirrels[key].position; game.IsVisible(position) {
+			observation.Squirrels = append(observation.Squirrels, BotPosition{position.x, position.y})
+		}
+	}
+
+	for _, coord := range game.world.Coordinates() {
+		if !game.IsVisible(coord) {
+			continue
+		}
+		tile := BotTile{X: coord.x, Y: coord.y}
+		switch content := game.world.content[coord].(type) {
+		case Object:
+			tile.Tile = game.settings.tiles[content.key].id
+		case *Tree:
+			tile.Tile = game.settings.tiles[content.Key()].id
+			tile.Tree = treeStateNames[content.state]
+		case *Fire:
+			tile.Tile = game.settings.tiles[KeyFireType1].id
+		}
+		observation.Tiles = append(observation.Tiles, tile)
+	}
+
+	return observation
+}
+
+// Returns whether the coordinate is within the player's vision.
+func (game *Game) IsVisible(coord Coordinate) bool {
+	dx, dy := coord.x-game.player.position.x, coord.y-game.player.position.y
+	radius := game.player.visionRadius
+
+	return dx >= -radius && dx <= radius && dy >= -radius && dy <= radius
+}
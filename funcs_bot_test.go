@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunBot(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, ""+
+		"#######\n"+
+		"#pT  s#\n"+
+		"#######")
+	game.rules.FireSpawnChance = 0
+	input := strings.Join([]string{
+		`{"action": "chop", "dir": "right"}`,
+		`{"action": "chop", "dir": "sideways"}`,
+		`not json`,
+		``,
+		`{"action": "chop", "dir": "right"}`,
+		`{"action": "chop", "dir": "right"}`,
+		`{"action": "move", "dir": "right"}`,
+		`{"action": "quit"}`,
+		`{"action": "wait"}`,
+	}, "\n")
+
+	var output bytes.Buffer
+	if err := game.RunBot(strings.NewReader(input), &output); err != nil {
+		t.Fatal(err)
+	}
+
+	var observations []BotObservation
+	errors := 0
+	lines := bufio.NewScanner(&output)
+	for lines.Scan() {
+		if strings.HasPrefix(lines.Text(), `{"error"`) {
+			errors++
+			continue
+		}
+		var observation BotObservation
+		if err := json.Unmarshal(lines.Bytes(), &observation); err != nil {
+			t.Fatal(err)
+		}
+		observations = append(observations, observation)
+	}
+
+	if errors != 2 {
+		t.Errorf("expected an error for each invalid action, got %d", errors)
+	}
+	if len(observations) != 6 {
+		t.Fatalf("expected an observation before each of 4 ticks, one after them and one after quitting, got %d", len(observations))
+	}
+	if tree := observations[1].Tiles[8]; tree != (BotTile{2, 1, "treeTrunk", "Felled tree trunk"}) {
+		t.Errorf("expected a felled tree trunk after chopping once, got %+v", tree)
+	}
+	last := observations[len(observations)-1]
+	if !last.Done || last.EndCause != "quit" || last.Tick != 4 {
+		t.Errorf("expected the game to be done after quitting at tick 4, got %+v", last)
+	}
+	if last.Player.X != 2 || last.Player.Score != 1 || last.Player.Wood != 1 {
+		t.Errorf("expected the player to chop down the tree and step into its place, got %+v", last.Player)
+	}
+}
+
+func TestObserveVision(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, ""+
+		"##########\n"+
+		"#p  s  s #\n"+
+		"##########")
+	game.player.visionRadius = 3
+
+	observation := game.Observe()
+	if len(observation.Squirrels) != 1 || observation.Squirrels[0] != (BotPosition{4, 1}) {
+		t.Errorf("expected only the nearer squirrel to be seen, got %+v", observation.Squirrels)
+	}
+	for _, tile := range observation.Tiles {
+		if tile.X > 4 {
+			t.Errorf("tile at %d,%d is out of sight", tile.X, tile.Y)
+		}
+	}
+	if len(observation.Tiles) != 2*5+1 { // Above, below, and left of the player
+		t.Errorf("expected the walls in sight, got %d tiles", len(observation.Tiles))
+	}
+}
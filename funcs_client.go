@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Reads the options of the join subcommand: the address of the server, and the name to play under.
+func ParseJoinFlags(args []string) (JoinOptions, error) {
+	var options JoinOptions
+	flags := flag.NewFlagSet("skogshuggare "+JoinCommand, flag.ContinueOnError)
+	flags.StringVar(&options.name, "name", "", fmt.Sprintf("name shown to the other players, up to %d characters", NetMaxNameLength))
+	flags.StringVar(&options.record, "record", "", "record the game to the file as an asciinema cast, e.g. out.cast")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare %s [flags] host:port | %spath\n\nJoins a game run with skogshuggare %s.\n\nFlags:\n", JoinCommand, NetUnixPrefix, ServeCommand)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+	if flags.NArg() != 1 {
+		return options, fmt.Errorf("expected the address of a server, e.g. localhost%s", ServeDefaultAddress)
+	}
+	options.address = flags.Arg(0)
+
+	return options, nil
+}
+
+// Returns a client that has joined the server, or is watching it if the options say so, with the world as the server
+// sent it on joining.
+func JoinServer(screen tcell.Screen, controls *Controls, settings *Settings, options JoinOptions) (*Client, error) {
+	client := &Client{address: options.address, name: options.name, spectate: options.spectate}
+	client.game = Game{screen: screen, controls: controls, settings: settings, camera: CameraOwn, mode: GameModeCoop, menu: Menu{messages: []string{}}, squirrels: make(map[int]*Actor)}
+	if options.spectate {
+		client.game.spectator = &Spectator{following: true, minimap: true}
+	}
+	if screen != nil {
+		client.game.UpdateLayout()
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Connects to the server and joins, as the same player again if the client joined before, or starts watching.
+// Waits for the server to answer with the client's player and the world.
+func (client *Client) Connect() error {
+	network, address := SplitAddress(client.address)
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return err
+	}
+	client.conn = conn
+	client.encoder = json.NewEncoder(conn)
+	command := NetCommand{Type: NetCommandJoin, Name: client.name, Token: client.token}
+	if client.spectate {
+		command = NetCommand{Type: NetCommandSpectate}
+	}
+	if err := client.encoder.Encode(command); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client.decoder = json.NewDecoder(conn)
+	var message NetMessage
+	if err := client.decoder.Decode(&message); err != nil {
+		conn.Close()
+		return err
+	}
+	if message.Error != "" {
+		conn.Close()
+		return fmt.Errorf("%s: %s", client.address, message.Error)
+	}
+
+	return client.Apply(message)
+}
+
+// Posts each message from the server to the screen as an interrupt event, and a ConnectionLost once the connection is lost.
+// Waits for room in the event queue rather than dropping a message while the game is busy.
+func (client *Client) Receive(decoder *json.Decoder, screen tcell.Screen) {
+	for {
+		var message NetMessage
+		if err := decoder.Decode(&message); err != nil {
+			screen.PostEventWait(tcell.NewEventInterrupt(ConnectionLost{err}))
+			return
+		}
+		screen.PostEventWait(tcell.NewEventInterrupt(message))
+	}
+}
+
+// Sends an action of player one's keys to the server. Other actions aren't played over the network.
+func (client *Client) SendAction(action int) error {
+	for name, actions := range botActions {
+		for dir, other := range actions {
+			if other == action {
+				return client.encoder.Encode(NetCommand{Type: NetCommandAction, Action: name, Dir: directionNames[dir]})
+			}
+		}
+	}
+
+	return nil
+}
+
+// Plays on the server, or watches it, until the player leaves with Escape. A lost connection is reconnected to a few
+// times before giving up.
+func (client *Client) Run() error {
+	game := &client.game
+	screen := game.screen
+	go client.Receive(client.decoder, screen)
+	defer func() { client.conn.Close() }() // The connection of the last reconnect
+
+	for {
+		game.Draw()
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyCtrlC {
+				client.encoder.Encode(NetCommand{Type: NetCommandLeave})
+				return nil
+			}
+			if action := game.controls.Action(ev); action == ActionScreenshot {
+				game.SaveScreenshot()
+			} else if client.spectate {
+				game.HandleSpectatorKey(ev)
+			} else {
+				client.SendAction(action) // A failed send shows up as a lost connection
+			}
+		case *tcell.EventInterrupt:
+			switch data := ev.Data().(type) {
+			case NetMessage:
+				if err := client.Apply(data); err != nil {
+					return err
+				}
+			case ConnectionLost:
+				if err := client.Reconnect(data.err); err != nil {
+					return err
+				}
+				go client.Receive(client.decoder, screen)
+			}
+		case *tcell.EventResize:
+			screen.Sync()
+			game.UpdateLayout()
+			game.renderer.Invalidate()
+		case *tcell.EventError, nil: // The terminal is gone
+			client.encoder.Encode(NetCommand{Type: NetCommandLeave})
+			return nil
+		}
+	}
+}
+
+// Tries to connect to the server again, waiting a little between tries.
+func (client *Client) Reconnect(cause error) error {
+	client.conn.Close()
+	for attempt := 1; attempt <= NetReconnectAttempts; attempt++ {
+		client.game.AppendToMenuMessages(fmt.Sprintf("Reconnecting (%d/%d)", attempt, NetReconnectAttempts))
+		client.game.Draw()
+		time.Sleep(NetReconnectDelay)
+		if err := client.Connect(); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("lost connection to %s: %v", client.address, cause)
+}
+
+// Updates the client's game with a message from the server.
+func (client *Client) Apply(message NetMessage) error {
+	if message.Id != 0 {
+		client.id, client.token = message.Id, message.Token
+	}
+	if message.Error != "" {
+		client.game.AppendToMenuMessages("Server: " + message.Error)
+	}
+	if message.State != nil {
+		return client.ApplyState(*message.State)
+	}
+
+	return nil
+}
+
+// Changes the tiles the state has, and replaces the actors. The client's own player becomes player one, or for a
+// spectator the first player of the state, if any.
+func (client *Client) ApplyState(state NetState) error {
+	game := &client.game
+	content := game.world.content
+	if state.Full {
+		content = make(map[Coordinate]any)
+	} else if content == nil {
+		return fmt.Errorf("changes from the server before the world")
+	}
+	for _, tile := range state.Tiles {
+		coord := Coordinate{tile.X, tile.Y}
+		tileContent, err := NetTileContent(game.settings.tiles, tile)
+		if err != nil {
+			return err
+		}
+		if tileContent == nil {
+			delete(content, coord)
+		} else {
+			content[coord] = tileContent
+		}
+	}
+	if state.Full {
+		game.world = NewWorld(state.Width, state.Height, content)
+	}
+
+	game.others, game.names = nil, []string{client.name}
+	if client.spectate {
+		game.player, game.names = Actor{}, nil
+	}
+	for _, player := range state.Players {
+		actor := Actor{position: Coordinate{player.X, player.Y}, visionRadius: player.Vision, score: player.Score, hitPointsCurrent: player.HP, hitPointsMax: player.MaxHP, inventory: Inventory{player.Wood, player.Seeds}}
+		switch {
+		case player.Id == client.id:
+			game.player = actor
+			game.names[0] = player.Name
+		case client.spectate && len(game.names) == 0:
+			game.player = actor
+			game.names = append(game.names, player.Name)
+		default:
+			game.others = append(game.others, &actor)
+			game.names = append(game.names, player.Name)
+		}
+	}
+
+	game.squirrels = make(map[int]*Actor)
+	for _, squirrel := range state.Squirrels {
+		game.squirrels[squirrel.Id] = &Actor{position: Coordinate{squirrel.X, squirrel.Y}, hitPointsCurrent: MaxHitPointsSquirrel, hitPointsMax: MaxHitPointsSquirrel}
+	}
+	game.ticks = state.Tick
+	for _, message := range state.Messages {
+		game.AppendToMenuMessages(message)
+	}
+	if game.spectator != nil {
+		game.UpdateSpectator(SpectatorTargets(state))
+	}
+
+	return nil
+}
+
+// Returns the world content a tile from the server stands for in the registry, or nil for empty ground.
+func NetTileContent(registry map[int]Tile, tile NetTile) (any, error) {
+	if tile.Tile == "" {
+		return nil, nil
+	}
+	key, found := TileKeyById(registry, tile.Tile)
+	if !found {
+		return nil, fmt.Errorf("unknown tile %q from the server", tile.Tile)
+	}
+
+	coord := Coordinate{tile.X, tile.Y}
+	switch {
+	case tile.Tree != "":
+		state, found := TreeStateByName(tile.Tree)
+		if !found {
+			return nil, fmt.Errorf("unknown tree state %q from the server", tile.Tree)
+		}
+		return &Tree{coord, state}, nil
+	case key == KeyFireType1:
+		return &Fire{position: coord}, nil
+	case key == KeyFireType2:
+		return &Fire{position: coord, phase: 1}, nil
+	}
+
+	return NewObject(registry, key), nil
+}
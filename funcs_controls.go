@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Returns the path of the controls file in the user config directory.
+func ControlsFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, ControlsFileName), nil
+}
+
+// Returns controls using the bindings of the given preset.
+func NewControls(preset string) (Controls, error) {
+	controls := Controls{}
+	if err := controls.SetPreset(preset); err != nil {
+		return controls, err
+	}
+
+	return controls, nil
+}
+
+// Reads controls from the given file. A missing file gives the default controls,
+// which are saved to that file once they are changed.
+func LoadControls(fileName string) (Controls, error) {
+	controls, _ := NewControls(PresetDefault)
+	controls.fileName = fileName
+
+	buffer, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return controls, nil
+	} else if err != nil {
+		return controls, err
+	}
+
+	var controlsFile ControlsFile
+	if err := json.Unmarshal(buffer, &controlsFile); err != nil {
+		return controls, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	if controlsFile.Preset != "" {
+		if err := controls.SetPreset(controlsFile.Preset); err != nil {
+			return controls, fmt.Errorf("%s: %v", fileName, err)
+		}
+	}
+
+	for name, keys := range controlsFile.Bindings {
+		action, found := ActionByName(name)
+		if !found {
+			return controls, fmt.Errorf("%s: unknown action %q", fileName, name)
+		}
+		for _, key := range keys {
+			if IsReservedKey(key) {
+				return controls, fmt.Errorf("%s: key %q is reserved and cannot be bound to %s", fileName, key, name)
+			}
+		}
+		controls.bindings[action] = keys
+	}
+	if err := CheckBindings(controls.bindings); err != nil {
+		return controls, fmt.Errorf("%s: %v", fileName, err)
+	}
+	controls.UpdateLookup()
+
+	return controls, nil
+}
+
+// Writes the preset and any bindings that differ from it to the controls file.
+func (controls *Controls) Save() error {
+	if controls.fileName == "" {
+		return nil
+	}
+
+	preset, _ := NewControls(controls.preset)
+	controlsFile := ControlsFile{controls.preset, make(map[string][]string)}
+	for _, action := range actionOrder {
+		if strings.Join(controls.bindings[action], "\x00") != strings.Join(preset.bindings[action], "\x00") {
+			controlsFile.Bindings[actionNames[action]] = controls.bindings[action]
+		}
+	}
+
+	buffer, err := json.MarshalIndent(controlsFile, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(controls.fileName), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(controls.fileName, buffer, 0644)
+}
+
+// Replaces all bindings with those of the given preset.
+func (controls *Controls) SetPreset(preset string) error {
+	overrides, found := presetBindings[preset]
+	if !found {
+		return fmt.Errorf("unknown controls preset %q", preset)
+	}
+
+	bindings := make(map[int][]string)
+	for action, keys := range defaultBindings {
+		bindings[action] = append([]string{}, keys...)
+	}
+	for action, keys := range overrides {
+		bindings[action] = append([]string{}, keys...)
+	}
+	if err := CheckBindings(bindings); err != nil {
+		return fmt.Errorf("controls preset %q: %v", preset, err)
+	}
+
+	controls.preset = preset
+	controls.bindings = bindings
+	controls.UpdateLookup()
+
+	return nil
+}
+
+// Returns an error if a key is bound to more than one action, as it could only do one of them.
+func CheckBindings(bindings map[int][]string) error {
+	actions := make(map[string]int)
+	for _, action := range actionOrder {
+		for _, key := range bindings[action] {
+			if other, taken := actions[key]; taken && other != action {
+				return fmt.Errorf("key %q is bound to both %s and %s", key, actionNames[other], actionNames[action])
+			}
+			actions[key] = action
+		}
+	}
+
+	return nil
+}
+
+// Binds the key to the action, replacing the action's previous keys and unbinding the key from any other action.
+// Returns false if the key is reserved for menus.
+func (controls *Controls) Bind(action int, key string) bool {
+	if IsReservedKey(key) {
+		return false
+	}
+
+	for otherAction, keys := range controls.bindings {
+		var remaining []string
+		for _, otherKey := range keys {
+			if otherKey != key {
+				remaining = append(remaining, otherKey)
+			}
+		}
+		controls.bindings[otherAction] = remaining
+	}
+	controls.bindings[action] = []string{key}
+	controls.UpdateLookup()
+
+	return true
+}
+
+// Maps each key to its action. Keys are bound to one action each, see CheckBindings.
+func (controls *Controls) UpdateLookup() {
+	controls.lookup = make(map[string]int)
+	for _, action := range actionOrder {
+		for _, key := range controls.bindings[action] {
+			controls.lookup[key] = action
+		}
+	}
+}
+
+// Returns the action bound to the key of the event, or ActionNone.
+func (controls *Controls) Action(ev *tcell.EventKey) int {
+	if action, found := controls.lookup[KeyName(ev)]; found {
+		return action
+	}
+
+	return ActionNone
+}
+
+// Returns the keys bound to the action, for display.
+func (controls *Controls) KeysString(action int) string {
+	if len(controls.bindings[action]) == 0 {
+		return "unbound"
+	}
+
+	return strings.Join(controls.bindings[action], ", ")
+}
+
+// Returns the name used for the key of the event in the controls file.
+// Characters are named by themselves, other keys by their tcell names, e.g. "Up" or "Ctrl-A".
+func KeyName(ev *tcell.EventKey) string {
+	if ev.Key() == tcell.KeyRune {
+		if ev.Rune() == ' ' {
+			return "Space"
+		}
+		return string(ev.Rune())
+	}
+
+	if name, found := tcell.KeyNames[ev.Key()]; found {
+		return name
+	}
+
+	return fmt.Sprintf("Key[%d]", ev.Key())
+}
+
+// Escape and Enter are used by the menus, so they cannot be bound to actions.
+func IsReservedKey(key string) bool {
+	return key == tcell.KeyNames[tcell.KeyEscape] || key == tcell.KeyNames[tcell.KeyEnter]
+}
+
+func ActionByName(name string) (int, bool) {
+	for action, actionName := range actionNames {
+		if actionName == name {
+			return action, true
+		}
+	}
+
+	return ActionNone, false
+}
+
+// Performs the action of the player whose key it is bound to. Players who burned can't act.
+func (game *Game) HandleAction(action int) {
+	if action == ActionAutopilot || action == ActionHint {
+		game.HandleAutopilotAction(action)
+		return
+	}
+	if action == ActionScreenshot {
+		game.SaveScreenshot()
+		return
+	}
+
+	player, action := game.PlayerAction(action)
+	if player != nil {
+		game.PerformAction(player, action)
+	}
+}
+
+// Moves, chops or digs for the given player, with the action as player one's. Players who burned can't act.
+func (game *Game) PerformAction(player *Actor, action int) {
+	if player.IsBurned() {
+		return
+	}
+
+	switch action {
+	case ActionMoveUp:
+		game.MovePlayer(player, DirUp)
+	case ActionMoveRight:
+		game.MovePlayer(player, DirRight)
+	case ActionMoveDown:
+		game.MovePlayer(player, DirDown)
+	case ActionMoveLeft:
+		game.MovePlayer(player, DirLeft)
+	case ActionChopUp:
+		game.Chop(player, DirUp, 1)
+	case ActionChopRight:
+		game.Chop(player, DirRight, 1)
+	case ActionChopDown:
+		game.Chop(player, DirDown, 1)
+	case ActionChopLeft:
+		game.Chop(player, DirLeft, 1)
+	case ActionChopOmni:
+		game.Chop(player, DirOmni, 1)
+	case ActionDigUp:
+		game.Dig(player, DirUp)
+	case ActionDigRight:
+		game.Dig(player, DirRight)
+	case ActionDigDown:
+		game.Dig(player, DirDown)
+	case ActionDigLeft:
+		game.Dig(player, DirLeft)
+	case ActionDigOmni:
+		game.Dig(player, DirOmni)
+	}
+}
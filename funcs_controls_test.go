@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLoadControls(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing file gives the defaults.
+	controls, err := LoadControls(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action := controls.Action(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)); action != ActionMoveUp {
+		t.Errorf("Up should move up by default, got action %d", action)
+	}
+
+	// The preset is applied before the overrides.
+	fileName := filepath.Join(dir, "controls.json")
+	if err := os.WriteFile(fileName, []byte(`{"preset": "vi", "bindings": {"DigOmni": ["x"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	controls, err = LoadControls(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[rune]int{'k': ActionMoveUp, 'H': ActionChopLeft, 'x': ActionDigOmni, 'Q': ActionNone}
+	for r, want := range tests {
+		if got := controls.Action(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)); got != want {
+			t.Errorf("key %q should give action %d, got %d", r, want, got)
+		}
+	}
+
+	// Unknown actions, reserved keys and keys bound twice are rejected.
+	for _, data := range []string{`{"bindings": {"Fly": ["f"]}}`, `{"bindings": {"MoveUp": ["Enter"]}}`, `{"preset": "dvorak"}`, `{"bindings": {"MoveUp": ["i"]}}`} {
+		if err := os.WriteFile(fileName, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadControls(fileName); err == nil {
+			t.Errorf("expected an error loading %s", data)
+		}
+	}
+}
+
+func TestControlsBindAndSave(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "skogshuggare", "controls.json")
+	controls, err := LoadControls(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if controls.Bind(ActionMoveUp, "Esc") {
+		t.Errorf("Esc should not be bindable")
+	}
+	if !controls.Bind(ActionDigUp, "w") {
+		t.Fatalf("w should be bindable")
+	}
+	if len(controls.bindings[ActionChopUp]) != 0 {
+		t.Errorf("w should have been unbound from ChopUp, got %v", controls.bindings[ActionChopUp])
+	}
+	if err := controls.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadControls(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Action(tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModNone)); got != ActionDigUp {
+		t.Errorf("saved binding for w should be DigUp, got %d", got)
+	}
+	if got := reloaded.KeysString(ActionChopUp); got != "unbound" {
+		t.Errorf("saved ChopUp should be unbound, got %s", got)
+	}
+}
@@ -1,5 +1,7 @@
 package main
 
+import "sort"
+
 func (coordinate *Coordinate) Translate(deltaX int, deltaY int) {
 	coordinate.x = coordinate.x + deltaX
 	coordinate.y = coordinate.y + deltaY
@@ -8,3 +10,90 @@ func (coordinate *Coordinate) Translate(deltaX int, deltaY int) {
 func Translate(coordinate Coordinate, deltaX int, deltaY int) Coordinate {
 	return Coordinate{coordinate.x + deltaX, coordinate.y + deltaY}
 }
+
+// Returns the direction of a step from one coordinate to a neighbouring one, or DirNone if they are not neighbours.
+func AdjacentDirection(from Coordinate, to Coordinate) int {
+	switch Translate(to, -from.x, -from.y) {
+	case Coordinate{0, -1}:
+		return DirUp
+	case Coordinate{1, 0}:
+		return DirRight
+	case Coordinate{0, 1}:
+		return DirDown
+	case Coordinate{-1, 0}:
+		return DirLeft
+	default:
+		return DirNone
+	}
+}
+
+// Returns the coordinates of everything in the world, row by row.
+// Updates go through the world in this order, so that the same seed always plays out the same way.
+func (world *World) Coordinates() []Coordinate {
+	coordinates := make([]Coordinate, 0, len(world.content))
+	for coord := range world.content {
+		coordinates = append(coordinates, coord)
+	}
+	sort.Slice(coordinates, func(i, j int) bool {
+		if coordinates[i].y != coordinates[j].y {
+			return coordinates[i].y < coordinates[j].y
+		}
+		return coordinates[i].x < coordinates[j].x
+	})
+
+	return coordinates
+}
+
+// Returns the coordinate one step from the given one in the direction, or the same coordinate for other directions.
+func Step(coordinate Coordinate, dir int) Coordinate {
+	switch dir {
+	case DirUp:
+		return Translate(coordinate, 0, -1)
+	case DirRight:
+		return Translate(coordinate, 1, 0)
+	case DirDown:
+		return Translate(coordinate, 0, 1)
+	case DirLeft:
+		return Translate(coordinate, -1, 0)
+	default:
+		return coordinate
+	}
+}
+
+// Returns the direction of the first step from one coordinate towards another, along the axis they are furthest apart on.
+// Returns DirNone if they are the same.
+func DirectionTowards(from Coordinate, to Coordinate) int {
+	dx, dy := to.x-from.x, to.y-from.y
+	switch {
+	case dx == 0 && dy == 0:
+		return DirNone
+	case Abs(dy) > Abs(dx) && dy < 0:
+		return DirUp
+	case Abs(dy) > Abs(dx):
+		return DirDown
+	case dx > 0:
+		return DirRight
+	default:
+		return DirLeft
+	}
+}
+
+func Abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// Returns n, moved into the range from low to high.
+func Clamp(n int, low int, high int) int {
+	if n > high {
+		n = high
+	}
+	if n < low {
+		n = low
+	}
+
+	return n
+}
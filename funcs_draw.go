@@ -1,27 +1,152 @@
 package main
 
 import (
-	"strconv"
+	"time"
+	"unicode/utf8"
 
-	"github.com/gdamore/tcell"
+	"github.com/gdamore/tcell/v2"
 )
 
-func (game *Game) Draw() {
-	game.screen.Clear()
+// Builds the frame, records it when the game is being recorded, and writes only the cells that changed since the
+// previous one to the screen.
+func (game *Game) Draw() int {
+	w, h := game.screen.Size()
+	game.renderer.Begin(w, h)
 	game.DrawViewport()
 	game.DrawMenu()
+	if game.paused {
+		game.DrawPauseMenu()
+	} else {
+		game.DrawTooltip()
+	}
+	if game.settings != nil {
+		game.settings.recorder.Record(game.renderer.current, w, h, time.Now())
+	}
+	written := game.renderer.Flush(game.screen)
 	game.screen.Show()
+
+	return written
 }
 
-// Check if the given object viewport coordinates are in the viewport
-func (game *Game) IsInViewport(playerViewportCoord Coordinate, objectViewportCoords Coordinate) bool {
-	return (objectViewportCoords.x >= playerViewportCoord.x-game.player.visionRadius && objectViewportCoords.x <= playerViewportCoord.x+game.player.visionRadius) &&
-		(objectViewportCoords.y >= playerViewportCoord.y-game.player.visionRadius && objectViewportCoords.y <= playerViewportCoord.y+game.player.visionRadius)
+// Returns the views of the world to draw. A single player is followed by one camera. Several players get a camera
+// each, side by side, with a split screen, or share one kept between the players still standing. A network game
+// only follows the client's own player, and a spectator's free camera sees everything.
+func (game *Game) Cameras() []Camera {
+	if game.spectator != nil {
+		return []Camera{{game.viewport, game.spectator.center, nil}}
+	}
+	players := game.Players()
+	if len(players) == 1 || game.camera == CameraOwn {
+		return []Camera{{game.viewport, game.player.position, []*Actor{&game.player}}}
+	}
+	if game.camera == CameraShared {
+		return []Camera{game.SharedCamera()}
+	}
+
+	var cameras []Camera
+	width := (game.viewport.width - SplitScreenGap*(len(players)-1)) / len(players)
+	for i, player := range players {
+		position := Translate(game.viewport.position, i*(width+SplitScreenGap), 0)
+		cameras = append(cameras, Camera{Viewport{width, game.viewport.height, position}, player.position, []*Actor{player}})
+	}
+
+	return cameras
 }
 
-// Only draw things within the player view range
-// Draw the player last, run checks in DrawPlayer function to check if player should be drawn or not.
+// Returns the camera centered between the players still standing, or between all players once every one has burned.
+func (game *Game) SharedCamera() Camera {
+	viewers := game.StandingPlayers()
+	if len(viewers) == 0 {
+		viewers = game.Players()
+	}
+
+	low, high := viewers[0].position, viewers[0].position
+	for _, viewer := range viewers {
+		if viewer.position.x < low.x {
+			low.x = viewer.position.x
+		}
+		if viewer.position.y < low.y {
+			low.y = viewer.position.y
+		}
+		if viewer.position.x > high.x {
+			high.x = viewer.position.x
+		}
+		if viewer.position.y > high.y {
+			high.y = viewer.position.y
+		}
+	}
+
+	return Camera{game.viewport, Coordinate{low.x + (high.x-low.x)/2, low.y + (high.y-low.y)/2}, viewers}
+}
+
+// Returns whether every player still standing is inside the view of the shared camera.
+func (game *Game) FitsSharedCamera() bool {
+	camera := game.SharedCamera()
+	for _, viewer := range camera.viewers {
+		if !camera.viewport.Contains(camera.WorldToScreen(viewer.position)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Converts a world coordinate to the screen coordinate it is drawn at.
+func (camera Camera) WorldToScreen(coord Coordinate) Coordinate {
+	middle := Translate(camera.viewport.position, camera.viewport.width/2, camera.viewport.height/2)
+	return Translate(middle, coord.x-camera.center.x, coord.y-camera.center.y)
+}
+
+// Converts a screen coordinate to the world coordinate drawn there.
+func (camera Camera) ScreenToWorld(coord Coordinate) Coordinate {
+	middle := Translate(camera.viewport.position, camera.viewport.width/2, camera.viewport.height/2)
+	return Translate(camera.center, coord.x-middle.x, coord.y-middle.y)
+}
+
+// Returns whether the world coordinate is within the vision of any of the camera's viewers. A camera without viewers,
+// a spectator's, sees everything.
+func (camera Camera) CanSee(coord Coordinate) bool {
+	if len(camera.viewers) == 0 {
+		return true
+	}
+	for _, viewer := range camera.viewers {
+		if Abs(coord.x-viewer.position.x) <= viewer.visionRadius && Abs(coord.y-viewer.position.y) <= viewer.visionRadius {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the given screen coordinates are inside the viewport
+func (viewport Viewport) Contains(coord Coordinate) bool {
+	return coord.x >= viewport.position.x && coord.x < viewport.position.x+viewport.width &&
+		coord.y >= viewport.position.y && coord.y < viewport.position.y+viewport.height
+}
+
+// Check if the given screen coordinates are inside the area of the screen reserved for the viewport, i.e. not under the HUD
+func (game *Game) IsInViewportBounds(coord Coordinate) bool {
+	return game.viewport.Contains(coord)
+}
+
+// Draws the view of each camera, with a line between the views of a split screen, and a spectator's minimap over them.
 func (game *Game) DrawViewport() {
+	for i, camera := range game.Cameras() {
+		if i > 0 {
+			for y := camera.viewport.position.y; y < camera.viewport.position.y+camera.viewport.height; y++ {
+				game.renderer.SetContent(camera.viewport.position.x-SplitScreenGap, y, tcell.RuneVLine, tcell.StyleDefault)
+			}
+		}
+		game.DrawCamera(camera)
+	}
+	if game.spectator != nil && game.spectator.minimap {
+		game.DrawMinimap()
+	}
+}
+
+// Only draw things within the vision of the camera's viewers, and inside its viewport.
+// Actors are drawn first, and other content is only drawn over them if it belongs above actors.
+func (game *Game) DrawCamera(camera Camera) {
 	/*
 		view_radius = 5
 		y_up = 5
@@ -42,46 +167,56 @@ func (game *Game) DrawViewport() {
 		Player pos = (5,5)
 	*/
 
-	// Draw player.
-	w, h := game.screen.Size()
-	playerViewportCoord := Coordinate{w / 2, h / 2}
-	game.DrawContent(KeyPlayer, playerViewportCoord, []Coordinate{})
+	// Draw players. Burned players leave a multiplayer game, and aren't shown to spectators.
+	var actorViewportCoords []Coordinate
+	for i, player := range game.Players() {
+		if (player.IsBurned() && (len(game.others) > 0 || game.spectator != nil)) || !camera.CanSee(player.position) {
+			continue
+		}
+		playerViewportCoord := camera.WorldToScreen(player.position)
+		game.DrawContent(camera.viewport, PlayerTileKey(i), playerViewportCoord, actorViewportCoords)
+		actorViewportCoords = append(actorViewportCoords, playerViewportCoord)
+	}
 
 	// Draw squirrels.
-	var squirrelViewportCoord Coordinate
-	var squirrelViewportCoords []Coordinate
+	playerViewportCoords := actorViewportCoords
 	for _, squirrel := range game.squirrels {
-		squirrelViewportCoord = Translate(playerViewportCoord, squirrel.position.x-game.player.position.x, squirrel.position.y-game.player.position.y)
-		if game.IsInViewport(playerViewportCoord, squirrelViewportCoord) {
-			game.DrawContent(KeySquirrel, squirrelViewportCoord, []Coordinate{playerViewportCoord}) // FIXME only draw inside viewport
-			squirrelViewportCoords = append(squirrelViewportCoords, squirrelViewportCoord)
+		if camera.CanSee(squirrel.position) {
+			squirrelViewportCoord := camera.WorldToScreen(squirrel.position)
+			game.DrawContent(camera.viewport, KeySquirrel, squirrelViewportCoord, playerViewportCoords)
+			actorViewportCoords = append(actorViewportCoords, squirrelViewportCoord)
 		}
 	}
 
 	// Draw content.
-	actorViewportCoords := append(squirrelViewportCoords, playerViewportCoord)
-	xRadiusMin, xRadiusMax, yRadiusMin, yRadiusMax := game.GetDrawRanges()
+	xRadiusMin, xRadiusMax, yRadiusMin, yRadiusMax := game.GetDrawRanges(camera)
 	for x := xRadiusMin; x <= xRadiusMax; x++ {
 		for y := yRadiusMin; y <= yRadiusMax; y++ {
 			coord := Coordinate{x, y}
+			if !camera.CanSee(coord) {
+				continue
+			}
 
 			// Get the viewport coordinates
-			contentViewportCoord := Translate(playerViewportCoord, x-game.player.position.x, y-game.player.position.y)
+			contentViewportCoord := camera.WorldToScreen(coord)
 
 			if border, isBorder := game.world.borders[coord]; isBorder {
+				if !camera.viewport.Contains(contentViewportCoord) {
+					continue
+				}
 				switch border {
 				case TopBorder, BottomBorder:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneHLine, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneHLine, tcell.StyleDefault)
 				case RightBorder, LeftBorder:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneVLine, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneVLine, tcell.StyleDefault)
 				case TopLeftCorner:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneULCorner, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneULCorner, tcell.StyleDefault)
 				case TopRightCorner:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneURCorner, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneURCorner, tcell.StyleDefault)
 				case BottomRightCorner:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneLRCorner, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneLRCorner, tcell.StyleDefault)
 				case BottomLeftCorner:
-					game.screen.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneLLCorner, nil, tcell.StyleDefault)
+					game.renderer.SetContent(contentViewportCoord.x, contentViewportCoord.y, tcell.RuneLLCorner, tcell.StyleDefault)
 				}
 				continue
 			}
@@ -90,27 +225,16 @@ func (game *Game) DrawViewport() {
 				switch content := content.(type) {
 				case Object:
 					// Draw object
-					game.DrawContent(content.key, contentViewportCoord, actorViewportCoords)
+					game.DrawContent(camera.viewport, content.key, contentViewportCoord, actorViewportCoords)
 				case *Fire:
-					game.DrawContent(RandomFireKey(), contentViewportCoord, actorViewportCoords)
+					game.DrawContent(camera.viewport, content.Key(), contentViewportCoord, actorViewportCoords)
 				case *Tree:
 					// Draw tree
-					switch content.state {
-					case TreeStateStump:
-						game.DrawContent(KeyTreeStump, contentViewportCoord, actorViewportCoords)
-					case TreeStateTrunk:
-						game.DrawContent(KeyTreeTrunk, contentViewportCoord, actorViewportCoords)
-					case TreeStateStumpling:
-						game.DrawContent(KeyTreeStumpling, contentViewportCoord, actorViewportCoords)
-					case TreeStateSapling:
-						game.DrawContent(KeyTreeSapling, contentViewportCoord, actorViewportCoords)
-					case TreeStateSeed:
-						game.DrawContent(KeyTreeSeed, contentViewportCoord, actorViewportCoords)
-					case TreeStateAdult:
-						game.DrawContent(KeyTreeTrunk, contentViewportCoord, actorViewportCoords)
-						game.DrawContent(KeyTreeLeaves, Translate(contentViewportCoord, -1, -1), actorViewportCoords)
-						game.DrawContent(KeyTreeLeaves, Translate(contentViewportCoord, 0, -1), actorViewportCoords)
-						game.DrawContent(KeyTreeLeaves, Translate(contentViewportCoord, 1, -1), actorViewportCoords)
+					game.DrawContent(camera.viewport, content.Key(), contentViewportCoord, actorViewportCoords)
+					if content.state == TreeStateAdult {
+						game.DrawContent(camera.viewport, KeyTreeLeaves, Translate(contentViewportCoord, -1, -1), actorViewportCoords)
+						game.DrawContent(camera.viewport, KeyTreeLeaves, Translate(contentViewportCoord, 0, -1), actorViewportCoords)
+						game.DrawContent(camera.viewport, KeyTreeLeaves, Translate(contentViewportCoord, 1, -1), actorViewportCoords)
 					}
 				}
 			}
@@ -118,89 +242,123 @@ func (game *Game) DrawViewport() {
 	}
 }
 
-// Draws content for the given key at the given coord, but only if that coord is not in priorityCoords
-func (game *Game) DrawContent(key int, coord Coordinate, priorityCoords []Coordinate) {
-	symbol := symbols[key]
+// Draws content for the given key at the given coord, but only if that coord is inside the viewport and not in priorityCoords
+func (game *Game) DrawContent(viewport Viewport, key int, coord Coordinate, priorityCoords []Coordinate) {
+	tile := game.settings.tiles[key]
 	draw := true
 	for _, priorityCoord := range priorityCoords {
-		if coord == priorityCoord && !symbol.aboveActor {
+		if coord == priorityCoord && !tile.aboveActor {
 			draw = false
 		}
 	}
 
-	if draw {
-		game.screen.SetContent(coord.x, coord.y, symbol.char, nil, symbol.style)
+	if draw && viewport.Contains(coord) {
+		game.renderer.SetContent(coord.x, coord.y, tile.char, tile.style)
 	}
 }
 
 func (game *Game) DrawMenu() {
 	game.DrawMenuBorder()
-	// Draw score: 0
-	//      12345678
-	scoreString := "Score: " + strconv.Itoa(game.player.score)
-	scoreIdx := 0
-	for i := 1; i < len(scoreString)+1; i++ {
-		game.screen.SetContent(i, 1, rune(scoreString[scoreIdx]), nil, tcell.StyleDefault)
-		scoreIdx++
+
+	lines := game.HudLines()
+	if game.menu.layout == HudLayoutSide {
+		lines = append(lines, HudLine{}) // Leave a gap between stats and messages
+	}
+	for _, message := range game.menu.messages {
+		lines = append(lines, HudLine{segments: PlainSegments(message)})
 	}
 
-	hitPointsString := "HP: " + strconv.Itoa(game.player.hitPointsCurrent)
-	hitPointsIdx := 0
-	for i := 1; i < len(hitPointsString)+1; i++ {
-		game.screen.SetContent(i, 2, rune(hitPointsString[hitPointsIdx]), nil, tcell.StyleDefault)
-		hitPointsIdx++
+	// Side panels list one line per row. Bottom panels fill rows first and then wrap into columns, sized to fit in the
+	// panel.
+	rows := game.menu.height - 2
+	if rows <= 0 || game.menu.width <= 2 {
+		return
+	}
+	columnWidths := []int{game.menu.width - 2}
+	if game.menu.layout == HudLayoutBottom {
+		columnWidths = HudColumnWidths(lines, rows, game.menu.width-2)
 	}
 
-	game.PrintToMenu()
+	x := game.menu.position.x + 1
+	for i, line := range lines {
+		column := i / rows
+		if column >= len(columnWidths) {
+			break
+		}
+		if column > 0 && i%rows == 0 {
+			x += columnWidths[column-1]
+		}
+		maxWidth := columnWidths[column]
+		if game.menu.layout == HudLayoutBottom {
+			maxWidth-- // Keep a space between columns
+		}
+		if maxWidth <= 0 {
+			break
+		}
+		game.DrawHudLine(x, game.menu.position.y+1+i%rows, maxWidth, line)
+	}
 }
 
-func (game *Game) DrawMenuBorder() {
-	for c := 1; c < game.menu.width; c++ { // Draw top and bottom borders
-		game.screen.SetContent(c, 0, tcell.RuneHLine, nil, tcell.StyleDefault)
-		game.screen.SetContent(c, game.menu.height, tcell.RuneHLine, nil, tcell.StyleDefault)
+// Draws a description of the tile under the mouse pointer next to the pointer, kept inside the viewport.
+func (game *Game) DrawTooltip() {
+	if !game.mouse.hovering {
+		return
+	}
+	coord, inWorld := game.ScreenToWorld(game.mouse.position)
+	if !inWorld {
+		return
 	}
 
-	for r := 1; r <= game.menu.height-1; r++ { // Add left and right borders
-		game.screen.SetContent(0, r, tcell.RuneVLine, nil, tcell.StyleDefault)
-		game.screen.SetContent(game.menu.width, r, tcell.RuneVLine, nil, tcell.StyleDefault)
+	text := " " + game.DescribeTile(coord) + " "
+	width := utf8.RuneCountInString(text)
+	right := game.viewport.position.x + game.viewport.width
+	x := game.mouse.position.x + 1
+	y := game.mouse.position.y + 1
+	if x+width > right {
+		x = right - width
+	}
+	if x < game.viewport.position.x {
+		x = game.viewport.position.x
+	}
+	if y >= game.viewport.position.y+game.viewport.height {
+		y = game.mouse.position.y - 1
 	}
 
-	// Add corners
-	game.screen.SetContent(0, 0, tcell.RuneULCorner, nil, tcell.StyleDefault)
-	game.screen.SetContent(game.menu.width, 0, tcell.RuneURCorner, nil, tcell.StyleDefault)
-	game.screen.SetContent(0, game.menu.height, tcell.RuneLLCorner, nil, tcell.StyleDefault)
-	game.screen.SetContent(game.menu.width, game.menu.height, tcell.RuneLRCorner, nil, tcell.StyleDefault)
+	game.DrawText(x, y, right-x, text, tcell.StyleDefault.Reverse(true))
 }
 
-func (game *Game) PrintToMenu() {
-	maxLen := game.menu.width
-	maxHeight := game.menu.height
+func (game *Game) DrawMenuBorder() {
+	left := game.menu.position.x
+	top := game.menu.position.y
+	right := left + game.menu.width - 1
+	bottom := top + game.menu.height - 1
+	if right <= left || bottom <= top {
+		return
+	}
 
-	currX := 1
-	currY := 2
-	for _, message := range game.menu.messages {
-		for c := 0; c < len(message); c++ {
-			r := rune(message[c])
-			if c%maxLen == 0 {
-				currX = 1
-				currY++
-			}
-			if currY >= maxHeight {
-				break
-			}
-			game.screen.SetContent(currX, currY, r, nil, tcell.StyleDefault)
-			currX++
-		}
+	for c := left + 1; c < right; c++ { // Draw top and bottom borders
+		game.renderer.SetContent(c, top, tcell.RuneHLine, tcell.StyleDefault)
+		game.renderer.SetContent(c, bottom, tcell.RuneHLine, tcell.StyleDefault)
+	}
+
+	for r := top + 1; r < bottom; r++ { // Add left and right borders
+		game.renderer.SetContent(left, r, tcell.RuneVLine, tcell.StyleDefault)
+		game.renderer.SetContent(right, r, tcell.RuneVLine, tcell.StyleDefault)
 	}
 
+	// Add corners
+	game.renderer.SetContent(left, top, tcell.RuneULCorner, tcell.StyleDefault)
+	game.renderer.SetContent(right, top, tcell.RuneURCorner, tcell.StyleDefault)
+	game.renderer.SetContent(left, bottom, tcell.RuneLLCorner, tcell.StyleDefault)
+	game.renderer.SetContent(right, bottom, tcell.RuneLRCorner, tcell.StyleDefault)
 }
 
+// Adds a message to the HUD, keeping the last two.
 func (game *Game) AppendToMenuMessages(text string) {
-	if len(game.menu.messages) <= 1 {
-		game.menu.messages = append(game.menu.messages, text)
-	} else {
+	if len(game.menu.messages) > 1 {
 		game.menu.messages = append(game.menu.messages[:0], game.menu.messages[1:]...)
 	}
+	game.menu.messages = append(game.menu.messages, text)
 }
 
 func IsBorder(width int, height int, coord Coordinate) (response int, ok bool) {
@@ -246,26 +404,47 @@ func IsBorder(width int, height int, coord Coordinate) (response int, ok bool) {
 	return -1, false
 }
 
-func (game *Game) GetDrawRanges() (xRadiusMin int, xRadiusMax int, yRadiusMin int, yRadiusMax int) {
-	xRadiusMin = 0
-	xRadiusMax = game.world.width
-	yRadiusMin = 0
-	yRadiusMax = game.world.height
-
-	if game.player.position.x-game.player.visionRadius > 0 {
-		xRadiusMin = game.player.position.x - game.player.visionRadius
+// Returns the world area within the vision of any of the camera's viewers, limited to the world. A camera without
+// viewers, a spectator's, gets the area its viewport shows.
+func (game *Game) GetDrawRanges(camera Camera) (xRadiusMin int, xRadiusMax int, yRadiusMin int, yRadiusMax int) {
+	if len(camera.viewers) == 0 {
+		low := camera.ScreenToWorld(camera.viewport.position)
+		high := camera.ScreenToWorld(Translate(camera.viewport.position, camera.viewport.width-1, camera.viewport.height-1))
+		return Clamp(low.x, 0, game.world.width-1), Clamp(high.x, 0, game.world.width-1), Clamp(low.y, 0, game.world.height-1), Clamp(high.y, 0, game.world.height-1)
 	}
 
-	if game.player.position.x+game.player.visionRadius < game.world.width {
-		xRadiusMax = game.player.position.x + game.player.visionRadius
-	}
+	xRadiusMin, yRadiusMin = game.world.width, game.world.height
+	for _, viewer := range camera.viewers {
+		xMin, xMax, yMin, yMax := 0, game.world.width, 0, game.world.height
 
-	if game.player.position.y-game.player.visionRadius > 0 {
-		yRadiusMin = game.player.position.y - game.player.visionRadius
-	}
+		if viewer.position.x-viewer.visionRadius > 0 {
+			xMin = viewer.position.x - viewer.visionRadius
+		}
+
+		if viewer.position.x+viewer.visionRadius < game.world.width {
+			xMax = viewer.position.x + viewer.visionRadius
+		}
 
-	if game.player.position.y+game.player.visionRadius < game.world.height {
-		yRadiusMax = game.player.position.y + game.player.visionRadius
+		if viewer.position.y-viewer.visionRadius > 0 {
+			yMin = viewer.position.y - viewer.visionRadius
+		}
+
+		if viewer.position.y+viewer.visionRadius < game.world.height {
+			yMax = viewer.position.y + viewer.visionRadius
+		}
+
+		if xMin < xRadiusMin {
+			xRadiusMin = xMin
+		}
+		if xMax > xRadiusMax {
+			xRadiusMax = xMax
+		}
+		if yMin < yRadiusMin {
+			yRadiusMin = yMin
+		}
+		if yMax > yRadiusMax {
+			yRadiusMax = yMax
+		}
 	}
 
 	return xRadiusMin, xRadiusMax, yRadiusMin, yRadiusMax
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	benchmarkMapWidth  = 400
+	benchmarkMapHeight = 200
+	benchmarkScreenW   = 200
+	benchmarkScreenH   = 60
+	// Rough cost of a changed cell on a real terminal: a cursor move, a colour change and the character itself.
+	benchmarkBytesPerCell = 16
+	// Throughput of a slow SSH connection, in bytes per second (1 Mbit/s).
+	benchmarkLinkSpeed = 125000
+)
+
+// Returns a large map with scattered water and fire.
+func benchmarkMap() []byte {
+	r := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for y := 0; y < benchmarkMapHeight; y++ {
+		for x := 0; x < benchmarkMapWidth; x++ {
+			switch {
+			case x == 0 || y == 0 || x == benchmarkMapWidth-1 || y == benchmarkMapHeight-1:
+				sb.WriteByte('#')
+			case x == benchmarkMapWidth/2 && y == benchmarkMapHeight/2:
+				sb.WriteByte('p')
+			case r.Float64() < 0.01:
+				sb.WriteByte('s')
+			case r.Float64() < 0.05:
+				sb.WriteByte('w')
+			case r.Float64() < 0.01:
+				sb.WriteByte('f')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+
+	return []byte(sb.String())
+}
+
+func newBenchmarkGame(b *testing.B) *Game {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		b.Fatal(err)
+	}
+	screen.SetSize(benchmarkScreenW, benchmarkScreenH)
+
+	settings := DefaultSettings()
+	world, playerPosition, squirrelPositions, err := ParseMap(benchmarkMap(), settings.tiles)
+	if err != nil {
+		b.Fatal(err)
+	}
+	game := &Game{screen: screen, random: rand.New(rand.NewSource(1)), settings: &settings, world: world, rules: rulesetPresets[DifficultyNormal]}
+	game.player = Actor{position: playerPosition, visionRadius: 100, hitPointsCurrent: MaxHitPointsPlayer, hitPointsMax: MaxHitPointsPlayer}
+	game.squirrels = make(map[int]*Actor)
+	for index, position := range squirrelPositions {
+		game.squirrels[index] = &Actor{position: position, visionRadius: 100, hitPointsCurrent: MaxHitPointsSquirrel, hitPointsMax: MaxHitPointsSquirrel}
+	}
+	game.PopulateTrees()
+	game.PopulateGrass()
+	game.UpdateLayout()
+
+	return game
+}
+
+// Runs the simulation between frames the way Update does, minus input and squirrels, and draws each frame.
+// With full set, every cell is written each frame, which is what clearing the screen used to cost.
+func benchmarkDraw(b *testing.B, full bool) {
+	game := newBenchmarkGame(b)
+	game.Draw()
+
+	cells := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		game.ticks++
+		game.GrowTrees()
+		game.UpdateFire()
+		if full {
+			game.renderer.Invalidate()
+		}
+		cells += game.Draw()
+	}
+
+	cellsPerFrame := float64(cells) / float64(b.N)
+	b.ReportMetric(cellsPerFrame, "cells/frame")
+	b.ReportMetric(1000*cellsPerFrame*benchmarkBytesPerCell/benchmarkLinkSpeed, "ms-on-1Mbps/frame")
+}
+
+func BenchmarkDrawFullRedraw(b *testing.B) {
+	benchmarkDraw(b, true)
+}
+
+func BenchmarkDrawDirtyRegions(b *testing.B) {
+	benchmarkDraw(b, false)
+}
+
+func TestRendererFlushWritesOnlyChangedCells(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(10, 5)
+
+	var renderer Renderer
+	renderer.Begin(10, 5)
+	renderer.SetContent(1, 1, 'a', tcell.StyleDefault)
+	if written := renderer.Flush(screen); written != 50 {
+		t.Errorf("first frame should write every cell, wrote %d", written)
+	}
+
+	renderer.Begin(10, 5)
+	renderer.SetContent(1, 1, 'a', tcell.StyleDefault)
+	renderer.SetContent(2, 1, 'b', tcell.StyleDefault)
+	if written := renderer.Flush(screen); written != 1 {
+		t.Errorf("unchanged frame with one new cell should write 1 cell, wrote %d", written)
+	}
+
+	renderer.Begin(10, 5)
+	if written := renderer.Flush(screen); written != 2 {
+		t.Errorf("clearing two cells should write 2 cells, wrote %d", written)
+	}
+
+	renderer.Begin(10, 5)
+	renderer.Invalidate()
+	if written := renderer.Flush(screen); written != 50 {
+		t.Errorf("invalidated frame should write every cell, wrote %d", written)
+	}
+}
+
+func TestFireAnimationIsDeterministic(t *testing.T) {
+	fire := NewFire(Coordinate{3, 4}, 1)
+	neighbor := NewFire(Coordinate{4, 4}, 1)
+	if fire.Key() == neighbor.Key() {
+		t.Errorf("neighbouring fires should start in different animation frames")
+	}
+
+	key := fire.Key()
+	for i := 0; i < 10; i++ {
+		if fire.Key() != key {
+			t.Fatalf("fire key changed without the fire ageing")
+		}
+	}
+
+	fire.age += FireAnimationPeriod
+	if fire.Key() == key {
+		t.Errorf("fire key should change after %d ticks", FireAnimationPeriod)
+	}
+}
+
+func TestHudColumnWidthsFitThePanel(t *testing.T) {
+	lines := []HudLine{
+		{"Long label", PlainSegments("1234567890")}, {"A", PlainSegments("1")},
+		{"B", PlainSegments("2")}, {"C", PlainSegments("3")},
+		{"Medium", PlainSegments("123")},
+	}
+	tests := []struct {
+		width int
+		want  []int
+	}{
+		{60, []int{23, 5, 12}}, // Each column as wide as its widest line and a space
+		{30, []int{13, 5, 12}}, // The widest column cut down to fit
+		{12, []int{4, 4, 4}},
+	}
+	for _, test := range tests {
+		widths := HudColumnWidths(lines, 2, test.width)
+		if len(widths) != len(test.want) || widths[0] != test.want[0] || widths[1] != test.want[1] || widths[2] != test.want[2] {
+			t.Errorf("expected columns %v in a width of %d, got %v", test.want, test.width, widths)
+		}
+	}
+}
@@ -1,105 +1,137 @@
 package main
 
-import (
-	"math/rand"
-)
+// Returns the animation phase a new fire at the given coordinate starts in.
+// Neighbouring fires start out of phase so that a burning area flickers in a checkered pattern.
+func FirePhase(coord Coordinate) int {
+	return ((coord.x+coord.y)%FireAnimationPhases + FireAnimationPhases) % FireAnimationPhases
+}
 
-func RandomFireKey() int {
-	var key int
-	switch rand.Intn(2) {
+// Returns the symbol key for the fire's current animation frame.
+// The frame only depends on the fire's age and phase, so it changes with game updates rather than with redraws.
+func (fire *Fire) Key() int {
+	switch (fire.age/FireAnimationPeriod + fire.phase) % FireAnimationPhases {
 	case 0:
-		key = KeyFireType1
-	case 1:
-		key = KeyFireType2
+		return KeyFireType1
+	default:
+		return KeyFireType2
 	}
+}
 
-	return key
+// Returns a new fire at the given coordinate, burning the given fuel.
+func NewFire(coord Coordinate, fuel float64) *Fire {
+	return &Fire{coord, 0, FirePhase(coord), fuel}
 }
 
-func BurnoutChance(t int) float64 {
-	return 1 - (1 / (1 + float64(t)/float64(FireBurnoutHalflife)))
+func BurnoutChance(t int, halflife int) float64 {
+	return 1 - (1 / (1 + float64(t)/float64(halflife)))
 }
 
 func (game *Game) SpawnRandomFire() {
-	coord := game.GetRandomFlammableCoordinate()
-	game.world.content[coord] = &Fire{coord, 0}
+	coord, found := game.GetRandomFlammableCoordinate()
+	if !found {
+		return
+	}
+	game.world.content[coord] = NewFire(coord, game.FuelAt(coord))
+	game.stats.firesStarted++
 }
 
 func (game *Game) UpdateFire() int {
 	spreadAndSpawnCount := 0
 
-	// Check for spreading and burning out of existing fire.
-	for position, content := range game.world.content {
-		switch content := content.(type) {
-		case *Fire:
-			// Check for burnout
-			if rand.Float64() <= BurnoutChance(content.age) {
-				delete(game.world.content, position)
-				game.world.content[position] = Object{KeyBurnt, false, false, true}
-			}
+	// Only the fires burning at the start of the tick spread or burn out, and their changes are made once all of them
+	// have had their turn, so that fires handled earlier don't favour spreading right and down.
+	var fires []*Fire
+	for _, position := range game.world.Coordinates() {
+		if fire, isFire := game.world.content[position].(*Fire); isFire {
+			fires = append(fires, fire)
+		}
+	}
 
-			// Check for spreading
-			if rand.Float64() <= FireSpreadChance {
-				// Pick random direction
-				deltaX := 0
-				deltaY := 0
-				switch GetRandomDirection() {
-				case DirUp:
-					deltaY = -1
-				case DirRight:
-					deltaX = 1
-				case DirDown:
-					deltaY = 1
-				case DirLeft:
-					deltaX = -1
-				}
+	// Check for spreading and burning out of existing fire.
+	var burntOut, spreads []Coordinate
+	for _, fire := range fires {
+		// Check for burnout. Fires burning more fuel last longer.
+		halflife := int(float64(game.rules.FireBurnoutHalflife) * fire.fuel)
+		if halflife < 1 {
+			halflife = 1
+		}
+		if game.random.Float64() <= BurnoutChance(fire.age, halflife) {
+			burntOut = append(burntOut, fire.position)
+		}
 
-				// Check if blocked
-				spread := true
-				spreadCoordinate := Translate(position, deltaX, deltaY)
-				if existingContent, exists := game.world.content[spreadCoordinate]; exists {
-					switch existingContent := existingContent.(type) {
-					case Object:
-						if !existingContent.flammable {
-							spread = false
-						}
-					}
-				}
+		// Check for spreading
+		if game.random.Float64() <= game.rules.FireSpreadChance {
+			// Pick random direction
+			deltaX := 0
+			deltaY := 0
+			switch game.GetRandomDirection() {
+			case DirUp:
+				deltaY = -1
+			case DirRight:
+				deltaX = 1
+			case DirDown:
+				deltaY = 1
+			case DirLeft:
+				deltaX = -1
+			}
 
-				// Spread if not blocked
-				if spread {
-					game.world.content[spreadCoordinate] = &Fire{spreadCoordinate, 0}
-					spreadAndSpawnCount++
-				}
+			// Spread if not blocked
+			spreadCoordinate := Translate(fire.position, deltaX, deltaY)
+			if object, isObject := game.world.content[spreadCoordinate].(Object); !isObject || object.flammable {
+				spreads = append(spreads, spreadCoordinate)
 			}
+		}
 
-			// Increment age
-			content.age = content.age + 1
+		// Increment age
+		fire.age = fire.age + 1
+	}
+
+	for _, position := range spreads {
+		if _, isFire := game.world.content[position].(*Fire); !isFire {
+			game.stats.firesStarted++
 		}
+		game.world.content[position] = NewFire(position, game.FuelAt(position))
+		spreadAndSpawnCount++
+	}
+	for _, position := range burntOut {
+		game.world.content[position] = NewObject(game.settings.tiles, KeyBurnt)
+		game.stats.firesBurntOut++
 	}
 
 	// Check for spawning of new fires
-	if rand.Float64() <= FireSpawnChance {
+	if game.random.Float64() <= game.rules.FireSpawnChance {
 		game.SpawnRandomFire()
 	}
 
 	return spreadAndSpawnCount
 }
 
+func (game *Game) CountFires() int {
+	fireCount := 0
+	for _, content := range game.world.content {
+		if _, isFire := content.(*Fire); isFire {
+			fireCount++
+		}
+	}
+
+	return fireCount
+}
+
 func (game *Game) CheckFireDamage() int {
 	damage := 0
 
-	// Check if fire exists on player tile
-	if content, exists := game.world.content[game.player.position]; exists {
-		switch content.(type) {
-		case *Fire:
-			// Damage player
-			newHitPoints := game.player.hitPointsCurrent - DamageFire
-			if newHitPoints <= 0 {
-				game.exit = true
+	// Check if fire exists on player tiles
+	for _, player := range game.StandingPlayers() {
+		if content, exists := game.world.content[player.position]; exists {
+			switch content.(type) {
+			case *Fire:
+				// Damage player
+				player.hitPointsCurrent -= game.rules.DamageFire
+				if player.IsBurned() {
+					game.BurnPlayer(player)
+				}
+				damage++
 			}
-			game.player.hitPointsCurrent = newHitPoints
-			damage++
 		}
 	}
 
@@ -109,10 +141,11 @@ func (game *Game) CheckFireDamage() int {
 			switch content.(type) {
 			case *Fire:
 				// Damage squirrel
-				newHitPoints := squirrel.hitPointsCurrent - DamageFire
+				newHitPoints := squirrel.hitPointsCurrent - game.rules.DamageFire
 				if newHitPoints <= 0 {
 					// Delete squirrel
 					delete(game.squirrels, key)
+					game.stats.squirrelsLost++
 				} else {
 					squirrel.hitPointsCurrent = newHitPoints
 				}
@@ -124,29 +157,30 @@ func (game *Game) CheckFireDamage() int {
 	return damage
 }
 
-func (game *Game) Dig(dir int) int {
+func (game *Game) Dig(player *Actor, dir int) int {
 	// Determine which coordinates to check for digging based on direction and player position.
 	var targetCoordinates [4]Coordinate
 	switch dir {
 	case DirOmni:
-		targetCoordinates[0] = Translate(game.player.position, 0, -1)
-		targetCoordinates[1] = Translate(game.player.position, 1, 0)
-		targetCoordinates[2] = Translate(game.player.position, 0, 1)
-		targetCoordinates[3] = Translate(game.player.position, -1, 0)
+		targetCoordinates[0] = Translate(player.position, 0, -1)
+		targetCoordinates[1] = Translate(player.position, 1, 0)
+		targetCoordinates[2] = Translate(player.position, 0, 1)
+		targetCoordinates[3] = Translate(player.position, -1, 0)
 	case DirUp:
-		targetCoordinates[0] = Translate(game.player.position, 0, -1)
+		targetCoordinates[0] = Translate(player.position, 0, -1)
 	case DirRight:
-		targetCoordinates[0] = Translate(game.player.position, 1, 0)
+		targetCoordinates[0] = Translate(player.position, 1, 0)
 	case DirDown:
-		targetCoordinates[0] = Translate(game.player.position, 0, 1)
+		targetCoordinates[0] = Translate(player.position, 0, 1)
 	case DirLeft:
-		targetCoordinates[0] = Translate(game.player.position, -1, 0)
+		targetCoordinates[0] = Translate(player.position, -1, 0)
 	}
 
 	// Dig tiles that are within the target coordinate(s) and unblocked
 	dugCount := 0
 	for _, targetCoordinate := range targetCoordinates {
 		dig := true
+		extinguish := false
 		if content, exists := game.world.content[targetCoordinate]; exists {
 			switch content := content.(type) {
 			case Object:
@@ -155,11 +189,17 @@ func (game *Game) Dig(dir int) int {
 				}
 			case *Tree:
 				dig = false
+			case *Fire:
+				extinguish = true
 			}
 		}
 
 		if dig {
-			game.world.content[targetCoordinate] = Object{KeyFirebreak, false, false, false}
+			game.world.content[targetCoordinate] = NewObject(game.settings.tiles, KeyFirebreak)
+			game.stats.firebreaksDug++
+			if extinguish {
+				game.stats.firesExtinguished++
+			}
 			dugCount++
 		}
 	}
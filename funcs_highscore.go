@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Returns the path of the high score table in the user config directory.
+func HighScoreFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, HighScoreFileName), nil
+}
+
+// Reads all high scores, best first. A missing file has no scores.
+func LoadHighScores(fileName string) ([]HighScore, error) {
+	buffer, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var scores []HighScore
+	if err := json.Unmarshal(buffer, &scores); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	SortHighScores(scores)
+
+	return scores, nil
+}
+
+// Adds the score to the high score file, keeping the best HighScoreLimit scores for each map and game mode.
+// Other instances of the game may be writing at the same time, so the file is locked while it is updated.
+// Returns the rank of the score for its map and game mode, or 0 if it did not make the table.
+func RecordHighScore(fileName string, score HighScore) (int, error) {
+	unlock, err := LockFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	scores, err := LoadHighScores(fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	score.Date = score.Date.UTC().Truncate(time.Second)
+	scores = append(scores, score)
+	newIndex := len(scores) - 1
+	order := make([]int, len(scores)) // Sort indices rather than scores, to keep track of the new score
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return IsBetterHighScore(scores[order[i]], scores[order[j]])
+	})
+
+	rank := 0
+	kept := []HighScore{}
+	tableSizes := make(map[string]int)
+	for _, i := range order {
+		table := scores[i].Map + "/" + scores[i].Mode
+		if tableSizes[table] >= HighScoreLimit {
+			continue
+		}
+		tableSizes[table]++
+		kept = append(kept, scores[i])
+		if i == newIndex {
+			rank = tableSizes[table]
+		}
+	}
+
+	buffer, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	// Write to a temporary file and rename it, so readers never see a half-written table.
+	tempFile, err := os.CreateTemp(filepath.Dir(fileName), HighScoreFileName+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tempFile.Write(buffer); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return 0, err
+	}
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return 0, err
+	}
+	if err := os.Rename(tempFile.Name(), fileName); err != nil {
+		os.Remove(tempFile.Name())
+		return 0, err
+	}
+
+	return rank, nil
+}
+
+// Higher scores are better. Ties go to the game that lasted longer, and then to the earlier game.
+func IsBetterHighScore(a HighScore, b HighScore) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Ticks != b.Ticks {
+		return a.Ticks > b.Ticks
+	}
+
+	return a.Date.Before(b.Date)
+}
+
+func SortHighScores(scores []HighScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		return IsBetterHighScore(scores[i], scores[j])
+	})
+}
+
+// Returns the scores for the given map and game mode, best first.
+func HighScoresFor(scores []HighScore, mapName string, mode string) []HighScore {
+	var table []HighScore
+	for _, score := range scores {
+		if score.Map == mapName && score.Mode == mode {
+			table = append(table, score)
+		}
+	}
+
+	return table
+}
+
+// Returns each map and game mode that has scores, sorted by map and then mode.
+func HighScoreTables(scores []HighScore) [][2]string {
+	var tables [][2]string
+	seen := make(map[[2]string]bool)
+	for _, score := range scores {
+		table := [2]string{score.Map, score.Mode}
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i][0] != tables[j][0] {
+			return tables[i][0] < tables[j][0]
+		}
+		return tables[i][1] < tables[j][1]
+	})
+
+	return tables
+}
+
+// Creates a lock file next to the given file, waiting for any other instance holding it to finish.
+// Lock files left behind by a crashed instance are removed once they are stale.
+// Returns a function that releases the lock.
+func LockFile(fileName string) (func(), error) {
+	lockName := fileName + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockName), 0755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(HighScoreLockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lockFile.Close()
+			return func() { os.Remove(lockName) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockName); err == nil && time.Since(info.ModTime()) > HighScoreLockStale {
+			TakeStaleLock(lockName)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lockName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Removes a stale lock file, so that creating it can be tried again. The lock is first renamed to a name of its own,
+// which only one instance can do, and then checked again, as another instance may have taken the stale lock over in the
+// meantime. A lock that turns out to be fresh is put back, unless yet another lock has been created since.
+func TakeStaleLock(lockName string) {
+	staleName := fmt.Sprintf("%s.%d.%d", lockName, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockName, staleName); err != nil {
+		return // Another instance got to it first
+	}
+	if info, err := os.Stat(staleName); err == nil && time.Since(info.ModTime()) <= HighScoreLockStale {
+		os.Link(staleName, lockName)
+	}
+	os.Remove(staleName)
+}
+
+// Records the finished game in the high score table and remembers its rank for the results screen.
+// Each difficulty has its own table.
+func (game *Game) RecordHighScore() {
+	fileName, err := HighScoreFilePath()
+	if err == nil {
+		game.rank, err = RecordHighScore(fileName, HighScore{game.mapName, game.mode + " " + game.difficulty, game.Score(), game.ticks, time.Now(), game.seed})
+	}
+	game.rankErr = err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordHighScoreRanks(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "highscores.json")
+	date := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, score := range []int{5, 9, 7} {
+		if _, err := RecordHighScore(fileName, HighScore{"skog.karta", GameModeClassic, score, 100, date.Add(time.Duration(i) * time.Hour), int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rank, _ := RecordHighScore(fileName, HighScore{"skog.karta", GameModeClassic, 8, 100, date, 3}); rank != 2 {
+		t.Errorf("score of 8 should rank second, got %d", rank)
+	}
+	if rank, _ := RecordHighScore(fileName, HighScore{"flod.karta", GameModeClassic, 1, 100, date, 4}); rank != 1 {
+		t.Errorf("first score on another map should rank first, got %d", rank)
+	}
+
+	scores, err := LoadHighScores(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := HighScoresFor(scores, "skog.karta", GameModeClassic)
+	if len(table) != 4 || table[0].Score != 9 || table[3].Score != 5 {
+		t.Errorf("table should be sorted best first, got %+v", table)
+	}
+	if tables := HighScoreTables(scores); len(tables) != 2 || tables[0][0] != "flod.karta" {
+		t.Errorf("expected tables for two maps, got %v", tables)
+	}
+}
+
+// Many instances finishing at once must not lose each other's scores.
+func TestRecordHighScoreConcurrently(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "highscores.json")
+	writers := HighScoreLimit * 2
+
+	var wg sync.WaitGroup
+	ranks := make([]int, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			ranks[i], err = RecordHighScore(fileName, HighScore{"skog.karta", GameModeClassic, i, i, time.Now(), int64(i)})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	scores, err := LoadHighScores(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != HighScoreLimit {
+		t.Fatalf("expected %d scores to be kept, got %d", HighScoreLimit, len(scores))
+	}
+	for rank, score := range scores {
+		if score.Score != writers-1-rank {
+			t.Errorf("rank %d should have score %d, got %d", rank+1, writers-1-rank, score.Score)
+		}
+	}
+}
+
+// A lock left behind by a crashed instance is taken over, but a lock in use is not.
+func TestLockFileTakesOverStaleLocks(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "highscores.json")
+	lockName := fileName + ".lock"
+	if err := os.WriteFile(lockName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	TakeStaleLock(lockName)
+	if _, err := os.Stat(lockName); err != nil {
+		t.Errorf("a fresh lock should be put back, got %v", err)
+	}
+
+	stale := time.Now().Add(-2 * HighScoreLockStale)
+	if err := os.Chtimes(lockName, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := LockFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	entries, err := os.ReadDir(filepath.Dir(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no lock files left, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Splits the screen into the viewport and the HUD panel so the two never overlap.
+// Wide screens get a panel on the right side, narrow ones get a panel along the bottom.
+func (game *Game) UpdateLayout() {
+	w, h := game.screen.Size()
+	if w >= HudSideMinScreenWidth {
+		game.menu.layout = HudLayoutSide
+		game.menu.width = HudSideWidth
+		game.menu.height = h
+		game.menu.position = Coordinate{w - HudSideWidth, 0}
+		game.viewport = Viewport{w - HudSideWidth, h, Coordinate{0, 0}}
+	} else {
+		menuHeight := HudBottomHeight
+		if menuHeight > h {
+			menuHeight = h
+		}
+		game.menu.layout = HudLayoutBottom
+		game.menu.width = w
+		game.menu.height = menuHeight
+		game.menu.position = Coordinate{0, h - menuHeight}
+		game.viewport = Viewport{w, h - menuHeight, Coordinate{0, 0}}
+	}
+}
+
+// Returns the lines shown in the HUD, in display order. With several players, each has their hitpoints and score shown
+// instead of the inventory, to fit in the same space, under their name in a network game. Spectators see every player
+// that way, below who they are watching.
+func (game *Game) HudLines() []HudLine {
+	barWidth := HudBarWidth
+	if game.menu.layout == HudLayoutBottom {
+		barWidth = HudBottomBarWidth
+	}
+	lines := []HudLine{
+		{"HP", HitPointsBar(game.player.hitPointsCurrent, game.player.hitPointsMax, barWidth)},
+		{"Score", PlainSegments(strconv.Itoa(game.player.score))},
+		{"Wood", PlainSegments(strconv.Itoa(game.player.inventory.wood))},
+		{"Seeds", PlainSegments(strconv.Itoa(game.player.inventory.seeds))},
+	}
+	if len(game.others) > 0 || game.spectator != nil {
+		lines = nil
+		for i, player := range game.Players() {
+			if game.spectator != nil && i >= len(game.names) { // Nobody to watch yet
+				break
+			}
+			prefix := "P" + strconv.Itoa(i+1) + " "
+			if i < len(game.names) {
+				prefix = game.names[i] + " "
+			}
+			lines = append(lines,
+				HudLine{prefix + "HP", HitPointsBar(player.hitPointsCurrent, player.hitPointsMax, barWidth)},
+				HudLine{prefix + "Score", PlainSegments(strconv.Itoa(player.score))},
+			)
+		}
+	}
+	if game.spectator != nil {
+		lines = append([]HudLine{{"Watching", PlainSegments(game.spectator.Watching())}}, lines...)
+	}
+
+	return append(lines, []HudLine{
+		{"Fires", PlainSegments(strconv.Itoa(game.CountFires()))},
+		{"Forest", PlainSegments(fmt.Sprintf("%.0f%%", 100*game.ForestCover()))},
+		{"Squirrels", PlainSegments(strconv.Itoa(len(game.squirrels)))},
+		{"Time", PlainSegments(strconv.Itoa(game.ticks))},
+	}...)
+}
+
+// Returns the widths of the columns of a bottom panel, whose lines fill the rows of each column in turn. Each column is
+// as wide as its widest line and a space, with the widest columns cut down until they all fit in the width.
+func HudColumnWidths(lines []HudLine, rows int, width int) []int {
+	var widths []int
+	for i, line := range lines {
+		if i%rows == 0 {
+			widths = append(widths, 0)
+		}
+		if lineWidth := line.Width() + 1; lineWidth > widths[len(widths)-1] {
+			widths[len(widths)-1] = lineWidth
+		}
+	}
+
+	limit := width
+	for ; limit > 0; limit-- {
+		total := 0
+		for _, columnWidth := range widths {
+			if columnWidth > limit {
+				columnWidth = limit
+			}
+			total += columnWidth
+		}
+		if total <= width {
+			break
+		}
+	}
+	for i := range widths {
+		if widths[i] > limit {
+			widths[i] = limit
+		}
+	}
+
+	return widths
+}
+
+// Returns the number of cells the line takes up when drawn in full.
+func (line HudLine) Width() int {
+	width := 0
+	if line.label != "" {
+		width += utf8.RuneCountInString(line.label + ": ")
+	}
+	for _, segment := range line.segments {
+		width += utf8.RuneCountInString(segment.text)
+	}
+
+	return width
+}
+
+func PlainSegments(text string) []HudSegment {
+	return []HudSegment{{text, tcell.StyleDefault}}
+}
+
+// Returns a coloured bar of the given width showing current out of max hitpoints, followed by the numbers themselves.
+func HitPointsBar(current int, max int, width int) []HudSegment {
+	if max <= 0 {
+		return PlainSegments(strconv.Itoa(current))
+	}
+
+	filled := 0
+	if current > 0 {
+		filled = (current*width + max - 1) / max // Round up so any remaining hitpoints are visible
+	}
+	if filled > width {
+		filled = width
+	}
+
+	color := tcell.ColorGreen
+	if 3*current <= max {
+		color = tcell.ColorRed
+	} else if 3*current <= 2*max {
+		color = tcell.ColorYellow
+	}
+
+	return []HudSegment{
+		{strings.Repeat("█", filled), tcell.StyleDefault.Foreground(color)},
+		{strings.Repeat("░", width-filled), tcell.StyleDefault.Foreground(tcell.ColorDarkGray)},
+		{" " + strconv.Itoa(current) + "/" + strconv.Itoa(max), tcell.StyleDefault},
+	}
+}
+
+// Writes text starting at (x, y), cutting it off after maxWidth cells. Returns the number of cells written.
+func (game *Game) DrawText(x int, y int, maxWidth int, text string, style tcell.Style) int {
+	written := 0
+	for _, r := range text {
+		if written >= maxWidth {
+			break
+		}
+		game.renderer.SetContent(x+written, y, r, style)
+		written++
+	}
+
+	return written
+}
+
+func (game *Game) DrawHudLine(x int, y int, maxWidth int, line HudLine) {
+	written := 0
+	if line.label != "" {
+		written += game.DrawText(x, y, maxWidth, line.label+": ", tcell.StyleDefault.Bold(true))
+	}
+	for _, segment := range line.segments {
+		written += game.DrawText(x+written, y, maxWidth-written, segment.text, segment.style)
+	}
+}
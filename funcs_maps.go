@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed kartor/*.karta
+var builtinMaps embed.FS
+
+// Returns where maps are looked for, in order: the maps directory from the settings,
+// the maps directory in the user config directory, and the maps built into the game.
+// A valid map in an earlier source hides maps with the same file name in later ones.
+func (settings *Settings) MapSources() []MapSource {
+	var sources []MapSource
+	if settings.MapsDir != "" {
+		sources = append(sources, MapSource{settings.MapsDir, os.DirFS(settings.MapsDir), settings.tiles})
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		sources = append(sources, MapSource{MapSourceUser, os.DirFS(filepath.Join(configDir, ConfigDirName, MapsDirName)), settings.tiles})
+	}
+	builtin, _ := fs.Sub(builtinMaps, MapsDirName)
+	sources = append(sources, MapSource{MapSourceBuiltin, builtin, settings.tiles})
+
+	return sources
+}
+
+// Lists the valid maps in the given sources. Files that aren't maps, or maps that can't be read, are left out.
+func FindMaps(sources []MapSource) []MapFile {
+	var mapFiles []MapFile
+	found := make(map[string]bool)
+	for _, source := range sources {
+		entries, err := fs.ReadDir(source.fsys, ".")
+		if err != nil {
+			continue // Missing directories just have no maps
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != MapFileExtension || found[entry.Name()] {
+				continue
+			}
+			mapFile, err := source.Open(entry.Name())
+			if err != nil {
+				continue
+			}
+			mapFiles = append(mapFiles, mapFile)
+			found[entry.Name()] = true
+		}
+	}
+
+	return mapFiles
+}
+
+// Returns the map with the given file name from the first source that has a valid one, like FindMaps lists it.
+// An error is only returned when no source has it, and then names every invalid one.
+func FindMap(sources []MapSource, fileName string) (MapFile, error) {
+	var errs []string
+	for _, source := range sources {
+		mapFile, err := source.Open(fileName)
+		if err == nil {
+			return mapFile, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return MapFile{}, fmt.Errorf("map %q: %s", fileName, strings.Join(errs, "; "))
+	}
+	return MapFile{}, fmt.Errorf("map %q not found", fileName)
+}
+
+// Reads and checks the map with the given file name in the source.
+func (source MapSource) Open(fileName string) (MapFile, error) {
+	data, err := fs.ReadFile(source.fsys, fileName)
+	if err != nil {
+		return MapFile{}, err
+	}
+	if _, _, _, err := ParseMap(data, source.tiles); err != nil {
+		return MapFile{}, fmt.Errorf("%s (%s): %v", fileName, source.name, err)
+	}
+
+	return MapFile{fileName, source.name, data, ParseMapMetadata(data)}, nil
+}
+
+// Returns the name shown for the map: the name in its metadata, or else its file name without the extension.
+func (mapFile MapFile) Title() string {
+	if mapFile.metadata.name != "" {
+		return mapFile.metadata.name
+	}
+
+	return strings.TrimSuffix(mapFile.fileName, MapFileExtension)
+}
+
+// Reads the metadata lines at the top of a map file, e.g. "; name: Liten skog".
+func ParseMapMetadata(data []byte) MapMetadata {
+	var metadata MapMetadata
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for lines.Scan() {
+		line := lines.Text()
+		if !strings.HasPrefix(line, MapMetadataPrefix) {
+			break
+		}
+
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, MapMetadataPrefix), ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			metadata.name = value
+		case "author":
+			metadata.author = value
+		case "description":
+			metadata.description = value
+		}
+	}
+
+	return metadata
+}
+
+// Reads the world, the player position and the squirrel positions from the contents of a map file, by the map
+// characters of the tiles in the registry. Metadata lines at the top of the file are skipped.
+func ParseMap(data []byte, registry map[int]Tile) (World, Coordinate, []Coordinate, error) {
+	worldContent := make(map[Coordinate]interface{})
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	lines.Split(bufio.ScanLines)
+	width := 0
+	height := 0
+	players := 0
+	inMetadata := true
+	var playerPosition Coordinate
+	var squirrelPositions []Coordinate
+	mapChars := TileKeysByMapChar(registry)
+	for lines.Scan() {
+		if inMetadata && strings.HasPrefix(lines.Text(), MapMetadataPrefix) {
+			continue
+		}
+		inMetadata = false
+
+		// Check if width needs to be updated. It's determined by the longest line.
+		line := []rune(lines.Text())
+		lineWidth := len(line)
+		if lineWidth > width {
+			width = lineWidth
+		}
+		if width > MapMaxWidth || height >= MapMaxHeight {
+			return World{}, playerPosition, nil, fmt.Errorf("map must be at most %d by %d tiles", MapMaxWidth, MapMaxHeight)
+		}
+
+		// Update the worldContent map according to the map characters of the tiles.
+		for i := 0; i < lineWidth; i++ {
+			key, isTile := mapChars[line[i]]
+			if !isTile {
+				continue
+			}
+			switch key {
+			case KeyPlayer:
+				playerPosition = Coordinate{i, height}
+				players++
+			case KeySquirrel:
+				squirrelPositions = append(squirrelPositions, Coordinate{i, height})
+			case KeyFireType1, KeyFireType2:
+				worldContent[Coordinate{i, height}] = NewFire(Coordinate{i, height}, 1)
+			default:
+				worldContent[Coordinate{i, height}] = NewObject(registry, key)
+			}
+		}
+
+		// Increment the height once for each row.
+		height++
+	}
+
+	if err := lines.Err(); err != nil {
+		return World{}, playerPosition, nil, err
+	}
+	if players != 1 {
+		return World{}, playerPosition, nil, fmt.Errorf("map must have exactly one player, found %d", players)
+	}
+
+	return NewWorld(width, height, worldContent), playerPosition, squirrelPositions, nil
+}
+
+// Writes a map file with the given metadata, world, player and squirrels, which ParseMap reads back the same.
+// Fire is written as the first fire tile, and content without a map character, like trees, is left out.
+func ExportMap(metadata MapMetadata, world World, playerPosition Coordinate, squirrelPositions []Coordinate, registry map[int]Tile) []byte {
+	var data bytes.Buffer
+	for _, field := range [][2]string{{"name", metadata.name}, {"author", metadata.author}, {"description", metadata.description}} {
+		if field[1] != "" {
+			fmt.Fprintf(&data, "%s %s: %s\n", MapMetadataPrefix, field[0], field[1])
+		}
+	}
+
+	squirrels := make(map[Coordinate]bool)
+	for _, position := range squirrelPositions {
+		squirrels[position] = true
+	}
+	for y := 0; y < world.height; y++ {
+		for x := 0; x < world.width; x++ {
+			coord := Coordinate{x, y}
+			char := ' '
+			switch content := world.content[coord].(type) {
+			case Object:
+				if mapChar := registry[content.key].mapChar; mapChar != 0 {
+					char = mapChar
+				}
+			case *Fire:
+				char = registry[KeyFireType1].mapChar
+			}
+			if coord == playerPosition {
+				char = registry[KeyPlayer].mapChar
+			} else if squirrels[coord] {
+				char = registry[KeySquirrel].mapChar
+			}
+			data.WriteRune(char)
+		}
+		data.WriteString("\n")
+	}
+
+	return data.Bytes()
+}
+
+// Reads the world, the player position and the squirrel positions from the map, with the tiles of the registry.
+func (mapFile MapFile) Read(registry map[int]Tile) (World, Coordinate, []Coordinate, error) {
+	world, playerPosition, squirrelPositions, err := ParseMap(mapFile.data, registry)
+	if err != nil {
+		return world, playerPosition, squirrelPositions, fmt.Errorf("%s: %v", mapFile.fileName, err)
+	}
+
+	return world, playerPosition, squirrelPositions, nil
+}
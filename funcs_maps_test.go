@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFindMapsListsOnlyValidMaps(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"skog.karta":     "; name: Min skog\n#####\n#p s#\n#####\n", // Hides the built-in skog.karta
+		"egen.karta":     "#####\n# p #\n#####\n",
+		"utan.karta":     "#####\n#   #\n#####\n", // No player
+		"anteckning.txt": "not a map",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	settings := DefaultSettings()
+	settings.MapsDir = dir
+
+	found := make(map[string]MapFile)
+	for _, mapFile := range FindMaps(settings.MapSources()) {
+		if _, duplicate := found[mapFile.fileName]; duplicate {
+			t.Errorf("%s listed twice", mapFile.fileName)
+		}
+		found[mapFile.fileName] = mapFile
+	}
+
+	for _, name := range []string{"utan.karta", "anteckning.txt"} {
+		if _, listed := found[name]; listed {
+			t.Errorf("%s should not be listed", name)
+		}
+	}
+	if mapFile := found["skog.karta"]; mapFile.source != dir || mapFile.Title() != "Min skog" {
+		t.Errorf("skog.karta should come from the maps directory, got %q from %q", mapFile.Title(), mapFile.source)
+	}
+	if mapFile := found["egen.karta"]; mapFile.Title() != "egen" {
+		t.Errorf("map without a name should be titled by file name, got %q", mapFile.Title())
+	}
+	if mapFile := found["liten_skog.karta"]; mapFile.source != MapSourceBuiltin || mapFile.Title() != "Liten skog" {
+		t.Errorf("built-in maps should be listed, got %q from %q", mapFile.Title(), mapFile.source)
+	}
+}
+
+func TestFindMapReportsInvalidMaps(t *testing.T) {
+	sources := []MapSource{{"test", fstest.MapFS{"tom.karta": {Data: []byte("###\n")}}, DefaultTiles()}}
+	if _, err := FindMap(sources, "tom.karta"); err == nil {
+		t.Errorf("expected an error for a map without a player")
+	}
+	if _, err := FindMap(sources, "saknas.karta"); err == nil {
+		t.Errorf("expected an error for a missing map")
+	}
+}
+
+func TestFindMapSkipsInvalidMaps(t *testing.T) {
+	sources := []MapSource{
+		{"first", fstest.MapFS{"skog.karta": {Data: []byte("###\n")}}, DefaultTiles()},
+		{"second", fstest.MapFS{"skog.karta": {Data: []byte("#####\n# p #\n#####\n")}}, DefaultTiles()},
+	}
+	if mapFile, err := FindMap(sources, "skog.karta"); err != nil || mapFile.source != "second" {
+		t.Errorf("expected the valid map from the second source, got %q and %v", mapFile.source, err)
+	}
+}
+
+func TestParseMapMetadata(t *testing.T) {
+	data := []byte("; name: Sjön\n;author: Anna\n; description: Water: lots of it\n#####\n#p  #\n; not metadata\n")
+	metadata := ParseMapMetadata(data)
+	if metadata.name != "Sjön" || metadata.author != "Anna" || metadata.description != "Water: lots of it" {
+		t.Errorf("metadata was not parsed, got %+v", metadata)
+	}
+
+	world, player, _, err := ParseMap(data, DefaultTiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world.height != 3 || player != (Coordinate{1, 1}) {
+		t.Errorf("metadata lines should not be part of the world, got height %d and player at %v", world.height, player)
+	}
+}
+
+func TestParseMapRejectsHugeMaps(t *testing.T) {
+	for _, data := range []string{"p" + strings.Repeat(" ", MapMaxWidth), "p" + strings.Repeat("\n#", MapMaxHeight)} {
+		if _, _, _, err := ParseMap([]byte(data), DefaultTiles()); err == nil {
+			t.Errorf("expected an error for a map of %d bytes over the largest size", len(data))
+		}
+	}
+	data := "p" + strings.Repeat(" ", MapMaxWidth-1) + strings.Repeat("\n#", MapMaxHeight-1)
+	if world, _, _, err := ParseMap([]byte(data), DefaultTiles()); err != nil || world.width != MapMaxWidth || world.height != MapMaxHeight {
+		t.Errorf("expected a map of the largest size to be read, got %dx%d and %v", world.width, world.height, err)
+	}
+}
+
+// Run with go test -fuzz FuzzParseMap to search for more inputs than the seeds.
+func FuzzParseMap(f *testing.F) {
+	entries, err := fs.ReadDir(builtinMaps, MapsDirName)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(builtinMaps, MapsDirName+"/"+entry.Name())
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("p"))
+	f.Add([]byte("; name: Tom\n;\n\n p\n\n"))
+	f.Add([]byte("#p\r\n; not metadata\n\xff fs\nW"))
+	f.Add([]byte("pp"))
+
+	registry := DefaultTiles()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > (MapMaxWidth+1)*MapMaxHeight { // Larger than any map that is read
+			return
+		}
+		world, player, squirrels, err := ParseMap(data, registry)
+		if err != nil {
+			return
+		}
+		metadata := ParseMapMetadata(data)
+
+		exported := ExportMap(metadata, world, player, squirrels, registry)
+		exportedWorld, exportedPlayer, exportedSquirrels, err := ParseMap(exported, registry)
+		if err != nil {
+			t.Fatalf("exported map can't be read back: %v\n%s", err, exported)
+		}
+		if !reflect.DeepEqual(exportedWorld, world) || exportedPlayer != player || !reflect.DeepEqual(exportedSquirrels, squirrels) {
+			t.Errorf("exported map differs when read back:\n%s", exported)
+		}
+		if exportedMetadata := ParseMapMetadata(exported); exportedMetadata != metadata {
+			t.Errorf("expected metadata %+v, got %+v", metadata, exportedMetadata)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Converts a screen coordinate to a world coordinate.
+// Returns false if the screen coordinate is outside the cameras' viewports, the vision of their players or the world.
+func (game *Game) ScreenToWorld(screenCoord Coordinate) (Coordinate, bool) {
+	for _, camera := range game.Cameras() {
+		if !camera.viewport.Contains(screenCoord) {
+			continue
+		}
+
+		worldCoord := camera.ScreenToWorld(screenCoord)
+		if !camera.CanSee(worldCoord) || worldCoord.x < 0 || worldCoord.y < 0 || worldCoord.x >= game.world.width || worldCoord.y >= game.world.height {
+			return Coordinate{}, false
+		}
+		return worldCoord, true
+	}
+
+	return Coordinate{}, false
+}
+
+// Returns the player the mouse controls at the screen coordinate: the one followed by the split screen camera it is
+// in, or the first player elsewhere.
+func (game *Game) MousePlayer(screenCoord Coordinate) *Actor {
+	for _, camera := range game.Cameras() {
+		if camera.viewport.Contains(screenCoord) && len(camera.viewers) == 1 {
+			return camera.viewers[0]
+		}
+	}
+
+	return &game.player
+}
+
+// Left-clicking an adjacent tree chops it, left-clicking anywhere else walks there.
+// Right-clicking an adjacent tile digs it. Returns true if the click chopped or dug something.
+func (game *Game) HandleMouseEvent(ev *tcell.EventMouse) bool {
+	x, y := ev.Position()
+	pressed := ev.Buttons() &^ game.mouse.buttons // Only buttons that went down with this event, not ones held since before
+	game.mouse.buttons = ev.Buttons()
+	game.mouse.position = Coordinate{x, y}
+
+	target, inWorld := game.ScreenToWorld(game.mouse.position)
+	game.mouse.hovering = inWorld
+	if !inWorld {
+		return false
+	}
+
+	player := game.MousePlayer(game.mouse.position)
+	dir := AdjacentDirection(player.position, target)
+	if pressed&tcell.Button1 != 0 {
+		if _, isTree := game.world.content[target].(*Tree); isTree && dir != DirNone {
+			player.path = nil
+			return game.Chop(player, dir, 1) > 0
+		}
+		game.WalkTo(player, target)
+	} else if pressed&tcell.Button3 != 0 && dir != DirNone {
+		player.path = nil
+		return game.Dig(player, dir) > 0
+	}
+
+	return false
+}
+
+// Returns true if any player is walking to a clicked tile.
+func (game *Game) IsWalking() bool {
+	for _, player := range game.Players() {
+		if len(player.path) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Finds a path for the player to the target and starts walking it.
+// Each step is taken on an interrupt event, so walking stops as soon as the player presses a key.
+func (game *Game) WalkTo(player *Actor, target Coordinate) {
+	player.path = nil
+	if game.IsPathBlocked(player.position) || game.IsPathBlocked(target) {
+		return
+	}
+
+	path := game.FindPath(player.position, target)
+	if path[1] == player.position { // No path found
+		return
+	}
+
+	walking := game.IsWalking() // Another player's steps are already being posted, and this one's are taken with them
+	player.destination = target
+	player.path = path
+	if !walking {
+		game.screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+}
+
+// Moves every player walking to a clicked tile one step along their path. Returns true if any player moved.
+func (game *Game) WalkPlayers() bool {
+	moved := false
+	for _, player := range game.Players() {
+		if len(player.path) > 0 && game.WalkPlayer(player) {
+			moved = true
+		}
+	}
+	if game.IsWalking() {
+		game.screen.PostEvent(tcell.NewEventInterrupt(nil))
+	}
+
+	return moved
+}
+
+// Moves the player one step along its path. Returns true if the player moved.
+func (game *Game) WalkPlayer(player *Actor) bool {
+	next, exists := player.path[1]
+	if !exists || game.IsPathBlocked(next) {
+		player.path = nil
+		return false
+	}
+
+	dir := AdjacentDirection(player.position, next)
+	if dir == DirNone || !game.MovePlayer(player, dir) {
+		player.path = nil
+		return false
+	}
+
+	player.AdvancePath()
+	return true
+}
+
+// Describes what is at the world coordinate, for the tooltip shown when hovering over it.
+func (game *Game) DescribeTile(coord Coordinate) string {
+	for i, player := range game.Players() {
+		if coord == player.position && !(player.IsBurned() && len(game.others) > 0) {
+			return game.settings.tiles[PlayerTileKey(i)].name + ", HP " + strconv.Itoa(player.hitPointsCurrent) + "/" + strconv.Itoa(player.hitPointsMax)
+		}
+	}
+	for _, squirrel := range game.squirrels {
+		if coord == squirrel.position {
+			return game.settings.tiles[KeySquirrel].name + ", HP " + strconv.Itoa(squirrel.hitPointsCurrent) + "/" + strconv.Itoa(squirrel.hitPointsMax)
+		}
+	}
+
+	switch content := game.world.content[coord].(type) {
+	case Object:
+		return game.settings.tiles[content.key].name
+	case *Tree:
+		return treeStateNames[content.state]
+	case *Fire:
+		return fmt.Sprintf("%s, burning for %d ticks", game.settings.tiles[KeyFireType1].name, content.age)
+	}
+
+	return "Ground"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newMouseTestGame(t *testing.T) (*Game, tcell.SimulationScreen) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(40, 20)
+
+	settings := DefaultSettings()
+	mapFile, err := FindMap(settings.MapSources(), "liten_skog.karta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, playerPosition, _, err := mapFile.Read(settings.tiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := &Game{screen: screen, random: rand.New(rand.NewSource(1)), settings: &settings, rules: rulesetPresets[DifficultyNormal], world: world, squirrels: map[int]*Actor{}}
+	game.player = Actor{position: playerPosition, visionRadius: 100, hitPointsCurrent: MaxHitPointsPlayer, hitPointsMax: MaxHitPointsPlayer}
+	game.UpdateLayout()
+
+	return game, screen
+}
+
+// Converts a world coordinate to the screen coordinate it is drawn at.
+func worldToScreen(game *Game, coord Coordinate) Coordinate {
+	playerViewportCoord := Translate(game.viewport.position, game.viewport.width/2, game.viewport.height/2)
+	return Translate(playerViewportCoord, coord.x-game.player.position.x, coord.y-game.player.position.y)
+}
+
+func TestClickToWalk(t *testing.T) {
+	game, screen := newMouseTestGame(t)
+	target := Translate(game.player.position, 3, 1)
+	click := worldToScreen(game, target)
+
+	screen.InjectMouse(click.x, click.y, tcell.Button1, tcell.ModNone)
+	for i := 0; i < 10 && game.player.position != target; i++ {
+		game.HandleEvent(screen.PollEvent())
+	}
+	if game.player.position != target {
+		t.Errorf("player should have walked to %v, is at %v", target, game.player.position)
+	}
+}
+
+func TestClickToChopAndDig(t *testing.T) {
+	game, _ := newMouseTestGame(t)
+	tree := Translate(game.player.position, 1, 0)
+	game.world.content[tree] = &Tree{tree, TreeStateSeed}
+	click := worldToScreen(game, tree)
+
+	if !game.HandleMouseEvent(tcell.NewEventMouse(click.x, click.y, tcell.Button1, tcell.ModNone)) {
+		t.Errorf("clicking an adjacent seed should chop it")
+	}
+	if _, exists := game.world.content[tree]; exists {
+		t.Errorf("seed should have been removed")
+	}
+	if game.HandleMouseEvent(tcell.NewEventMouse(click.x, click.y, tcell.Button1, tcell.ModNone)) {
+		t.Errorf("holding the button should not count as another click")
+	}
+
+	game.HandleMouseEvent(tcell.NewEventMouse(click.x, click.y, tcell.ButtonNone, tcell.ModNone))
+	if !game.HandleMouseEvent(tcell.NewEventMouse(click.x, click.y, tcell.Button3, tcell.ModNone)) {
+		t.Errorf("right-clicking an adjacent tile should dig it")
+	}
+	if game.DescribeTile(tree) != game.settings.tiles[KeyFirebreak].name {
+		t.Errorf("tile should be described as a firebreak, got %q", game.DescribeTile(tree))
+	}
+}
+
+func TestOnlyActionsTakeUpATick(t *testing.T) {
+	game, _ := newMouseTestGame(t)
+	controls, _ := NewControls(PresetDefault)
+	game.controls = &controls
+
+	if game.HandleEvent(tcell.NewEventResize(50, 20)) {
+		t.Errorf("resizing the terminal shouldn't take up a tick")
+	}
+	if game.HandleEvent(tcell.NewEventKey(tcell.KeyRune, '§', tcell.ModNone)) {
+		t.Errorf("a key bound to nothing shouldn't take up a tick")
+	}
+	if !game.HandleEvent(tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone)) {
+		t.Errorf("moving should take up a tick")
+	}
+}
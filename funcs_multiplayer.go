@@ -0,0 +1,151 @@
+package main
+
+import "strconv"
+
+// Returns every player, starting with player one.
+func (game *Game) Players() []*Actor {
+	return append([]*Actor{&game.player}, game.others...)
+}
+
+// Returns the players that haven't burned yet.
+func (game *Game) StandingPlayers() []*Actor {
+	var standing []*Actor
+	for _, player := range game.Players() {
+		if !player.IsBurned() {
+			standing = append(standing, player)
+		}
+	}
+
+	return standing
+}
+
+func (actor *Actor) IsBurned() bool {
+	return actor.hitPointsCurrent <= 0
+}
+
+// Returns the player whose key the action is bound to, and the action as player one's, e.g. ActionMoveUp for
+// ActionPlayer2MoveUp. Returns nil if that player isn't in the game.
+func (game *Game) PlayerAction(action int) (*Actor, int) {
+	if action < ActionPlayer2MoveUp || action > ActionPlayer2DigOmni {
+		return &game.player, action
+	}
+	if len(game.others) == 0 {
+		return nil, ActionNone
+	}
+
+	return game.others[0], action - ActionPlayer2MoveUp + ActionMoveUp
+}
+
+// Returns the name of the player for messages and the results screen, e.g. "Player 2", or the name a player
+// joined a network game with.
+func (game *Game) PlayerName(player *Actor) string {
+	for i, other := range game.Players() {
+		if other == player && i < len(game.names) {
+			return game.names[i]
+		} else if other == player {
+			return "Player " + strconv.Itoa(i+1)
+		}
+	}
+
+	return "Player"
+}
+
+// Returns the tile the player is drawn with. Players beyond the local ones, who joined over the network,
+// share the last tile.
+func PlayerTileKey(index int) int {
+	if index >= len(playerTileKeys) {
+		index = len(playerTileKeys) - 1
+	}
+
+	return playerTileKeys[index]
+}
+
+// Takes a player who left out of the game. The next player takes player one's place if player one left.
+func (game *Game) RemovePlayer(player *Actor) {
+	if player == &game.player {
+		if len(game.others) > 0 {
+			game.player = *game.others[0]
+			game.others = game.others[1:]
+		}
+		return
+	}
+
+	for i, other := range game.others {
+		if other == player {
+			game.others = append(game.others[:i:i], game.others[i+1:]...)
+			return
+		}
+	}
+}
+
+// Returns the free tile nearest to the start, for a player who has no place on the map. Tiles with another player on
+// them aren't free. Returns the start itself if there is no free tile to walk to.
+func (game *Game) SpawnPosition(start Coordinate) Coordinate {
+	path := game.FindPathTo(start, func(coord Coordinate) bool {
+		for _, player := range game.Players() {
+			if player.position == coord {
+				return false
+			}
+		}
+		return true
+	})
+
+	return path[len(path)]
+}
+
+// Takes a player out of the game after burning. A single player's game ends right away. Co-op games end once every
+// player has burned, and competitive games once at most one player is left standing.
+func (game *Game) BurnPlayer(player *Actor) {
+	if len(game.others) == 0 {
+		game.exit = true
+		game.endCause = EndCauseBurned
+		return
+	}
+
+	game.AppendToMenuMessages(game.PlayerName(player) + " burned")
+	standing := len(game.StandingPlayers())
+	if standing == 0 || (game.mode == GameModeVersus && standing == 1) {
+		game.exit = true
+		game.endCause = EndCauseBurned
+	}
+}
+
+// Returns the score the game is ranked by: the player's score, the sum of the scores in a co-op game,
+// or the winner's score in a competitive game.
+func (game *Game) Score() int {
+	switch game.mode {
+	case GameModeCoop:
+		score := 0
+		for _, player := range game.Players() {
+			score += player.score
+		}
+		return score
+	case GameModeVersus:
+		if winner, found := game.Winner(); found {
+			return winner.score
+		}
+		return 0
+	}
+
+	return game.player.score
+}
+
+// Returns the winner of a competitive game: the last player standing, or if every player burned, the one with the
+// highest score. Returns false for a draw.
+func (game *Game) Winner() (*Actor, bool) {
+	if standing := game.StandingPlayers(); len(standing) == 1 {
+		return standing[0], true
+	}
+
+	var winner *Actor
+	draw := false
+	for _, player := range game.Players() {
+		if winner == nil || player.score > winner.score {
+			winner, draw = player, false
+		} else if player.score == winner.score {
+			draw = true
+		}
+	}
+
+	return winner, !draw
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Adds another player at the given position, and switches the game to the given mode and camera.
+func addPlayer(game *Game, position Coordinate, mode string, camera string) *Actor {
+	player := &Actor{position: position, visionRadius: 100, hitPointsCurrent: MaxHitPointsPlayer, hitPointsMax: MaxHitPointsPlayer}
+	game.others = append(game.others, player)
+	game.mode = mode
+	game.camera = camera
+
+	return player
+}
+
+func TestPlayerTwoKeys(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, ""+
+		"########\n"+
+		"#pT  T #\n"+
+		"########")
+	two := addPlayer(game, Coordinate{6, 1}, GameModeCoop, CameraSplit)
+	controls, _ := NewControls(PresetDefault)
+	game.controls = &controls
+
+	for _, key := range []rune{'j', 'j', 'j', '4', '4'} {
+		game.HandleAction(controls.lookup[string(key)])
+	}
+
+	if two.score != 1 || game.player.score != 0 {
+		t.Errorf("expected player two to be credited with the tree, got scores %d and %d", game.player.score, two.score)
+	}
+	if two.position != (Coordinate{4, 1}) {
+		t.Errorf("expected player two to walk through the tree's place, got %v", two.position)
+	}
+	if _, isTree := game.world.content[Coordinate{2, 1}].(*Tree); !isTree {
+		t.Errorf("expected player one's tree to be left standing")
+	}
+}
+
+func TestPresetsGivePlayerTwoOtherKeys(t *testing.T) {
+	for preset := range presetBindings {
+		if _, err := NewControls(preset); err != nil {
+			t.Errorf("expected every key of the %s preset to do one thing, got %v", preset, err)
+		}
+	}
+
+	controls, _ := NewControls(PresetVi)
+	if action := controls.lookup["j"]; action != ActionMoveDown {
+		t.Errorf("expected j to move player one down with the vi preset, got %s", actionNames[action])
+	}
+	if action := controls.lookup["7"]; action != ActionPlayer2ChopUp {
+		t.Errorf("expected player two to chop up with 7 with the vi preset, got %s", actionNames[action])
+	}
+	controls, _ = NewControls(PresetNumpad)
+	if action := controls.lookup["t"]; action != ActionPlayer2MoveUp {
+		t.Errorf("expected player two to move up with t with the numpad preset, got %s", actionNames[action])
+	}
+}
+
+func TestNewGameSpawnsOtherPlayers(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Players = 2
+	settings.Mode = GameModeVersus
+	mapFile := MapFile{"test.karta", MapSourceBuiltin, []byte("############\n#p         #\n#          #\n############"), MapMetadata{}}
+	game, err := NewGame(nil, nil, &settings, mapFile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(game.others) != 1 || game.mode != GameModeVersus {
+		t.Fatalf("expected a competitive game of two players, got %d others in mode %s", len(game.others), game.mode)
+	}
+	position := game.others[0].position
+	if position == game.player.position || game.IsPathBlocked(position) {
+		t.Errorf("expected player two on a free tile, got %v", position)
+	}
+	if game.others[0].hitPointsCurrent != game.rules.MaxHitPointsPlayer {
+		t.Errorf("expected player two to start with full hitpoints")
+	}
+}
+
+func TestBurningInMultiplayerGames(t *testing.T) {
+	for _, test := range []struct {
+		mode      string
+		burnTwo   bool
+		exit      bool
+		score     int
+		scoreLine string
+	}{
+		{GameModeCoop, false, false, 5, "Team score: 5"},
+		{GameModeCoop, true, true, 5, "Team score: 5"},
+		{GameModeVersus, false, true, 2, "Winner: Player 2"}, // The last player standing wins
+		{GameModeVersus, true, true, 3, "Winner: Player 1"},  // If both burn, the higher score wins
+	} {
+		game, _ := newGoldenGame(t, 80, 24, ""+
+			"#######\n"+
+			"#p f f#\n"+
+			"#######")
+		two := addPlayer(game, Coordinate{5, 1}, test.mode, CameraSplit)
+		game.player.position = Coordinate{3, 1}
+		game.player.score, two.score = 3, 2
+		game.player.hitPointsCurrent = game.rules.DamageFire
+		if !test.burnTwo {
+			two.position = Coordinate{4, 1}
+		}
+		two.hitPointsCurrent = game.rules.DamageFire
+		game.CheckFireDamage()
+
+		if game.exit != test.exit {
+			t.Errorf("%s, both burned %v: expected exit %v", test.mode, test.burnTwo, test.exit)
+		}
+		if score := game.Score(); score != test.score {
+			t.Errorf("%s, both burned %v: expected score %d, got %d", test.mode, test.burnTwo, test.score, score)
+		}
+		if line := game.ScoreLines()[0]; line != test.scoreLine {
+			t.Errorf("%s, both burned %v: expected %q, got %q", test.mode, test.burnTwo, test.scoreLine, line)
+		}
+	}
+}
+
+func TestSharedCameraKeepsPlayersInView(t *testing.T) {
+	game, _ := newGoldenGame(t, 40, 12, ""+
+		"##############################\n"+
+		"#p                           #\n"+
+		"##############################")
+	addPlayer(game, Coordinate{38, 1}, GameModeCoop, CameraShared) // Beyond the map, only to stretch the camera
+	game.others[0].position = Coordinate{game.viewport.width - 1, 1}
+
+	if game.MovePlayer(&game.player, DirLeft) {
+		t.Errorf("expected player one to be kept inside the %d wide view", game.viewport.width)
+	}
+	if !game.MovePlayer(&game.player, DirRight) {
+		t.Errorf("expected player one to be able to walk towards player two")
+	}
+}
+
+func TestPlayersBlockEachOther(t *testing.T) {
+	game, _ := newGoldenGame(t, 80, 24, ""+
+		"########\n"+
+		"#p     #\n"+
+		"########")
+	two := addPlayer(game, Coordinate{2, 1}, GameModeCoop, CameraSplit)
+
+	if game.MovePlayer(&game.player, DirRight) || game.MovePlayer(two, DirLeft) {
+		t.Errorf("expected the players not to walk into each other")
+	}
+	two.hitPointsCurrent = 0
+	if !game.MovePlayer(&game.player, DirRight) {
+		t.Errorf("expected player one to walk over player two once burned")
+	}
+}
+
+func TestClickMovesPlayerOfSplitScreen(t *testing.T) {
+	game, screen := newGoldenGame(t, 80, 24, ""+
+		"##############\n"+
+		"#p           #\n"+
+		"#            #\n"+
+		"##############")
+	two := addPlayer(game, Coordinate{10, 1}, GameModeCoop, CameraSplit)
+	target := Coordinate{8, 2}
+	click := game.Cameras()[1].WorldToScreen(target)
+
+	screen.InjectMouse(click.x, click.y, tcell.Button1, tcell.ModNone)
+	for i := 0; i < 10 && two.position != target; i++ {
+		game.HandleEvent(screen.PollEvent())
+	}
+	if two.position != target || game.player.position != (Coordinate{1, 1}) {
+		t.Errorf("expected player two to walk to %v and player one to stay, got %v and %v", target, two.position, game.player.position)
+	}
+}
@@ -1,21 +1,5 @@
 package main
 
-import (
-	"math/rand"
-)
-
-type Node struct {
-	position  Coordinate
-	parent    Coordinate
-	neighbors []Coordinate
-	distance  int
-	visited   bool
-}
-
-const (
-	Infinity = 9999999
-)
-
 // Returns true if coordinate contains a collidable object, or a tree.
 func (game *Game) IsBlocked(coordinate Coordinate) bool {
 	if content, exists := game.world.content[coordinate]; exists {
@@ -83,7 +67,7 @@ func (game *Game) IsUnflammable(coordinate Coordinate) bool {
 }
 
 func (game *Game) GetRandomAvailableCoordinate() Coordinate {
-	coordinate := Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
+	coordinate := Coordinate{game.random.Intn(game.world.width), game.random.Intn(game.world.height)}
 	iterations := 0
 	for {
 		if iterations >= MaxIterations {
@@ -92,7 +76,7 @@ func (game *Game) GetRandomAvailableCoordinate() Coordinate {
 		iterations++
 
 		if game.IsPathBlocked(coordinate) {
-			coordinate = Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
+			coordinate = Coordinate{game.random.Intn(game.world.width), game.random.Intn(game.world.height)}
 		} else {
 			break
 		}
@@ -101,27 +85,20 @@ func (game *Game) GetRandomAvailableCoordinate() Coordinate {
 	return coordinate
 }
 
-func (game *Game) GetRandomFlammableCoordinate() Coordinate {
-	coordinate := Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
-	iterations := 0
-	for {
-		if iterations >= MaxIterations {
-			panic("Reached max iterations in GetRandomFlammableCoordinate()")
-		}
-		iterations++
-
-		if game.IsUnflammable(coordinate) {
-			coordinate = Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
-		} else {
-			break
+// Returns a random coordinate that can catch fire, or false if none was found, as when the forest has burnt down.
+func (game *Game) GetRandomFlammableCoordinate() (Coordinate, bool) {
+	for iterations := 0; iterations < MaxIterations; iterations++ {
+		coordinate := Coordinate{game.random.Intn(game.world.width), game.random.Intn(game.world.height)}
+		if !game.IsUnflammable(coordinate) {
+			return coordinate, true
 		}
 	}
 
-	return coordinate
+	return Coordinate{}, false
 }
 
 func (game *Game) GetRandomPlantableCoordinate() Coordinate {
-	coordinate := Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
+	coordinate := Coordinate{game.random.Intn(game.world.width), game.random.Intn(game.world.height)}
 	iterations := 0
 	for {
 		if iterations >= MaxIterations {
@@ -130,7 +107,7 @@ func (game *Game) GetRandomPlantableCoordinate() Coordinate {
 		iterations++
 
 		if game.IsUnplantable(coordinate) {
-			coordinate = Coordinate{rand.Intn(game.world.width), rand.Intn(game.world.height)}
+			coordinate = Coordinate{game.random.Intn(game.world.width), game.random.Intn(game.world.height)}
 		} else {
 			break
 		}
@@ -139,8 +116,8 @@ func (game *Game) GetRandomPlantableCoordinate() Coordinate {
 	return coordinate
 }
 
-func GetRandomDirection() int {
-	randInt := rand.Intn(4)
+func (game *Game) GetRandomDirection() int {
+	randInt := game.random.Intn(4)
 	switch randInt {
 	case 0:
 		return DirUp
@@ -155,128 +132,63 @@ func GetRandomDirection() int {
 	}
 }
 
-func (node *Node) GetNeighbors(graph map[Coordinate]*Node) []Coordinate {
-	// Get slice of neighbors based, but only ones that are within the world dimensions.
+// Returns the coordinates next to the given one that a path can go through, in the order up, right, down, left.
+func (game *Game) PathNeighbors(coordinate Coordinate) []Coordinate {
 	var neighbors []Coordinate
-	if _, exists := graph[Translate(node.position, 0, -1)]; exists { // Check above
-		neighbors = append(neighbors, Translate(node.position, 0, -1))
-	}
-	if _, exists := graph[Translate(node.position, 1, 0)]; exists { // Check right
-		neighbors = append(neighbors, Translate(node.position, 1, 0))
-	}
-	if _, exists := graph[Translate(node.position, 0, 1)]; exists { // Check below
-		neighbors = append(neighbors, Translate(node.position, 0, 1))
-	}
-	if _, exists := graph[Translate(node.position, -1, 0)]; exists { // Check left
-		neighbors = append(neighbors, Translate(node.position, -1, 0))
-	}
-
-	return neighbors
-}
-
-func SelectNextNode(graph map[Coordinate]*Node) *Node {
-	var nextNode *Node
-	lowestDistance := Infinity
-
-	// Determine the lowest distance among unvisited nodes.
-	for _, node := range graph {
-		if !node.visited && node.distance <= lowestDistance {
-			lowestDistance = node.distance
+	for _, delta := range []Coordinate{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		neighbor := Translate(coordinate, delta.x, delta.y)
+		if neighbor.x < 0 || neighbor.y < 0 || neighbor.x >= game.world.width || neighbor.y >= game.world.height {
+			continue
 		}
-	}
-
-	// Select any unvisited node with the lowest distance.
-	for _, node := range graph {
-		if !node.visited && node.distance == lowestDistance {
-			nextNode = node
-			break
+		if !game.IsPathBlocked(neighbor) {
+			neighbors = append(neighbors, neighbor)
 		}
 	}
 
-	return nextNode
+	return neighbors
 }
 
+// Finds a shortest path from start to end that goes around everything blocking paths, one step up, right, down or left at a time.
+// The path maps step numbers to coordinates, from 1 for the first step to the number of steps for end.
+// If there is no path, or start and end are the same, the path is just start as the first step.
 func (game *Game) FindPath(start Coordinate, end Coordinate) map[int]Coordinate {
-	// Create a map of all coordinates.
-	// We set the distance to all other nodes to "inf", except the distance
-	// to the start node which we set at 0.
-	graph := make(map[Coordinate]*Node, (2*game.world.height)*(2*game.world.width))
-	for c := 0; c < game.world.width; c++ {
-		for r := 0; r < game.world.height; r++ {
-			// Only add non-collidable points, since actors cannot move throgh blocked points.
-			if !game.IsPathBlocked(Coordinate{c, r}) {
-				graph[Coordinate{c, r}] = &Node{position: Coordinate{c, r}, distance: Infinity, visited: false}
-			}
-		}
-	}
-
-	// Add neighbors to each node of the graph.
-	for _, node := range graph {
-		node.neighbors = node.GetNeighbors(graph)
-	}
-
-	// Initialize the starting coordinate as the currentNode.
-	graph[start].parent = start
-	graph[start].distance = 0
-	var currentNode *Node
-	iterations := 0
-	// Now begin the algorithm:
-	// While there are unvisited nodes left in the graph,
-	// select an unknown node currentNode with the loewst distance.
-
-	// and update neighboringNode's parent to currentNode.
-	// Continue until there are no unvisited nodes left in the graph, or until max iterations is reached.
-
-	for {
-		if iterations >= MaxIterations {
-			panic("Reached max iterations in FindPath()")
-		}
-		iterations++
+	return game.FindPathTo(start, func(coord Coordinate) bool { return coord == end })
+}
 
-		// Select an unvisited node with the lowest distance.
-		// If there are no more unvisited nodes, or we reach the maximum number of iterations, we're done.
-		currentNode = SelectNextNode(graph)
-		if currentNode == nil {
+// Finds a shortest path like FindPath, to the nearest coordinate for which isEnd returns true.
+func (game *Game) FindPathTo(start Coordinate, isEnd func(Coordinate) bool) map[int]Coordinate {
+	// Search outwards from start, one step further each round, and remember where each coordinate was reached from.
+	parents := map[Coordinate]Coordinate{start: start}
+	queue := []Coordinate{start}
+	end, found := start, false
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if isEnd(current) {
+			end, found = current, true
 			break
 		}
-		currentNode.visited = true
-
-		// For each node neighboring currentNode,
-		// if currentNode distance + distance from currentNode to neighbor is less than neighbor's distance,
-		// update neighbor's distance to currentNode distance + distance from currentNode to neighbor.
-		// Also mark currentNode as neighbor's parent, so it knows how to follow the shortest path.
-		for _, neighbor := range currentNode.neighbors {
-			if currentNode.distance+1 < graph[neighbor].distance {
-				graph[neighbor].distance = currentNode.distance + 1
-				graph[neighbor].parent = currentNode.position
+
+		for _, neighbor := range game.PathNeighbors(current) {
+			if _, reached := parents[neighbor]; !reached {
+				parents[neighbor] = current
+				queue = append(queue, neighbor)
 			}
 		}
 	}
 
-	// Create map where key is an int representing the path order (e.g. key = 1 is first step, key = 2 is second step)
-	// and value is the target coordinate (e.g. value for key = 1 is the coordinate for first step in path).
-	pathNode, exists := graph[end]
-	path := make(map[int]Coordinate)
-	if !exists || pathNode.distance == Infinity {
-		// Was unable to find a path.
-		path[1] = start
-		return path
+	if !found || start == end {
+		return map[int]Coordinate{1: start}
 	}
 
-	stepCount := 1
-	for {
-		if pathNode.parent == start {
-			break
-		} else {
-			pathNode = graph[pathNode.parent]
-		}
-		stepCount++
+	// Follow the parents back from end, numbering the steps from the last one.
+	steps := 0
+	for coord := end; coord != start; coord = parents[coord] {
+		steps++
 	}
-
-	pathNode = graph[end]
-	for i := stepCount; i > 0; i-- {
-		path[i] = pathNode.position
-		pathNode = graph[pathNode.parent]
+	path := make(map[int]Coordinate, steps)
+	for coord, step := end, steps; coord != start; coord, step = parents[coord], step-1 {
+		path[step] = coord
 	}
 
 	return path
@@ -323,13 +235,17 @@ func (game *Game) FindNextDirection(squirrelKey int) int {
 }
 
 func (game *Game) UpdatePath(squirrelKey int) {
-	squirrel := game.squirrels[squirrelKey]
+	game.squirrels[squirrelKey].AdvancePath()
+}
+
+// Drops the first step of the actor's path, so that the next step becomes the first.
+func (actor *Actor) AdvancePath() {
 	newPath := make(map[int]Coordinate)
-	for key, coord := range squirrel.path {
+	for key, coord := range actor.path {
 		if key != 1 {
 			newPath[key-1] = coord
 		}
 	}
 
-	squirrel.path = newPath
+	actor.path = newPath
 }
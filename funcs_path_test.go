@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Builds a game with a random world, where about a third of the coordinates block paths in some way.
+func newRandomPathGame(r *rand.Rand) *Game {
+	width, height := 2+r.Intn(30), 2+r.Intn(20)
+	registry := DefaultTiles()
+	content := make(map[Coordinate]interface{})
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			coord := Coordinate{x, y}
+			switch r.Intn(12) {
+			case 0, 1:
+				content[coord] = NewObject(registry, KeyWall)
+			case 2:
+				content[coord] = NewObject(registry, KeyWaterHeavy)
+			case 3:
+				content[coord] = &Tree{coord, TreeStateAdult}
+			case 4:
+				content[coord] = NewFire(coord, 1)
+			case 5:
+				content[coord] = NewObject(registry, KeyGrassLight) // Doesn't block paths
+			}
+		}
+	}
+
+	return &Game{world: NewWorld(width, height, content)}
+}
+
+// Returns the number of steps on the shortest path from start to end, or -1 if there is none.
+func pathDistance(game *Game, start Coordinate, end Coordinate) int {
+	distances := map[Coordinate]int{start: 0}
+	queue := []Coordinate{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == end {
+			return distances[current]
+		}
+		for _, next := range []Coordinate{{current.x, current.y - 1}, {current.x + 1, current.y}, {current.x, current.y + 1}, {current.x - 1, current.y}} {
+			inside := next.x >= 0 && next.y >= 0 && next.x < game.world.width && next.y < game.world.height
+			if _, seen := distances[next]; inside && !seen && !game.IsPathBlocked(next) {
+				distances[next] = distances[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return -1
+}
+
+// Checks that the path is a shortest path from start to end, or the path of a single step to start if there is none.
+func checkPath(t *testing.T, game *Game, start Coordinate, end Coordinate, path map[int]Coordinate) {
+	t.Helper()
+	distance := pathDistance(game, start, end)
+	if distance <= 0 {
+		if len(path) != 1 || path[1] != start {
+			t.Errorf("expected no path from %v to %v, got %v", start, end, path)
+		}
+		return
+	}
+
+	if len(path) != distance {
+		t.Fatalf("expected %d steps from %v to %v, got %v", distance, start, end, path)
+	}
+	previous := start
+	for step := 1; step <= distance; step++ {
+		coord, exists := path[step]
+		if !exists {
+			t.Fatalf("step %d missing from path %v", step, path)
+		}
+		if dx, dy := coord.x-previous.x, coord.y-previous.y; dx*dx+dy*dy != 1 {
+			t.Fatalf("step %d from %v to %v is not to a neighbour", step, previous, coord)
+		}
+		if game.IsPathBlocked(coord) {
+			t.Fatalf("step %d goes through blocked %v", step, coord)
+		}
+		previous = coord
+	}
+	if previous != end {
+		t.Errorf("path ends at %v instead of %v", previous, end)
+	}
+}
+
+func TestFindPathFindsShortestPaths(t *testing.T) {
+	for seed := int64(0); seed < 300; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		game := newRandomPathGame(r)
+		start := Coordinate{r.Intn(game.world.width), r.Intn(game.world.height)}
+		end := Coordinate{r.Intn(game.world.width), r.Intn(game.world.height)}
+		checkPath(t, game, start, end, game.FindPath(start, end))
+	}
+}
+
+func TestFindPathWithoutPath(t *testing.T) {
+	world, _, _, err := ParseMap([]byte(""+
+		"#######\n"+
+		"#p # f#\n"+
+		"#  ####\n"+
+		"#######"), DefaultTiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := &Game{world: world}
+
+	start := Coordinate{1, 1}
+	for _, end := range []Coordinate{{4, 1}, {5, 1}, {3, 1}, start} { // Walled in, on fire, on a wall, and already there
+		if path := game.FindPath(start, end); len(path) != 1 || path[1] != start {
+			t.Errorf("expected no path to %v, got %v", end, path)
+		}
+	}
+}
+
+func TestFindPathOnLargeMaps(t *testing.T) {
+	// Used to panic after visiting a thousand coordinates.
+	game := &Game{world: NewWorld(120, 60, make(map[Coordinate]interface{}))}
+	start, end := Coordinate{0, 0}, Coordinate{119, 59}
+	checkPath(t, game, start, end, game.FindPath(start, end))
+}
+
+func TestFindPathFromFire(t *testing.T) {
+	// Squirrels caught by fire find their way out of it.
+	start := Coordinate{1, 1}
+	game := &Game{world: NewWorld(5, 3, map[Coordinate]interface{}{start: NewFire(start, 1)})}
+	end := Coordinate{3, 1}
+	checkPath(t, game, start, end, game.FindPath(start, end))
+}
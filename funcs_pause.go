@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Opens the pause menu. The world does not update while it is open.
+func (game *Game) Pause() {
+	game.paused = true
+	for _, player := range game.Players() {
+		player.path = nil // Stop walking to a clicked tile
+	}
+	game.pauseMenu = PauseMenu{page: PausePageMain}
+}
+
+func (game *Game) Resume() {
+	game.paused = false
+}
+
+// Returns the lines of text and the selectable items of the current pause menu page.
+func (game *Game) PauseMenuLines() (info []string, items []string) {
+	switch game.pauseMenu.page {
+	case PausePageSettings:
+		items = []string{"Vision radius: < " + strconv.Itoa(game.player.visionRadius) + " >", "Go back"}
+	case PausePageControls:
+		for _, action := range actionOrder {
+			info = append(info, actionLabels[action]+": "+game.controls.KeysString(action))
+		}
+		info = append(info, "Pause: Esc", "Walk, chop: left click", "Dig: right click")
+		items = []string{"Go back"}
+	default:
+		items = []string{"Resume", "Save game", "Settings", "Controls", "Quit to title", "Quit game"}
+	}
+
+	return info, items
+}
+
+func (game *Game) HandlePauseEvent(ev tcell.Event) {
+	_, items := game.PauseMenuLines()
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			if game.pauseMenu.page == PausePageMain {
+				game.Resume()
+			} else {
+				game.OpenPausePage(PausePageMain)
+			}
+		case tcell.KeyUp:
+			game.pauseMenu.cursor = (game.pauseMenu.cursor + len(items) - 1) % len(items)
+		case tcell.KeyDown:
+			game.pauseMenu.cursor = (game.pauseMenu.cursor + 1) % len(items)
+		case tcell.KeyLeft:
+			game.ChangePauseSetting(-1)
+		case tcell.KeyRight:
+			game.ChangePauseSetting(1)
+		case tcell.KeyEnter:
+			game.SelectPauseItem(items[game.pauseMenu.cursor])
+		}
+	case *tcell.EventMouse:
+		_, y := ev.Position()
+		pressed := ev.Buttons() &^ game.mouse.buttons // Only buttons that went down with this event, not ones held since before
+		game.mouse.buttons = ev.Buttons()
+		game.mouse.hovering = false
+		_, top, _, _ := game.PauseMenuBox()
+		item := y - top - game.PauseMenuItemsOffset()
+		if item >= 0 && item < len(items) {
+			game.pauseMenu.cursor = item
+			if pressed&tcell.Button1 != 0 {
+				game.SelectPauseItem(items[item])
+			}
+		}
+	case *tcell.EventResize:
+		game.screen.Sync()
+		game.UpdateLayout()
+		game.renderer.Invalidate()
+	}
+}
+
+func (game *Game) SelectPauseItem(item string) {
+	game.pauseMenu.status = ""
+	switch item {
+	case "Resume":
+		game.Resume()
+	case "Save game":
+		if game.settings.SaveFile == "" {
+			game.pauseMenu.status = "Saving is turned off"
+		} else if err := game.Save(game.settings.SaveFile); err != nil {
+			game.pauseMenu.status = "Could not save: " + err.Error()
+		} else {
+			game.pauseMenu.status = "Game saved"
+		}
+	case "Settings":
+		game.OpenPausePage(PausePageSettings)
+	case "Controls":
+		game.OpenPausePage(PausePageControls)
+	case "Quit to title":
+		game.quitToTitle = true
+		game.exit = true
+	case "Quit game":
+		game.exit = true
+		game.endCause = EndCauseQuit
+	case "Go back":
+		game.OpenPausePage(PausePageMain)
+	}
+}
+
+func (game *Game) OpenPausePage(page int) {
+	game.pauseMenu.page = page
+	game.pauseMenu.cursor = 0
+	game.pauseMenu.status = ""
+}
+
+// Changes the setting under the cursor one step up or down.
+func (game *Game) ChangePauseSetting(steps int) {
+	if game.pauseMenu.page != PausePageSettings || game.pauseMenu.cursor != 0 {
+		return
+	}
+
+	game.player.visionRadius += steps * VisionRadiusStep
+	if game.player.visionRadius < VisionRadiusStep {
+		game.player.visionRadius = VisionRadiusStep
+	}
+	game.settings.VisionRadius = game.player.visionRadius // Keep it for the next game
+}
+
+// Returns the number of rows between the top border of the pause menu and its first item.
+func (game *Game) PauseMenuItemsOffset() int {
+	info, _ := game.PauseMenuLines()
+	offset := 3 // Border, title and a gap
+	if len(info) > 0 {
+		offset += len(info) + 1
+	}
+
+	return offset
+}
+
+// Returns the screen area of the pause menu, centered in the viewport.
+func (game *Game) PauseMenuBox() (left int, top int, width int, height int) {
+	info, items := game.PauseMenuLines()
+	width = utf8.RuneCountInString(game.pauseMenu.status)
+	for _, line := range append(info, items...) {
+		if utf8.RuneCountInString(line)+2 > width { // Leave room for the cursor
+			width = utf8.RuneCountInString(line) + 2
+		}
+	}
+	width += 4                                            // Borders and padding
+	height = game.PauseMenuItemsOffset() + len(items) + 2 // Status line and bottom border
+
+	left = game.viewport.position.x + (game.viewport.width-width)/2
+	top = game.viewport.position.y + (game.viewport.height-height)/2
+	if top < game.viewport.position.y {
+		top = game.viewport.position.y
+	}
+
+	return left, top, width, height
+}
+
+// Draws the pause menu on top of the viewport.
+func (game *Game) DrawPauseMenu() {
+	info, items := game.PauseMenuLines()
+	left, top, width, height := game.PauseMenuBox()
+	right := left + width - 1
+	bottom := top + height - 1
+
+	for y := top; y <= bottom; y++ {
+		for x := left; x <= right; x++ {
+			char := ' '
+			switch {
+			case x == left && y == top:
+				char = tcell.RuneULCorner
+			case x == right && y == top:
+				char = tcell.RuneURCorner
+			case x == left && y == bottom:
+				char = tcell.RuneLLCorner
+			case x == right && y == bottom:
+				char = tcell.RuneLRCorner
+			case y == top || y == bottom:
+				char = tcell.RuneHLine
+			case x == left || x == right:
+				char = tcell.RuneVLine
+			}
+			game.renderer.SetContent(x, y, char, tcell.StyleDefault)
+		}
+	}
+
+	title := "Paused"
+	switch game.pauseMenu.page {
+	case PausePageSettings:
+		title = "Settings"
+	case PausePageControls:
+		title = "Controls"
+	}
+	game.DrawText(left+(width-len(title))/2, top+1, len(title), title, tcell.StyleDefault.Bold(true))
+
+	for i, line := range info {
+		game.DrawText(left+2, top+3+i, width-4, line, tcell.StyleDefault)
+	}
+
+	itemsTop := top + game.PauseMenuItemsOffset()
+	for i, item := range items {
+		if i == game.pauseMenu.cursor {
+			game.renderer.SetContent(left+2, itemsTop+i, '>', tcell.StyleDefault)
+		}
+		game.DrawText(left+4, itemsTop+i, width-6, item, tcell.StyleDefault)
+	}
+
+	if game.pauseMenu.status != "" {
+		game.DrawText(left+2, bottom-1, width-4, game.pauseMenu.status, tcell.StyleDefault)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestPauseFreezesWorld(t *testing.T) {
+	game, screen := newMouseTestGame(t)
+	controls, _ := NewControls(PresetDefault)
+	game.controls = &controls
+
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	for i := 0; i < 3; i++ {
+		game.Update()
+	}
+	if !game.paused {
+		t.Fatalf("Escape should pause the game")
+	}
+	if game.ticks != 0 {
+		t.Errorf("world should not update while paused, got %d ticks", game.ticks)
+	}
+	if game.pauseMenu.cursor != 1 {
+		t.Errorf("Down should move the pause menu cursor, got %d", game.pauseMenu.cursor)
+	}
+
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	game.Update()
+	game.Update()
+	if game.paused || game.ticks != 1 {
+		t.Errorf("Escape should resume the game, paused %t after %d ticks", game.paused, game.ticks)
+	}
+
+	game.Pause()
+	game.SelectPauseItem("Quit to title")
+	if !game.exit || !game.quitToTitle {
+		t.Errorf("Quit to title should exit to the title menu")
+	}
+}
+
+func TestSaveAndLoadGame(t *testing.T) {
+	game, screen := newMouseTestGame(t)
+	game.mapName = "liten_skog.karta"
+	game.ticks = 42
+	game.player.score = 7
+	game.player.inventory.wood = 3
+	tree := Translate(game.player.position, 1, 0)
+	fire := Translate(game.player.position, 0, 1)
+	game.world.content[tree] = &Tree{tree, TreeStateSapling}
+	game.world.content[fire] = &Fire{fire, 5, 1, 2}
+	game.squirrels[0] = &Actor{position: Coordinate{2, 2}, hitPointsCurrent: 1, hitPointsMax: 1}
+	game.stats = Stats{chopped: map[int]int{TreeStateStump: 2}, firesStarted: 4, squirrelsLost: 1, forestCover: []float64{0.5, 0.25}}
+
+	fileName := filepath.Join(t.TempDir(), "save.json")
+	if err := game.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	settings := DefaultSettings()
+	loaded, err := LoadGame(screen, game.controls, &settings, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.mapName != game.mapName || loaded.ticks != 42 || loaded.player.score != 7 || loaded.player.inventory.wood != 3 || loaded.player.position != game.player.position {
+		t.Errorf("game state was not restored: %+v", loaded.player)
+	}
+	if got, ok := loaded.world.content[tree].(*Tree); !ok || got.state != TreeStateSapling {
+		t.Errorf("sapling was not restored, got %v", loaded.world.content[tree])
+	}
+	if got, ok := loaded.world.content[fire].(*Fire); !ok || got.age != 5 || got.phase != 1 || got.fuel != 2 {
+		t.Errorf("fire was not restored, got %v", loaded.world.content[fire])
+	}
+	if len(loaded.squirrels) != 1 || len(loaded.world.borders) != len(game.world.borders) {
+		t.Errorf("squirrels or borders were not restored")
+	}
+	if stats := loaded.stats; stats.chopped[TreeStateStump] != 2 || stats.firesStarted != 4 || stats.squirrelsLost != 1 || len(stats.forestCover) != 2 {
+		t.Errorf("stats were not restored, got %+v", stats)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Reads a map for previewing with the tiles of the registry, and counts what is on it.
+func NewMapPreview(mapFile MapFile, registry map[int]Tile) (MapPreview, error) {
+	world, playerPosition, squirrelPositions, err := mapFile.Read(registry)
+	if err != nil {
+		return MapPreview{}, err
+	}
+
+	preview := MapPreview{world: world, players: map[Coordinate]bool{playerPosition: true}, squirrels: make(map[Coordinate]bool), metadata: mapFile.metadata, tiles: registry}
+	for _, position := range squirrelPositions {
+		preview.squirrels[position] = true
+	}
+
+	water := 0
+	for _, content := range world.content {
+		if object, isObject := content.(Object); isObject && (object.key == KeyWaterLight || object.key == KeyWaterHeavy) {
+			water++
+		}
+	}
+	if area := (world.width - 2) * (world.height - 2); area > 0 { // Inside the borders
+		preview.water = float64(water) / float64(area)
+	}
+
+	return preview, nil
+}
+
+// Returns the lines describing the map below its thumbnail, wrapped to the given width.
+func (preview *MapPreview) InfoLines(width int) []string {
+	lines := []string{
+		"Size: " + strconv.Itoa(preview.world.width) + "x" + strconv.Itoa(preview.world.height),
+		"Squirrels: " + strconv.Itoa(len(preview.squirrels)),
+		fmt.Sprintf("Water: %.0f%%", 100*preview.water),
+	}
+	if preview.metadata.author != "" {
+		lines = append(lines, "Author: "+preview.metadata.author)
+	}
+	if preview.metadata.description != "" {
+		lines = append(lines, "")
+		lines = append(lines, WrapText(preview.metadata.description, width)...)
+	}
+
+	return lines
+}
+
+// Returns the tile key drawn for the block of the map starting at the given coordinate, and whether anything is drawn.
+// Actors and fire stand out over everything else, and otherwise the most common object in the block is drawn.
+func (preview *MapPreview) BlockKey(origin Coordinate, scale int) (int, bool) {
+	counts := make(map[int]int)
+	squirrel := false
+	fire := false
+	for y := origin.y; y < origin.y+scale; y++ {
+		for x := origin.x; x < origin.x+scale; x++ {
+			coord := Coordinate{x, y}
+			if preview.players[coord] {
+				return KeyPlayer, true
+			}
+			if preview.squirrels[coord] {
+				squirrel = true
+			}
+			switch content := preview.world.content[coord].(type) {
+			case Object:
+				counts[content.key]++
+			case *Tree:
+				counts[content.Key()]++
+			case *Fire:
+				fire = true
+			}
+		}
+	}
+
+	switch {
+	case squirrel:
+		return KeySquirrel, true
+	case fire:
+		return KeyFireType1, true
+	}
+
+	bestKey, bestCount := 0, 0
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < bestKey) {
+			bestKey, bestCount = key, count
+		}
+	}
+
+	return bestKey, bestCount > 0
+}
+
+// Draws a thumbnail of the map within the given area of the screen, shrunk as much as needed for it to fit,
+// with its dimensions and metadata below it.
+func (preview *MapPreview) Draw(screen tcell.Screen, x int, y int, width int, height int) {
+	infoLines := preview.InfoLines(width)
+	thumbnailHeight := height - len(infoLines) - 1 // Keep a row between the thumbnail and the info
+	if width <= 0 || thumbnailHeight <= 0 {
+		return
+	}
+
+	scale := preview.Scale(width, thumbnailHeight)
+	rows := (preview.world.height + scale - 1) / scale
+	for row := 0; row < rows; row++ {
+		for column := 0; column < (preview.world.width+scale-1)/scale; column++ {
+			if key, found := preview.BlockKey(Coordinate{column * scale, row * scale}, scale); found {
+				tile := preview.tiles[key]
+				screen.SetContent(x+column, y+row, tile.char, nil, tile.style)
+			}
+		}
+	}
+
+	for i, line := range infoLines {
+		DrawScreenText(screen, x, y+rows+1+i, width, line, tcell.StyleDefault)
+	}
+}
+
+// Returns how many tiles across each cell of the thumbnail shows, for it to fit in the given size.
+func (preview *MapPreview) Scale(width int, height int) int {
+	scale := 1
+	for (preview.world.width+scale-1)/scale > width || (preview.world.height+scale-1)/scale > height {
+		scale++
+	}
+
+	return scale
+}
+
+// Draws the preview of the highlighted map on the New game page, to the right of the map list.
+func (titleMenu *TitleMenu) DrawMapPreview(screen tcell.Screen, y int) {
+	page := titleMenu.titleMenuPages[NewGamePageOrder]
+	mapFile, isMap := page.titleMenuItems[page.cursorState].value.(MapFile)
+	if !isMap {
+		return
+	}
+
+	if titleMenu.previews == nil {
+		titleMenu.previews = make(map[string]*MapPreview)
+	}
+	preview, found := titleMenu.previews[mapFile.fileName]
+	if !found {
+		newPreview, err := NewMapPreview(mapFile, titleMenu.settings.tiles)
+		if err != nil {
+			return
+		}
+		preview = &newPreview
+		titleMenu.previews[mapFile.fileName] = preview
+	}
+
+	widthScreen, heightScreen := screen.Size()
+	x := widthScreen/2 + MapPreviewMargin
+	preview.Draw(screen, x, y, widthScreen-x-MapPreviewMargin, heightScreen-y)
+}
+
+// Writes text starting at (x, y), cutting it off after maxWidth cells.
+func DrawScreenText(screen tcell.Screen, x int, y int, maxWidth int, text string, style tcell.Style) {
+	written := 0
+	for _, r := range text {
+		if written >= maxWidth {
+			break
+		}
+		screen.SetContent(x+written, y, r, nil, style)
+		written++
+	}
+}
+
+// Splits text into lines no wider than the given width, breaking between words where possible.
+func WrapText(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > width { // Words longer than a line are split
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		if line == "" {
+			line = word
+		} else if len([]rune(line))+1+len([]rune(word)) <= width {
+			line += " " + word
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapPreview(t *testing.T) {
+	mapFile := MapFile{"sjö.karta", "test", []byte("; author: Anna\n; description: A lake\n######\n#p ww#\n#s ww#\n######\n"), MapMetadata{}}
+	mapFile.metadata = ParseMapMetadata(mapFile.data)
+	preview, err := NewMapPreview(mapFile, DefaultTiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Size: 6x4", "Squirrels: 1", "Water: 50%", "Author: Anna", "", "A lake"}
+	if lines := preview.InfoLines(20); !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected info %q, got %q", want, lines)
+	}
+
+	// At half size, the player stands out over the wall in its block, and water fills its blocks.
+	tests := map[Coordinate]int{{0, 0}: KeyPlayer, {2, 0}: KeyWall, {4, 2}: KeyWall, {4, 0}: KeyWall, {2, 2}: KeyWall}
+	for origin, want := range tests {
+		if key, found := preview.BlockKey(origin, 2); !found || key != want {
+			t.Errorf("block at %v should show tile %d, got %d", origin, want, key)
+		}
+	}
+	if key, _ := preview.BlockKey(Coordinate{3, 1}, 2); key != KeyWaterLight {
+		t.Errorf("block of water should show water, got %d", key)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	want := []string{"An island", "surrounded", "by water", "abcdefghij", "kl"}
+	if lines := WrapText("An island surrounded by water abcdefghijkl", 10); !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected %q, got %q", want, lines)
+	}
+}
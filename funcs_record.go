@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Returns a recorder writing an asciinema cast to the output. The header is written with the first frame, once the
+// size of the screen is known.
+func NewRecorder(output io.Writer) *Recorder {
+	return &Recorder{output: output}
+}
+
+// Records the frame as an event of the cast: the cells that changed since the last frame, or the whole frame for the
+// first one and after the screen was resized. Frames without changes aren't recorded. Does nothing without a recorder,
+// or once writing failed.
+func (recorder *Recorder) Record(frame []Cell, width int, height int, now time.Time) {
+	if recorder == nil || recorder.err != nil {
+		return
+	}
+
+	if recorder.start.IsZero() {
+		recorder.start = now
+		recorder.write(CastHeader{CastVersion, width, height, now.Unix(), CastIdleTimeLimit, "Skogshuggare"})
+	}
+	elapsed := math.Round(now.Sub(recorder.start).Seconds()*1e6) / 1e6
+	previous := recorder.frame
+	if width != recorder.width || height != recorder.height {
+		if recorder.width != 0 || recorder.height != 0 {
+			recorder.write([]any{elapsed, "r", fmt.Sprintf("%dx%d", width, height)})
+		}
+		recorder.width, recorder.height = width, height
+		previous = nil
+	}
+
+	output := AnsiCells(previous, frame, width)
+	if previous == nil {
+		output = "\x1b[?25l\x1b[0m\x1b[2J" + output // Hide the cursor, like the game does, and start from a blank screen
+	}
+	if output != "" {
+		recorder.write([]any{elapsed, "o", output})
+	}
+	recorder.frame = append(recorder.frame[:0], frame...)
+}
+
+// Writes a line of the cast, keeping the first error.
+func (recorder *Recorder) write(line any) {
+	data, err := json.Marshal(line)
+	if err == nil {
+		_, err = recorder.output.Write(append(data, '\n'))
+	}
+	if err != nil {
+		recorder.err = err
+	}
+}
+
+// Closes the file recorded to, and returns the first error writing to it. Does nothing without a recorder.
+func (recorder *Recorder) Close() error {
+	if recorder == nil {
+		return nil
+	}
+	if closer, ok := recorder.output.(io.Closer); ok {
+		if err := closer.Close(); recorder.err == nil {
+			recorder.err = err
+		}
+	}
+
+	return recorder.err
+}
+
+// Returns the escape codes drawing the cells of the frame that differ from the previous frame, or every cell
+// without one. The cursor is moved to the start of each run of changed cells, and to the start of each row.
+func AnsiCells(previous []Cell, frame []Cell, width int) string {
+	var b strings.Builder
+	style, cursor := "", -1
+	for i, cell := range frame {
+		if previous != nil && cell == previous[i] {
+			continue
+		}
+		if i != cursor || i%width == 0 {
+			fmt.Fprintf(&b, "\x1b[%d;%dH", i/width+1, i%width+1)
+		}
+		if sgr := AnsiStyle(cell.style); sgr != style {
+			b.WriteString(sgr)
+			style = sgr
+		}
+		b.WriteRune(cell.char)
+		cursor = i + 1
+	}
+
+	return b.String()
+}
+
+// Returns the escape code selecting the style, starting from the default style. Colours are given as 24-bit colours,
+// so that they look the same whatever the palette of the terminal playing them back.
+func AnsiStyle(style tcell.Style) string {
+	foreground, background, attributes := style.Decompose()
+	codes := []string{"0"}
+	for _, attribute := range []struct {
+		mask tcell.AttrMask
+		code string
+	}{{tcell.AttrBold, "1"}, {tcell.AttrDim, "2"}, {tcell.AttrItalic, "3"}, {tcell.AttrUnderline, "4"}, {tcell.AttrBlink, "5"}, {tcell.AttrReverse, "7"}, {tcell.AttrStrikeThrough, "9"}} {
+		if attributes&attribute.mask != 0 {
+			codes = append(codes, attribute.code)
+		}
+	}
+	if r, g, b := foreground.RGB(); foreground != tcell.ColorDefault && r >= 0 {
+		codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+	}
+	if r, g, b := background.RGB(); background != tcell.ColorDefault && r >= 0 {
+		codes = append(codes, fmt.Sprintf("48;2;%d;%d;%d", r, g, b))
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// Returns the frame as lines of text with escape codes for the styles, which a terminal shows as it was drawn.
+func AnsiText(frame []Cell, width int) string {
+	var b strings.Builder
+	for row := 0; row*width < len(frame); row++ {
+		style := ""
+		for _, cell := range frame[row*width : (row+1)*width] {
+			if sgr := AnsiStyle(cell.style); sgr != style {
+				b.WriteString(sgr)
+				style = sgr
+			}
+			b.WriteRune(cell.char)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+
+	return b.String()
+}
+
+// Returns the frame as an HTML page, with each run of cells of the same style in a span styled like them.
+func HtmlText(frame []Cell, width int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>Skogshuggare</title>\n"+
+		"<style>body { margin: 0; background: %s; color: %s; } pre { margin: 0; font: 16px/1.2 monospace; }</style>\n</head>\n<body>\n<pre>",
+		ScreenshotBackground, ScreenshotForeground)
+	for row := 0; row*width < len(frame); row++ {
+		cells := frame[row*width : (row+1)*width]
+		for start := 0; start < len(cells); {
+			end := start + 1
+			for end < len(cells) && cells[end].style == cells[start].style {
+				end++
+			}
+			var text strings.Builder
+			for _, cell := range cells[start:end] {
+				text.WriteRune(cell.char)
+			}
+			if css := CssStyle(cells[start].style); css != "" {
+				fmt.Fprintf(&b, "<span style=\"%s\">%s</span>", css, html.EscapeString(text.String()))
+			} else {
+				b.WriteString(html.EscapeString(text.String()))
+			}
+			start = end
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("</pre>\n</body>\n</html>\n")
+
+	return b.String()
+}
+
+// Returns the style as CSS declarations, or an empty string for the default style. Reversed cells swap their colours,
+// with the page's colours standing in for the terminal's default ones.
+func CssStyle(style tcell.Style) string {
+	foreground, background, attributes := style.Decompose()
+	color, backgroundColor := CssColor(foreground), CssColor(background)
+	if attributes&tcell.AttrReverse != 0 {
+		if color == "" {
+			color = ScreenshotForeground
+		}
+		if backgroundColor == "" {
+			backgroundColor = ScreenshotBackground
+		}
+		color, backgroundColor = backgroundColor, color
+	}
+
+	var declarations []string
+	if color != "" {
+		declarations = append(declarations, "color: "+color)
+	}
+	if backgroundColor != "" {
+		declarations = append(declarations, "background-color: "+backgroundColor)
+	}
+	if attributes&tcell.AttrBold != 0 {
+		declarations = append(declarations, "font-weight: bold")
+	}
+	if attributes&tcell.AttrDim != 0 {
+		declarations = append(declarations, "opacity: 0.6")
+	}
+	if attributes&tcell.AttrItalic != 0 {
+		declarations = append(declarations, "font-style: italic")
+	}
+	var decorations []string
+	if attributes&tcell.AttrUnderline != 0 {
+		decorations = append(decorations, "underline")
+	}
+	if attributes&tcell.AttrStrikeThrough != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if len(decorations) > 0 {
+		declarations = append(declarations, "text-decoration: "+strings.Join(decorations, " "))
+	}
+
+	return strings.Join(declarations, "; ")
+}
+
+// Saves the frame last drawn as ANSI text and as an HTML page, named after the time, and tells the player where.
+func (game *Game) SaveScreenshot() {
+	if game.settings == nil || game.settings.ScreenshotDir == "" {
+		game.AppendToMenuMessages("Screenshots are turned off")
+		return
+	}
+
+	name, err := ScreenshotFileName(game.settings.ScreenshotDir, time.Now())
+	frame, width := game.renderer.previous, game.renderer.width
+	if err == nil {
+		err = os.WriteFile(name+ScreenshotAnsiExt, []byte(AnsiText(frame, width)), 0644)
+	}
+	if err == nil {
+		err = os.WriteFile(name+ScreenshotHtmlExt, []byte(HtmlText(frame, width)), 0644)
+	}
+	if err != nil {
+		game.AppendToMenuMessages("Could not save screenshot: " + err.Error())
+		return
+	}
+	game.AppendToMenuMessages("Screenshot saved to " + filepath.Base(name) + ScreenshotHtmlExt)
+}
+
+// Returns the path of a screenshot taken at the time, without an extension. A number is added to the name when
+// another screenshot was already taken that second.
+func ScreenshotFileName(dir string, now time.Time) (string, error) {
+	base := filepath.Join(dir, ScreenshotFilePrefix+now.Format(ScreenshotTimeFormat))
+	name := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(name + ScreenshotHtmlExt); errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		} else if err != nil {
+			return "", err
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+}
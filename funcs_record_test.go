@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+type playedCell struct {
+	char rune
+	sgr  string // Escape code selecting the cell's style
+}
+
+// Plays back the output of a cast onto a screen of the size, and returns its cells. Only the escape codes the
+// recorder writes are understood.
+func playCast(events []string, width int, height int) []playedCell {
+	cells := make([]playedCell, width*height)
+	cursor, sgr := 0, AnsiStyle(tcell.StyleDefault)
+	for _, output := range events {
+		for output != "" {
+			if escape := ansiEscape.FindStringIndex(output); escape == nil || escape[0] > 0 {
+				char := []rune(output)[0]
+				cells[cursor] = playedCell{char, sgr}
+				cursor++
+				output = output[len(string(char)):]
+				continue
+			}
+			code := ansiEscape.FindString(output)
+			output = output[len(code):]
+			switch code[len(code)-1] {
+			case 'H':
+				position := strings.Split(code[2:len(code)-1], ";")
+				row, _ := strconv.Atoi(position[0])
+				column, _ := strconv.Atoi(position[1])
+				cursor = (row-1)*width + column - 1
+			case 'J':
+				for i := range cells {
+					cells[i] = playedCell{' ', AnsiStyle(tcell.StyleDefault)}
+				}
+			case 'm':
+				sgr = code
+			}
+		}
+	}
+
+	return cells
+}
+
+func TestRecordGame(t *testing.T) {
+	game, screen := newGoldenGame(t, 40, 10, ""+
+		"##########\n"+
+		"#    T   #\n"+
+		"#  p   s #\n"+
+		"##########")
+	var output bytes.Buffer
+	game.settings.recorder = NewRecorder(&output)
+
+	game.Draw()
+	game.HandleAction(ActionMoveRight)
+	game.Draw()
+	game.Draw() // Nothing changed, so nothing is recorded
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	var header CastHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 40 || header.Height != 10 || header.Timestamp == 0 {
+		t.Errorf("expected a version 2 header for the screen's size, got %+v", header)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and two frames, got %q", lines)
+	}
+
+	var events []string
+	for i, line := range lines[1:] {
+		var event []any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if seconds, _ := event[0].(float64); event[1] != "o" || seconds < 0 {
+			t.Errorf("expected output at a time since the start, got %q", line)
+		}
+		events = append(events, event[2].(string))
+		if i == 1 && len(events[1]) > len(events[0])/4 {
+			t.Errorf("expected the second frame to only redraw what moved, got %q", events[1])
+		}
+	}
+
+	// Playing the cast back draws what is on the screen.
+	contents, width, height := screen.GetContents()
+	played := playCast(events, width, height)
+	for i, cell := range contents {
+		if want := (playedCell{cell.Runes[0], AnsiStyle(cell.Style)}); played[i] != want {
+			t.Fatalf("expected %+v at %d,%d when played back, got %+v", want, i%width, i/width, played[i])
+		}
+	}
+}
+
+func TestRecordResize(t *testing.T) {
+	var output bytes.Buffer
+	recorder := NewRecorder(&output)
+	start := time.Unix(1000, 0)
+	var renderer Renderer
+	renderer.Begin(3, 1)
+	recorder.Record(renderer.current, 3, 1, start)
+	renderer.Begin(2, 2)
+	renderer.SetContent(1, 1, 'X', tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true))
+	recorder.Record(renderer.current, 2, 2, start.Add(1500*time.Millisecond))
+
+	lines := strings.Split(output.String(), "\n")
+	if lines[2] != `[1.5,"r","2x2"]` || !strings.HasPrefix(lines[3], `[1.5,"o","\u001b[?25l\u001b[0m\u001b[2J`) {
+		t.Errorf("expected a resize and then the whole frame, got %q", lines[2:])
+	}
+	if !strings.Contains(lines[3], `\u001b[2;1H \u001b[0;1;38;2;255;0;0mX`) {
+		t.Errorf("expected a bold red X in 24-bit colour, got %q", lines[3])
+	}
+	if err := recorder.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScreenshot(t *testing.T) {
+	game, screen := newGoldenGame(t, 40, 10, ""+
+		"##########\n"+
+		"#    T   #\n"+
+		"#  p   s #\n"+
+		"##########")
+	game.settings.ScreenshotDir = t.TempDir()
+	game.Draw()
+	game.HandleAction(ActionScreenshot)
+	game.HandleAction(ActionScreenshot)
+
+	names, _ := filepath.Glob(filepath.Join(game.settings.ScreenshotDir, "*"))
+	if len(names) != 4 || !strings.HasSuffix(names[0], "-2"+ScreenshotAnsiExt) || !strings.HasSuffix(names[1], "-2"+ScreenshotHtmlExt) {
+		t.Fatalf("expected two screenshots, the second one numbered, got %q", names)
+	}
+	if message := game.menu.messages[len(game.menu.messages)-1]; !strings.HasPrefix(message, "Screenshot saved to "+ScreenshotFilePrefix) {
+		t.Errorf("expected to be told where the screenshot went, got %q", message)
+	}
+
+	ansi, _ := os.ReadFile(names[0])
+	contents, width, _ := screen.GetContents()
+	var want strings.Builder
+	for i, cell := range contents {
+		want.WriteRune(cell.Runes[0])
+		if i%width == width-1 {
+			want.WriteString("\n")
+		}
+	}
+	if text := ansiEscape.ReplaceAllString(string(ansi), ""); text != want.String() {
+		t.Errorf("expected the ANSI screenshot to show the screen\n%s\ngot\n%s", want.String(), text)
+	}
+
+	page, _ := os.ReadFile(names[1])
+	for _, part := range []string{"<pre>", CssStyle(game.settings.tiles[KeyPlayer].style) + `">` + string(game.settings.tiles[KeyPlayer].char)} {
+		if !strings.Contains(string(page), part) {
+			t.Errorf("expected the HTML screenshot to contain %q, got\n%s", part, page)
+		}
+	}
+}
+
+func TestHtmlTextEscapes(t *testing.T) {
+	frame := []Cell{{'<', tcell.StyleDefault}, {'&', tcell.StyleDefault}, {'>', tcell.StyleDefault.Bold(true)}}
+	if page := HtmlText(frame, 3); !strings.Contains(page, "<pre>&lt;&amp;<span style=\"font-weight: bold\">&gt;</span>\n</pre>") {
+		t.Errorf("expected the cells escaped, the bold one in a span, got\n%s", page)
+	}
+}
+
+func TestCssStyle(t *testing.T) {
+	tests := []struct {
+		style tcell.Style
+		want  string
+	}{
+		{tcell.StyleDefault, ""},
+		{tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorGreen), "color: #ff0000; background-color: #008000"},
+		{tcell.StyleDefault.Reverse(true), "color: " + ScreenshotBackground + "; background-color: " + ScreenshotForeground},
+		{tcell.StyleDefault.Bold(true).Underline(true).StrikeThrough(true), "font-weight: bold; text-decoration: underline line-through"},
+	}
+	for _, test := range tests {
+		if css := CssStyle(test.style); css != test.want {
+			t.Errorf("expected %q, got %q", test.want, css)
+		}
+	}
+}
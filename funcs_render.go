@@ -0,0 +1,50 @@
+package main
+
+import "github.com/gdamore/tcell/v2"
+
+// Prepares an empty frame matching the current screen size.
+// If the size changed since the last frame, the whole screen is redrawn on the next flush.
+func (renderer *Renderer) Begin(width int, height int) {
+	if width != renderer.width || height != renderer.height {
+		renderer.width = width
+		renderer.height = height
+		renderer.current = make([]Cell, width*height)
+		renderer.previous = make([]Cell, width*height)
+		renderer.full = true
+	}
+
+	for i := range renderer.current {
+		renderer.current[i] = Cell{' ', tcell.StyleDefault}
+	}
+}
+
+// Sets a cell of the frame being built. Cells outside the screen are ignored.
+func (renderer *Renderer) SetContent(x int, y int, char rune, style tcell.Style) {
+	if x < 0 || y < 0 || x >= renderer.width || y >= renderer.height {
+		return
+	}
+
+	renderer.current[y*renderer.width+x] = Cell{char, style}
+}
+
+// Writes the cells that differ from the previous frame to the screen, and returns how many were written.
+func (renderer *Renderer) Flush(screen tcell.Screen) int {
+	written := 0
+	for i, cell := range renderer.current {
+		if !renderer.full && cell == renderer.previous[i] {
+			continue
+		}
+		screen.SetContent(i%renderer.width, i/renderer.width, cell.char, nil, cell.style)
+		written++
+	}
+
+	renderer.current, renderer.previous = renderer.previous, renderer.current
+	renderer.full = false
+
+	return written
+}
+
+// Forces every cell to be written on the next flush, e.g. after the terminal was resized or cleared.
+func (renderer *Renderer) Invalidate() {
+	renderer.full = true
+}
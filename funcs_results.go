@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var (
+	resultsItems    = []string{"Retry with same seed", "Pick another map", "Quit"}
+	resultsChoices  = []int{ResultsRetry, ResultsPickMap, ResultsQuit}
+	sparklineLevels = []rune("▁▂▃▄▅▆▇█")
+)
+
+// Shows the results of the finished game until the player chooses what to do next.
+// Returns one of the results screen choices.
+func (game *Game) ShowResults() int {
+	cursor := 0
+	game.renderer.Invalidate()
+	for {
+		game.DrawResults(cursor)
+
+		switch ev := game.screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyUp:
+				cursor = (cursor + len(resultsItems) - 1) % len(resultsItems)
+			case tcell.KeyDown:
+				cursor = (cursor + 1) % len(resultsItems)
+			case tcell.KeyEnter:
+				return resultsChoices[cursor]
+			case tcell.KeyEscape:
+				return ResultsQuit
+			}
+		case *tcell.EventMouse:
+			_, y := ev.Position()
+			pressed := ev.Buttons() &^ game.mouse.buttons // Only buttons that went down with this event, not ones held since before
+			game.mouse.buttons = ev.Buttons()
+			_, itemsTop := game.ResultsPosition()
+			if item := y - itemsTop; item >= 0 && item < len(resultsItems) {
+				cursor = item
+				if pressed&tcell.Button1 != 0 {
+					return resultsChoices[cursor]
+				}
+			}
+		case *tcell.EventResize:
+			game.screen.Sync()
+			game.renderer.Invalidate()
+		case *tcell.EventError, nil: // The terminal is gone
+			return ResultsQuit
+		}
+	}
+}
+
+// Returns the lines summarizing the finished game.
+func (game *Game) ResultsLines() []string {
+	cause := "Quit"
+	if game.endCause == EndCauseBurned {
+		cause = "Burned by fire"
+	}
+
+	lines := []string{
+		"Game over: " + cause,
+		"",
+		"Map: " + game.mapName + " (seed " + strconv.FormatInt(game.seed, 10) + ")",
+	}
+	lines = append(lines, game.ScoreLines()...)
+	lines = append(lines,
+		"Ticks survived: "+strconv.Itoa(game.ticks),
+		game.RankString(),
+		"",
+	)
+
+	for _, state := range harvestedStateOrder {
+		lines = append(lines, harvestedStateNames[state]+": "+strconv.Itoa(game.stats.chopped[state]))
+	}
+
+	lines = append(lines,
+		"",
+		"Firebreaks dug: "+strconv.Itoa(game.stats.firebreaksDug),
+		"Fires started: "+strconv.Itoa(game.stats.firesStarted),
+		"Fires burnt out: "+strconv.Itoa(game.stats.firesBurntOut),
+		"Fires extinguished: "+strconv.Itoa(game.stats.firesExtinguished),
+		"Squirrels lost: "+strconv.Itoa(game.stats.squirrelsLost)+" of "+strconv.Itoa(game.stats.squirrelsLost+len(game.squirrels)),
+		"",
+	)
+
+	peak := 0.0
+	for _, cover := range game.stats.forestCover {
+		if cover > peak {
+			peak = cover
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("Forest cover over time (peak %.0f%%):", 100*peak),
+		Sparkline(game.stats.forestCover, SparklineWidth),
+	)
+
+	return lines
+}
+
+// Returns the lines with the score: the player's, or the team's or the winner followed by each player's score.
+func (game *Game) ScoreLines() []string {
+	switch game.mode {
+	case GameModeCoop:
+		return append([]string{"Team score: " + strconv.Itoa(game.Score())}, game.PlayerScoreLines()...)
+	case GameModeVersus:
+		winner := "Draw"
+		if player, found := game.Winner(); found {
+			winner = "Winner: " + game.PlayerName(player)
+		}
+		return append([]string{winner}, game.PlayerScoreLines()...)
+	}
+
+	return []string{"Score: " + strconv.Itoa(game.player.score)}
+}
+
+func (game *Game) PlayerScoreLines() []string {
+	var lines []string
+	for _, player := range game.Players() {
+		line := "  " + game.PlayerName(player) + ": " + strconv.Itoa(player.score) + " points"
+		if player.IsBurned() {
+			line += ", burned"
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Returns the left edge of the results and the row of the first menu item, keeping the results centered.
+func (game *Game) ResultsPosition() (left int, itemsTop int) {
+	lines := game.ResultsLines()
+	width := 0
+	for _, line := range append(lines, resultsItems...) {
+		if utf8.RuneCountInString(line)+2 > width { // Leave room for the cursor
+			width = utf8.RuneCountInString(line) + 2
+		}
+	}
+
+	w, h := game.screen.Size()
+	top := (h - len(lines) - len(resultsItems) - 1) / 2
+	if top < 0 {
+		top = 0
+	}
+
+	return (w - width) / 2, top + len(lines) + 1
+}
+
+func (game *Game) DrawResults(cursor int) {
+	w, h := game.screen.Size()
+	game.renderer.Begin(w, h)
+
+	lines := game.ResultsLines()
+	left, itemsTop := game.ResultsPosition()
+	top := itemsTop - len(lines) - 1
+	for i, line := range lines {
+		style := tcell.StyleDefault
+		if i == 0 {
+			style = style.Bold(true)
+		}
+		game.DrawText(left+2, top+i, w-left-2, line, style)
+	}
+
+	for i, item := range resultsItems {
+		if i == cursor {
+			game.renderer.SetContent(left, itemsTop+i, '>', tcell.StyleDefault)
+		}
+		game.DrawText(left+2, itemsTop+i, w-left-2, item, tcell.StyleDefault)
+	}
+
+	game.renderer.Flush(game.screen)
+	game.screen.Show()
+}
+
+// Draws the values as a line of block characters, scaled so the largest value is a full block.
+// If there are more values than fit in the width, neighbouring values are averaged.
+func Sparkline(values []float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	buckets := values
+	if len(values) > width {
+		buckets = make([]float64, width)
+		for i := range buckets {
+			start := i * len(values) / width
+			end := (i + 1) * len(values) / width
+			sum := 0.0
+			for _, value := range values[start:end] {
+				sum += value
+			}
+			buckets[i] = sum / float64(end-start)
+		}
+	}
+
+	peak := 0.0
+	for _, value := range buckets {
+		if value > peak {
+			peak = value
+		}
+	}
+
+	line := make([]rune, len(buckets))
+	for i, value := range buckets {
+		level := 0
+		if peak > 0 {
+			level = int(value / peak * float64(len(sparklineLevels)-1))
+		}
+		line[i] = sparklineLevels[level]
+	}
+
+	return string(line)
+}
+
+func (game *Game) RankString() string {
+	if game.rankErr != nil {
+		return "High score not saved: " + game.rankErr.Error()
+	} else if game.rank == 0 {
+		return "Not in the top " + strconv.Itoa(HighScoreLimit) + " for this map"
+	}
+
+	return "High score rank: #" + strconv.Itoa(game.rank) + " for this map"
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		width  int
+		want   string
+	}{
+		{nil, 10, ""},
+		{[]float64{0, 0}, 10, "▁▁"},
+		{[]float64{0, 0.5, 1}, 10, "▁▄█"},
+		{[]float64{0, 0, 1, 1}, 2, "▁█"},
+	}
+
+	for _, test := range tests {
+		if got := Sparkline(test.values, test.width); got != test.want {
+			t.Errorf("Sparkline(%v, %d) = %q, want %q", test.values, test.width, got, test.want)
+		}
+	}
+}
+
+func TestResultsAfterBurning(t *testing.T) {
+	game, screen := newMouseTestGame(t)
+	game.player.hitPointsCurrent = 1
+	game.world.content[game.player.position] = NewFire(game.player.position, 1)
+	game.CheckFireDamage()
+	if !game.exit || game.endCause != EndCauseBurned {
+		t.Fatalf("player at 1 HP standing in fire should burn, exit %t cause %d", game.exit, game.endCause)
+	}
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	if choice := game.ShowResults(); choice != ResultsPickMap {
+		t.Errorf("second item should pick another map, got %d", choice)
+	}
+}
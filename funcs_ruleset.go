@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Returns the rules for the difficulty in the settings.
+func (settings *Settings) Ruleset() Ruleset {
+	if settings.Difficulty == DifficultyCustom {
+		return settings.Custom
+	}
+
+	return rulesetPresets[settings.Difficulty]
+}
+
+// Returns the value of the rule at the given index of rulesetFields.
+func (rules *Ruleset) Field(index int) float64 {
+	switch index {
+	case 0:
+		return rules.FireSpawnChance
+	case 1:
+		return rules.FireSpreadChance
+	case 2:
+		return float64(rules.FireBurnoutHalflife)
+	case 3:
+		return rules.GrowthChanceSeed
+	case 4:
+		return float64(rules.MaxHitPointsPlayer)
+	case 5:
+		return float64(rules.DamageFire)
+	}
+
+	return 0
+}
+
+// Sets the rule at the given index of rulesetFields, clamped to the limits of the field.
+func (rules *Ruleset) SetField(index int, value float64) {
+	field := rulesetFields[index]
+	value = math.Max(field.min, math.Min(field.max, value))
+	value = math.Round(value*math.Pow10(field.decimals)) / math.Pow10(field.decimals) // Avoid drifting from adding up steps
+
+	switch index {
+	case 0:
+		rules.FireSpawnChance = value
+	case 1:
+		rules.FireSpreadChance = value
+	case 2:
+		rules.FireBurnoutHalflife = int(value)
+	case 3:
+		rules.GrowthChanceSeed = value
+	case 4:
+		rules.MaxHitPointsPlayer = int(value)
+	case 5:
+		rules.DamageFire = int(value)
+	}
+}
+
+// Changes the rule at the given index of rulesetFields by the given number of steps.
+func (rules *Ruleset) StepField(index int, steps int) {
+	rules.SetField(index, rules.Field(index)+float64(steps)*rulesetFields[index].step)
+}
+
+func (rules *Ruleset) FieldString(index int) string {
+	return strconv.FormatFloat(rules.Field(index), 'f', rulesetFields[index].decimals, 64)
+}
+
+// Returns an error if any rule is outside the limits of its field.
+func (rules *Ruleset) Validate() error {
+	for i, field := range rulesetFields {
+		if value := rules.Field(i); value < field.min || value > field.max {
+			return fmt.Errorf("%s must be between %v and %v, got %v", field.label, field.min, field.max, value)
+		}
+	}
+
+	return nil
+}
+
+// Returns the keys of the rules in settings.json, in alphabetical order.
+func RuleKeys() []string {
+	var keys []string
+	for key := range RuleValues(Ruleset{}) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Returns each rule written as in settings.json, keyed by its key there.
+func RuleValues(rules Ruleset) map[string]string {
+	data, _ := json.Marshal(rules)
+	var raw map[string]json.RawMessage
+	json.Unmarshal(data, &raw)
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		values[key] = string(value)
+	}
+
+	return values
+}
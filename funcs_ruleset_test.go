@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestRulesetStepFieldClamps(t *testing.T) {
+	rules := rulesetPresets[DifficultyNormal]
+	rules.StepField(4, 1000)
+	if rules.MaxHitPointsPlayer != 99 {
+		t.Errorf("max hitpoints should be clamped to 99, got %d", rules.MaxHitPointsPlayer)
+	}
+	rules.StepField(0, -1000)
+	if rules.FireSpawnChance != 0 {
+		t.Errorf("fire spawn chance should be clamped to 0, got %v", rules.FireSpawnChance)
+	}
+	for i := 0; i < 3; i++ {
+		rules.StepField(0, 1)
+	}
+	if got := rules.FieldString(0); got != "0.003" {
+		t.Errorf("three steps of 0.001 should give 0.003, got %s", got)
+	}
+	if err := rules.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSettingsRuleset(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Difficulty = DifficultyHard
+	if rules := settings.Ruleset(); rules != rulesetPresets[DifficultyHard] {
+		t.Errorf("hard difficulty should use the hard preset, got %+v", rules)
+	}
+
+	settings.Custom.DamageFire = 0
+	settings.Difficulty = DifficultyCustom
+	if rules := settings.Ruleset(); rules.DamageFire != 0 {
+		t.Errorf("custom difficulty should use the custom rules, got %+v", rules)
+	}
+}
+
+func TestNewGameUsesDifficulty(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	settings := DefaultSettings()
+	settings.Difficulty = DifficultyHard
+	mapFile, err := FindMap(settings.MapSources(), "liten_skog.karta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game, err := NewGame(screen, nil, &settings, mapFile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.rules != rulesetPresets[DifficultyHard] {
+		t.Errorf("new game should use the hard preset, got %+v", game.rules)
+	}
+	if game.player.hitPointsMax != game.rules.MaxHitPointsPlayer || game.player.hitPointsCurrent != game.rules.MaxHitPointsPlayer {
+		t.Errorf("player should start with %d HP, got %d/%d", game.rules.MaxHitPointsPlayer, game.player.hitPointsCurrent, game.player.hitPointsMax)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Returns the path of the saved game in the user config directory.
+func SaveFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, SaveFileName), nil
+}
+
+// Writes the world, the player and the squirrels to the given file.
+func (game *Game) Save(fileName string) error {
+	saveFile := SaveFile{
+		Map:        game.mapName,
+		Mode:       game.mode,
+		Difficulty: game.difficulty,
+		Rules:      game.rules,
+		Seed:       game.seed,
+		Width:      game.world.width,
+		Height:     game.world.height,
+		Ticks:      game.ticks,
+		Player:     SaveActor(&game.player),
+		Camera:     game.camera,
+		Stats:      SaveStats(game.stats),
+	}
+
+	for _, player := range game.others {
+		saveFile.Others = append(saveFile.Others, SaveActor(player))
+	}
+
+	for _, squirrel := range game.squirrels {
+		saveFile.Squirrels = append(saveFile.Squirrels, SaveActor(squirrel))
+	}
+
+	for position, content := range game.world.content {
+		switch content := content.(type) {
+		case Object:
+			saveFile.Objects = append(saveFile.Objects, SavedObject{position.x, position.y, game.settings.tiles[content.key].id, content.collidable, content.flammable, content.plantable})
+		case *Tree:
+			saveFile.Trees = append(saveFile.Trees, SavedTree{position.x, position.y, treeStateNames[content.state]})
+		case *Fire:
+			saveFile.Fires = append(saveFile.Fires, SavedFire{position.x, position.y, content.age, content.phase, content.fuel})
+		}
+	}
+
+	buffer, err := json.Marshal(saveFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, buffer, 0644)
+}
+
+// Reads a game written by Save.
+func LoadGame(screen tcell.Screen, controls *Controls, settings *Settings, fileName string) (Game, error) {
+	var game Game
+	buffer, err := os.ReadFile(fileName)
+	if err != nil {
+		return game, err
+	}
+
+	saveFile := SaveFile{Difficulty: DifficultyNormal, Rules: rulesetPresets[DifficultyNormal]} // For games saved before difficulties
+	if err := json.Unmarshal(buffer, &saveFile); err != nil {
+		return game, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	worldContent := make(map[Coordinate]interface{})
+	for _, object := range saveFile.Objects {
+		key, found := TileKeyByName(settings.tiles, object.Name)
+		if !found {
+			return game, fmt.Errorf("%s: unknown object %q", fileName, object.Name)
+		}
+		worldContent[Coordinate{object.X, object.Y}] = Object{key, object.Collidable, object.Flammable, object.Plantable}
+	}
+	for _, tree := range saveFile.Trees {
+		state, found := TreeStateByName(tree.State)
+		if !found {
+			return game, fmt.Errorf("%s: unknown tree state %q", fileName, tree.State)
+		}
+		worldContent[Coordinate{tree.X, tree.Y}] = &Tree{Coordinate{tree.X, tree.Y}, state}
+	}
+	for _, fire := range saveFile.Fires {
+		if fire.Fuel <= 0 { // Saved before fires had fuel
+			fire.Fuel = 1
+		}
+		worldContent[Coordinate{fire.X, fire.Y}] = &Fire{Coordinate{fire.X, fire.Y}, fire.Age, fire.Phase, fire.Fuel}
+	}
+
+	game.screen = screen
+	game.controls = controls
+	game.settings = settings
+	game.difficulty = saveFile.Difficulty
+	game.rules = saveFile.Rules
+	game.mapName = saveFile.Map
+	game.mode = saveFile.Mode
+	if game.mode == "" {
+		game.mode = GameModeClassic
+	}
+	game.seed = saveFile.Seed
+	game.random = rand.New(rand.NewSource(time.Now().UTC().UnixNano())) // Where the saved game's randomizer was is not saved
+	game.world = NewWorld(saveFile.Width, saveFile.Height, worldContent)
+	game.player = LoadActor(saveFile.Player)
+	for _, saved := range saveFile.Others {
+		player := LoadActor(saved)
+		game.others = append(game.others, &player)
+	}
+	game.camera = saveFile.Camera
+	if game.camera == "" {
+		game.camera = CameraSplit
+	}
+	game.squirrels = make(map[int]*Actor)
+	for index, squirrel := range saveFile.Squirrels {
+		actor := LoadActor(squirrel)
+		game.squirrels[index] = &actor
+	}
+	game.menu = Menu{messages: []string{}}
+	game.ticks = saveFile.Ticks
+	if game.stats, err = LoadStats(saveFile.Stats); err != nil {
+		return game, fmt.Errorf("%s: %v", fileName, err)
+	}
+	game.UpdateLayout()
+
+	return game, nil
+}
+
+func SaveActor(actor *Actor) SavedActor {
+	return SavedActor{actor.position.x, actor.position.y, actor.visionRadius, actor.score, actor.hitPointsCurrent, actor.hitPointsMax, actor.inventory.wood, actor.inventory.seeds}
+}
+
+// Paths and destinations are not saved. Squirrels pick new ones on the next update.
+func LoadActor(saved SavedActor) Actor {
+	return Actor{
+		position:         Coordinate{saved.X, saved.Y},
+		visionRadius:     saved.VisionRadius,
+		score:            saved.Score,
+		hitPointsCurrent: saved.HitPoints,
+		hitPointsMax:     saved.HitPointsMax,
+		inventory:        Inventory{saved.Wood, saved.Seeds},
+	}
+}
+
+func SaveStats(stats Stats) SavedStats {
+	saved := SavedStats{
+		FirebreaksDug:     stats.firebreaksDug,
+		FiresStarted:      stats.firesStarted,
+		FiresBurntOut:     stats.firesBurntOut,
+		FiresExtinguished: stats.firesExtinguished,
+		SquirrelsLost:     stats.squirrelsLost,
+		ForestCover:       stats.forestCover,
+	}
+	for state, count := range stats.chopped {
+		if saved.Chopped == nil {
+			saved.Chopped = make(map[string]int)
+		}
+		saved.Chopped[treeStateNames[state]] = count
+	}
+
+	return saved
+}
+
+// Games saved before stats were saved start counting from nothing.
+func LoadStats(saved SavedStats) (Stats, error) {
+	stats := Stats{
+		firebreaksDug:     saved.FirebreaksDug,
+		firesStarted:      saved.FiresStarted,
+		firesBurntOut:     saved.FiresBurntOut,
+		firesExtinguished: saved.FiresExtinguished,
+		squirrelsLost:     saved.SquirrelsLost,
+		forestCover:       saved.ForestCover,
+	}
+	for name, count := range saved.Chopped {
+		state, found := TreeStateByName(name)
+		if !found {
+			return stats, fmt.Errorf("unknown tree state %q", name)
+		}
+		if stats.chopped == nil {
+			stats.chopped = make(map[int]int)
+		}
+		stats.chopped[state] = count
+	}
+
+	return stats, nil
+}
+
+// Looks up a tile of the registry by id, or by display name for games saved before tiles had ids.
+func TileKeyByName(registry map[int]Tile, name string) (int, bool) {
+	if key, found := TileKeyById(registry, name); found {
+		return key, true
+	}
+	for key, tile := range registry {
+		if tile.name == name {
+			return key, true
+		}
+	}
+
+	return 0, false
+}
+
+func TreeStateByName(name string) (int, bool) {
+	for state, stateName := range treeStateNames {
+		if stateName == name {
+			return state, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Reads the options of the serve subcommand. The map, seed, difficulty and game mode come from the settings,
+// and can be overridden by flags like when playing locally.
+func ParseServeFlags(args []string, settings Settings) (ServeOptions, error) {
+	options := ServeOptions{address: ServeDefaultAddress, tickRate: ServeDefaultTickRate}
+	flags := flag.NewFlagSet("skogshuggare "+ServeCommand, flag.ContinueOnError)
+	flags.StringVar(&options.address, "addr", options.address, "address to listen for players and spectators on, or "+NetUnixPrefix+"path for a Unix socket")
+	flags.StringVar(&settings.Map, "map", settings.Map, "map file to play")
+	flags.StringVar(&settings.MapsDir, "maps-dir", settings.MapsDir, "directory to search for maps before the user and built-in maps")
+	flags.Int64Var(&settings.Seed, "seed", settings.Seed, "seed of the first round, or 0 for a random seed")
+	flags.IntVar(&options.tickRate, "tick-rate", options.tickRate, "milliseconds between ticks")
+	flags.IntVar(&settings.VisionRadius, "vision", settings.VisionRadius, "maximum distance each player can see")
+	flags.StringVar(&settings.Difficulty, "difficulty", settings.Difficulty, "one of "+strings.Join(difficultyOrder, ", "))
+	flags.StringVar(&settings.Mode, "mode", settings.Mode, "game mode, one of "+strings.Join(multiplayerModeOrder, ", "))
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare %s [flags]\n\nRuns a game that players join over the network with skogshuggare %s, and others watch with skogshuggare %s.\n\nFlags:\n", ServeCommand, JoinCommand, SpectateCommand)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+	if flags.NArg() > 0 {
+		return options, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if options.tickRate <= 0 {
+		return options, fmt.Errorf("tick rate must be positive, got %d", options.tickRate)
+	}
+	if settings.Map == "" {
+		return options, fmt.Errorf("no map given, use --map to pick one")
+	}
+	if err := settings.Validate(); err != nil {
+		return options, err
+	}
+	options.settings = settings
+
+	return options, nil
+}
+
+// Listens on the address of the options and runs the game for whoever joins, until the process is interrupted.
+func Serve(options ServeOptions) error {
+	mapFile, err := FindMap(options.settings.MapSources(), options.settings.Map)
+	if err != nil {
+		return err
+	}
+	server, err := NewServer(options.settings, mapFile, options.settings.NewSeed(time.Now().UTC().UnixNano()))
+	if err != nil {
+		return err
+	}
+	network, address := SplitAddress(options.address)
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving %s on %s\n", mapFile.fileName, listener.Addr())
+
+	// Stop on Ctrl-C or when killed, so that the listener is closed, which removes a Unix socket's file.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+
+	ticker := time.NewTicker(time.Duration(options.tickRate) * time.Millisecond)
+	defer ticker.Stop()
+	return server.Run(listener, ticker.C)
+}
+
+// Runs a game on the loopback interface for the process's own clients to join, like SSH sessions, and returns its
+// address. A server that fails exits the process.
+func ServeLoopback(options ServeOptions) (string, error) {
+	mapFile, err := FindMap(options.settings.MapSources(), options.settings.Map)
+	if err != nil {
+		return "", err
+	}
+	server, err := NewServer(options.settings, mapFile, options.settings.NewSeed(time.Now().UTC().UnixNano()))
+	if err != nil {
+		return "", err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	ticker := time.NewTicker(time.Duration(options.tickRate) * time.Millisecond)
+	go func() {
+		if err := server.Run(listener, ticker.C); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	return listener.Addr().String(), nil
+}
+
+// Returns the network and address to listen on or dial for an address given on the command line,
+// which is a Unix socket if it starts with NetUnixPrefix, and a TCP address otherwise.
+func SplitAddress(address string) (string, string) {
+	if path := strings.TrimPrefix(address, NetUnixPrefix); path != address {
+		return "unix", path
+	}
+
+	return "tcp", address
+}
+
+// Returns a server for the map, which starts its first round with the given seed.
+func NewServer(settings Settings, mapFile MapFile, seed int64) (*Server, error) {
+	settings.Players = 1 // Players join one by one
+	server := &Server{settings: settings, mapFile: mapFile, seed: seed, nextId: 1, events: make(chan ServerEvent), done: make(chan struct{})}
+	if err := server.NewRound(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// Starts a new game on the map, with everyone who is still in the game from the last round. Each round gets the next seed.
+func (server *Server) NewRound() error {
+	game, err := NewGame(nil, nil, &server.settings, server.mapFile, server.seed)
+	if err != nil {
+		return err
+	}
+	game.mode = server.settings.Mode
+	game.camera = CameraOwn
+	server.game = game
+	server.start = game.player.position
+	server.seed++
+	server.sent = nil
+
+	players := server.players
+	server.players = nil
+	for _, player := range players {
+		player.actor = server.AddActor()
+		player.actions = nil
+		server.players = append(server.players, player)
+	}
+	server.UpdateNames()
+
+	return nil
+}
+
+// Adds a player to the game, on the map's start if nobody else is in the game, or else on the free tile nearest to it.
+func (server *Server) AddActor() *Actor {
+	game := &server.game
+	actor := Actor{visionRadius: server.settings.VisionRadius, hitPointsCurrent: game.rules.MaxHitPointsPlayer, hitPointsMax: game.rules.MaxHitPointsPlayer}
+	if len(server.players) == 0 {
+		actor.position = server.start
+		game.player = actor
+		game.others = nil
+		return &game.player
+	}
+
+	actor.position = game.SpawnPosition(server.start)
+	game.others = append(game.others, &actor)
+	return &actor
+}
+
+// Points each player at their actor, and gives the game their names, after players were added or removed.
+func (server *Server) UpdateNames() {
+	server.game.names = nil
+	for i, actor := range server.game.Players() {
+		if i < len(server.players) {
+			server.players[i].actor = actor
+			server.game.names = append(server.game.names, server.players[i].name)
+		}
+	}
+}
+
+// Accepts clients on the listener, and runs the game until Close is called, with a tick every time tick fires.
+// Only this goroutine touches the game. Connections send it their commands as events.
+func (server *Server) Run(listener net.Listener, tick <-chan time.Time) error {
+	defer func() {
+		listener.Close()
+		for _, player := range server.players {
+			if player.client != nil {
+				server.Drop(player.client)
+			}
+		}
+		for len(server.spectators) > 0 {
+			server.Drop(server.spectators[0])
+		}
+	}()
+
+	go server.Accept(listener)
+	for {
+		select {
+		case event := <-server.events:
+			server.HandleEvent(event)
+		case <-tick:
+			if err := server.Tick(); err != nil {
+				return err
+			}
+		case <-server.done:
+			return nil
+		}
+	}
+}
+
+// Stops Run, which closes the listener and every connection.
+func (server *Server) Close() {
+	close(server.done)
+}
+
+func (server *Server) Accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.Read(conn)
+	}
+}
+
+// Reads commands from the connection, one JSON object per line, and sends them to Run as events until the connection closes.
+func (server *Server) Read(conn net.Conn) {
+	client := &ServerClient{conn: conn, send: make(chan NetMessage, NetSendBuffer)}
+	go client.Write()
+
+	lines := bufio.NewScanner(conn)
+	for lines.Scan() {
+		if strings.TrimSpace(lines.Text()) == "" {
+			continue
+		}
+		event := ServerEvent{client: client}
+		event.err = json.Unmarshal(lines.Bytes(), &event.command)
+		if !server.Post(event) {
+			return
+		}
+	}
+	server.Post(ServerEvent{client: client, closed: true})
+}
+
+// Sends an event to Run. Returns false if the server was closed.
+func (server *Server) Post(event ServerEvent) bool {
+	select {
+	case server.events <- event:
+		return true
+	case <-server.done:
+		return false
+	}
+}
+
+// Writes the messages for the client to its connection, until the client is dropped or writing fails.
+func (client *ServerClient) Write() {
+	encoder := json.NewEncoder(client.conn)
+	for message := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(NetWriteTimeout))
+		if err := encoder.Encode(message); err != nil {
+			break
+		}
+	}
+	client.conn.Close()
+}
+
+// Queues a message for the client. A client too slow to keep up is dropped, and can reconnect once it catches up.
+func (server *Server) Send(client *ServerClient, message NetMessage) {
+	if client.dropped {
+		return
+	}
+
+	select {
+	case client.send <- message:
+	default:
+		server.Drop(client)
+	}
+}
+
+// Closes the client's connection. The player stays in the game, so that the client can rejoin with its token.
+func (server *Server) Drop(client *ServerClient) {
+	if player := server.PlayerOf(client); player != nil {
+		player.client = nil
+		server.messages = append(server.messages, player.name+" lost connection")
+	}
+	for i, spectator := range server.spectators {
+		if spectator == client {
+			server.spectators = append(server.spectators[:i:i], server.spectators[i+1:]...)
+			break
+		}
+	}
+	if !client.dropped {
+		client.dropped = true
+		close(client.send)
+	}
+}
+
+// Returns the player the client is connected as, or nil if it hasn't joined.
+func (server *Server) PlayerOf(client *ServerClient) *RemotePlayer {
+	for _, player := range server.players {
+		if player.client == client {
+			return player
+		}
+	}
+
+	return nil
+}
+
+// Returns whether the client is watching the game.
+func (server *Server) IsSpectator(client *ServerClient) bool {
+	for _, spectator := range server.spectators {
+		if spectator == client {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (server *Server) HandleEvent(event ServerEvent) {
+	player := server.PlayerOf(event.client)
+	spectator := server.IsSpectator(event.client)
+	command := event.command
+	switch {
+	case event.closed:
+		server.Drop(event.client)
+	case event.err != nil:
+		server.Send(event.client, NetMessage{Error: event.err.Error()})
+	case (command.Type == NetCommandJoin || command.Type == NetCommandSpectate) && (player != nil || spectator):
+		server.Send(event.client, NetMessage{Error: "already joined"})
+	case command.Type == NetCommandJoin:
+		server.Join(event.client, command)
+	case command.Type == NetCommandSpectate:
+		server.Spectate(event.client)
+	case spectator && command.Type == NetCommandLeave:
+		server.Drop(event.client)
+	case spectator && command.Type == NetCommandAction:
+		server.Send(event.client, NetMessage{Error: "spectators can't act"})
+	case player == nil:
+		server.Send(event.client, NetMessage{Error: "join before sending " + strconv.Quote(command.Type)})
+	case command.Type == NetCommandLeave:
+		server.Leave(player)
+		server.Drop(event.client)
+	case command.Type == NetCommandAction:
+		action, err := BotAction{command.Action, command.Dir}.PlayerAction()
+		if err != nil {
+			server.Send(event.client, NetMessage{Error: err.Error()})
+		} else if action != ActionNone && len(player.actions) < NetActionQueue {
+			player.actions = append(player.actions, action)
+		}
+	default:
+		server.Send(event.client, NetMessage{Error: fmt.Sprintf("unknown command %q, expected one of %s, %s, %s or %s", command.Type, NetCommandJoin, NetCommandSpectate, NetCommandAction, NetCommandLeave)})
+	}
+}
+
+// Adds the client's player to the game, or with the token of an earlier join, connects it to that player again.
+// The client gets its id and token, and the whole world.
+func (server *Server) Join(client *ServerClient, command NetCommand) {
+	var player *RemotePlayer
+	for _, other := range server.players {
+		if command.Token != "" && other.token == command.Token {
+			player = other
+		}
+	}
+
+	if player != nil {
+		if player.client != nil {
+			server.Drop(player.client)
+		}
+		server.messages = append(server.messages, player.name+" reconnected")
+	} else {
+		if len(server.players) >= NetMaxPlayers {
+			server.Send(client, NetMessage{Error: "the game is full"})
+			server.Drop(client)
+			return
+		}
+		token, err := NewToken()
+		if err != nil {
+			server.Send(client, NetMessage{Error: err.Error()})
+			server.Drop(client)
+			return
+		}
+
+		player = &RemotePlayer{id: server.nextId, name: PlayerNameOrDefault(command.Name, server.nextId), token: token}
+		server.nextId++
+		player.actor = server.AddActor()
+		server.players = append(server.players, player)
+		server.UpdateNames()
+		server.messages = append(server.messages, player.name+" joined")
+	}
+
+	player.client = client
+	snapshot := server.Snapshot()
+	state := server.State(DiffTiles(nil, snapshot), true)
+	server.Send(client, NetMessage{Id: player.id, Token: player.token, State: &state})
+}
+
+// Lets the client watch the game without a player. The client gets the whole world, and every state after it.
+func (server *Server) Spectate(client *ServerClient) {
+	server.spectators = append(server.spectators, client)
+	state := server.State(DiffTiles(nil, server.Snapshot()), true)
+	server.Send(client, NetMessage{State: &state})
+}
+
+// Takes the player out of the game for good. Once the last player leaves, the world waits for the next to join.
+func (server *Server) Leave(player *RemotePlayer) {
+	server.game.RemovePlayer(player.actor)
+	for i, other := range server.players {
+		if other == player {
+			server.players = append(server.players[:i:i], server.players[i+1:]...)
+			break
+		}
+	}
+	server.UpdateNames()
+	server.messages = append(server.messages, player.name+" left")
+}
+
+// Cuts the name down to size, or names the player by their id if they gave no name.
+func PlayerNameOrDefault(name string, id int) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Player " + strconv.Itoa(id)
+	}
+	if runes := []rune(name); len(runes) > NetMaxNameLength {
+		return string(runes[:NetMaxNameLength])
+	}
+
+	return name
+}
+
+// Returns a random secret for a player to rejoin with.
+func NewToken() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buffer), nil
+}
+
+// Performs each player's next action and advances the world, then sends what changed to every client.
+// Time stands still while no player is connected, even with spectators watching. A round that ends is followed right away by the next one.
+func (server *Server) Tick() error {
+	connected := false
+	for _, player := range server.players {
+		connected = connected || player.client != nil
+	}
+	if !connected {
+		return nil
+	}
+
+	game := &server.game
+	game.menu.messages = nil
+	for _, player := range server.players {
+		if len(player.actions) > 0 {
+			game.PerformAction(player.actor, player.actions[0])
+			player.actions = player.actions[1:]
+		}
+	}
+	game.UpdateWorld()
+	server.messages = append(server.messages, game.menu.messages...)
+
+	if !game.exit {
+		server.Broadcast(false)
+		return nil
+	}
+
+	server.messages = append(server.messages, "Round over. "+game.ScoreLines()[0])
+	server.Broadcast(true)
+	if err := server.NewRound(); err != nil {
+		return err
+	}
+	server.Broadcast(false)
+
+	return nil
+}
+
+// Sends what changed since the last state to every connected player and spectator, along with the messages since then.
+func (server *Server) Broadcast(done bool) {
+	snapshot := server.Snapshot()
+	state := server.State(DiffTiles(server.sent, snapshot), server.sent == nil)
+	state.Messages = server.messages
+	state.Done = done
+	server.sent = snapshot
+	server.messages = nil
+
+	for _, player := range server.players {
+		if player.client != nil {
+			server.Send(player.client, NetMessage{State: &state})
+		}
+	}
+	for _, spectator := range append([]*ServerClient(nil), server.spectators...) { // Sending can drop a spectator
+		server.Send(spectator, NetMessage{State: &state})
+	}
+}
+
+// Returns the state of the game with the given tiles.
+func (server *Server) State(tiles []NetTile, full bool) NetState {
+	game := &server.game
+	state := NetState{
+		Tick:      game.ticks,
+		Width:     game.world.width,
+		Height:    game.world.height,
+		Full:      full,
+		Tiles:     tiles,
+		Squirrels: []NetSquirrel{},
+	}
+	for i, actor := range game.Players() {
+		if i >= len(server.players) { // The map's own player, before anyone joined
+			break
+		}
+		player := server.players[i]
+		state.Players = append(state.Players, NetPlayer{player.id, player.name, actor.position.x, actor.position.y, actor.visionRadius, actor.hitPointsCurrent, actor.hitPointsMax, actor.score, actor.inventory.wood, actor.inventory.seeds, player.client != nil})
+	}
+	for _, key := range game.SquirrelKeys() {
+		position := game.squirrels[key].position
+		state.Squirrels = append(state.Squirrels, NetSquirrel{key, position.x, position.y})
+	}
+
+	return state
+}
+
+// Returns how every tile of the world is sent to clients. Fires are sent as the frame of their animation they show.
+func (server *Server) Snapshot() map[Coordinate]NetTile {
+	snapshot := make(map[Coordinate]NetTile)
+	for coord, content := range server.game.world.content {
+		tile := NetTile{X: coord.x, Y: coord.y}
+		switch content := content.(type) {
+		case Object:
+			tile.Tile = server.game.settings.tiles[content.key].id
+		case *Tree:
+			tile.Tile = server.game.settings.tiles[content.Key()].id
+			tile.Tree = treeStateNames[content.state]
+		case *Fire:
+			tile.Tile = server.game.settings.tiles[content.Key()].id
+		}
+		snapshot[coord] = tile
+	}
+
+	return snapshot
+}
+
+// Returns the tiles that differ between the two snapshots, row by row. Tiles that are gone are sent as empty ground.
+func DiffTiles(old map[Coordinate]NetTile, new map[Coordinate]NetTile) []NetTile {
+	changed := []NetTile{}
+	for coord, tile := range new {
+		if old[coord] != tile {
+			changed = append(changed, tile)
+		}
+	}
+	for coord := range old {
+		if _, found := new[coord]; !found {
+			changed = append(changed, NetTile{X: coord.x, Y: coord.y})
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		if changed[i].Y != changed[j].Y {
+			return changed[i].Y < changed[j].Y
+		}
+		return changed[i].X < changed[j].X
+	})
+
+	return changed
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type testClient struct {
+	t       *testing.T
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+// Starts a server for the map on a free localhost port, without fires starting on their own.
+// The test ticks the server by sending on the returned channel.
+func startTestServer(t *testing.T, karta string) (string, chan<- time.Time) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener.Addr().String(), startTestServerOn(t, karta, listener)
+}
+
+// Starts a server for the map like startTestServer, on the given listener.
+func startTestServerOn(t *testing.T, karta string, listener net.Listener) chan<- time.Time {
+	settings := DefaultSettings()
+	settings.Difficulty = DifficultyCustom
+	settings.Custom.FireSpawnChance = 0
+	settings.Custom.FireSpreadChance = 0
+	server, err := NewServer(settings, MapFile{"test.karta", MapSourceBuiltin, []byte(karta), MapMetadata{}}, 1)
+	if err != nil {
+		listener.Close()
+		t.Fatal(err)
+	}
+
+	tick := make(chan time.Time)
+	go server.Run(listener, tick)
+	t.Cleanup(server.Close)
+
+	return tick
+}
+
+// Connects to the server and joins with the command, returning the server's answer. The command
+// joins as a player unless it says otherwise.
+func joinTestServer(t *testing.T, address string, join NetCommand) (*testClient, NetMessage) {
+	network, address := SplitAddress(address)
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	client := &testClient{t, conn, json.NewEncoder(conn), json.NewDecoder(conn)}
+	if join.Type == "" {
+		join.Type = NetCommandJoin
+	}
+	client.send(join)
+
+	return client, client.receive()
+}
+
+func (client *testClient) send(command NetCommand) {
+	if err := client.encoder.Encode(command); err != nil {
+		client.t.Fatal(err)
+	}
+}
+
+func (client *testClient) receive() NetMessage {
+	client.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message NetMessage
+	if err := client.decoder.Decode(&message); err != nil {
+		client.t.Fatal(err)
+	}
+
+	return message
+}
+
+// Ticks the server until the client gets a state the condition holds for, and returns that state.
+func (client *testClient) tickUntil(tick chan<- time.Time, condition func(NetState) bool) NetState {
+	for i := 0; i < 20; i++ {
+		tick <- time.Now()
+		message := client.receive()
+		if message.State != nil && condition(*message.State) {
+			return *message.State
+		}
+	}
+	client.t.Fatal("condition not met after 20 ticks")
+
+	return NetState{}
+}
+
+func findNetPlayer(state NetState, id int) (NetPlayer, bool) {
+	for _, player := range state.Players {
+		if player.Id == id {
+			return player, true
+		}
+	}
+
+	return NetPlayer{}, false
+}
+
+func TestServerJoinsAndActions(t *testing.T) {
+	address, tick := startTestServer(t, ""+
+		"##########\n"+
+		"#   p    #\n"+
+		"#        #\n"+
+		"##########")
+
+	anna, welcome := joinTestServer(t, address, NetCommand{Name: "Anna"})
+	if welcome.Id != 1 || welcome.Token == "" || welcome.State == nil || !welcome.State.Full {
+		t.Fatalf("expected a welcome with an id, a token and the whole world, got %+v", welcome)
+	}
+	if player, _ := findNetPlayer(*welcome.State, 1); player.Name != "Anna" || (Coordinate{player.X, player.Y} != Coordinate{4, 1}) {
+		t.Errorf("expected Anna on the map's start, got %+v", player)
+	}
+
+	bertil, welcome := joinTestServer(t, address, NetCommand{Name: "Bertil with a long name"})
+	if welcome.Id != 2 || len(welcome.State.Players) != 2 {
+		t.Fatalf("expected a second player, got %+v", welcome)
+	}
+	if player, _ := findNetPlayer(*welcome.State, 2); player.Name != "Bertil w" || (Coordinate{player.X, player.Y} == Coordinate{4, 1}) {
+		t.Errorf("expected Bertil's name cut short, next to Anna, got %+v", player)
+	}
+
+	anna.send(NetCommand{Type: NetCommandAction, Action: BotActionMove, Dir: "left"})
+	state := anna.tickUntil(tick, func(state NetState) bool {
+		player, _ := findNetPlayer(state, 1)
+		return player.X == 3
+	})
+	for {
+		message := bertil.receive()
+		if message.State != nil && message.State.Tick == state.Tick {
+			if player, _ := findNetPlayer(*message.State, 1); player.X != 3 || message.State.Full {
+				t.Errorf("expected Bertil to see Anna move in a change, got %+v", message.State)
+			}
+			break
+		}
+	}
+
+	anna.send(NetCommand{Type: NetCommandAction, Action: "jump", Dir: "up"})
+	if message := anna.receive(); message.Error == "" {
+		t.Errorf("expected an error for an unknown action, got %+v", message)
+	}
+}
+
+func TestServerReconnectsAndLeaves(t *testing.T) {
+	address, tick := startTestServer(t, ""+
+		"##########\n"+
+		"#   p    #\n"+
+		"#        #\n"+
+		"##########")
+
+	anna, _ := joinTestServer(t, address, NetCommand{Name: "Anna"})
+	bertil, welcome := joinTestServer(t, address, NetCommand{Name: "Bertil"})
+	bertil.conn.Close()
+
+	// Bertil comes back as the same player.
+	bertil, rejoined := joinTestServer(t, address, NetCommand{Name: "Bertil", Token: welcome.Token})
+	if rejoined.Id != welcome.Id || rejoined.Token != welcome.Token || len(rejoined.State.Players) != 2 {
+		t.Fatalf("expected Bertil to rejoin as player %d, got %+v", welcome.Id, rejoined)
+	}
+
+	// Anna leaves, and Bertil takes over as player one.
+	anna.send(NetCommand{Type: NetCommandLeave})
+	state := bertil.tickUntil(tick, func(state NetState) bool {
+		return len(state.Players) == 1
+	})
+	if state.Players[0].Id != welcome.Id || !state.Players[0].Connected {
+		t.Errorf("expected only Bertil to be left, got %+v", state.Players)
+	}
+	bertil.send(NetCommand{Type: NetCommandAction, Action: BotActionMove, Dir: "down"})
+	bertil.tickUntil(tick, func(state NetState) bool {
+		return state.Players[0].Y == 2
+	})
+
+	// A token the server doesn't know joins as a new player.
+	_, welcome = joinTestServer(t, address, NetCommand{Token: "unknown"})
+	if welcome.Id != 3 || len(welcome.State.Players) != 2 {
+		t.Errorf("expected a new player 3, got %+v", welcome)
+	}
+	if player, _ := findNetPlayer(*welcome.State, 3); player.Name != "Player 3" {
+		t.Errorf("expected a player without a name to be named by id, got %q", player.Name)
+	}
+}
+
+func TestClientMirrorsServer(t *testing.T) {
+	address, tick := startTestServer(t, ""+
+		"################\n"+
+		"#p          s  #\n"+
+		"#              #\n"+
+		"#  s       #####\n"+
+		"#          #  f#\n"+
+		"################")
+
+	settings := DefaultSettings()
+	first, err := JoinServer(nil, nil, &settings, JoinOptions{address: address, name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.conn.Close()
+
+	changes := 0
+	for i := 0; i < 30; i++ {
+		tick <- time.Now()
+		var message NetMessage
+		if err := first.decoder.Decode(&message); err != nil {
+			t.Fatal(err)
+		}
+		if !message.State.Full {
+			changes += len(message.State.Tiles)
+		}
+		if err := first.Apply(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changes == 0 {
+		t.Errorf("expected the world to change over 30 ticks")
+	}
+
+	// A client joining now gets the whole world, which the first client built from the changes.
+	second, err := JoinServer(nil, nil, &settings, JoinOptions{address: address, name: "Bertil"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.conn.Close()
+
+	if first.game.ticks != second.game.ticks {
+		t.Fatalf("expected both clients at the same tick, got %d and %d", first.game.ticks, second.game.ticks)
+	}
+	if !reflect.DeepEqual(first.game.world, second.game.world) {
+		t.Errorf("expected both clients to have the same world")
+	}
+	if second.game.player.position != (Coordinate{2, 1}) && second.game.player.position != (Coordinate{1, 2}) {
+		t.Errorf("expected the second client's own player next to the start, got %v", second.game.player.position)
+	}
+	if len(second.game.others) != 1 || second.game.others[0].position != first.game.player.position || second.game.names[1] != "Anna" {
+		t.Errorf("expected the first client's player among the others, got %v and %q", second.game.others, second.game.names)
+	}
+}
+
+func TestClientReceivesEveryMessage(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	var stream strings.Builder
+	for tick := 1; tick <= 50; tick++ { // More than the event queue holds
+		json.NewEncoder(&stream).Encode(NetMessage{State: &NetState{Tick: tick}})
+	}
+	var client Client
+	go client.Receive(json.NewDecoder(strings.NewReader(stream.String())), screen)
+	time.Sleep(50 * time.Millisecond) // Let the queue fill up before reading it
+
+	next := func() any {
+		events := make(chan tcell.Event, 1)
+		go func() { events <- screen.PollEvent() }()
+		select {
+		case ev := <-events:
+			return ev.(*tcell.EventInterrupt).Data()
+		case <-time.After(time.Second):
+			t.Fatalf("expected another message")
+			return nil
+		}
+	}
+	for tick := 1; tick <= 50; tick++ {
+		if message, _ := next().(NetMessage); message.State == nil || message.State.Tick != tick {
+			t.Fatalf("expected the message of tick %d, got %+v", tick, message)
+		}
+	}
+	if _, lost := next().(ConnectionLost); !lost {
+		t.Errorf("expected the end of the stream to be a lost connection")
+	}
+}
+
+func TestServerSpectators(t *testing.T) {
+	// Spectators watch over a Unix socket, players join over it too.
+	socket := filepath.Join(t.TempDir(), "skogshuggare.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("no Unix sockets: %v", err)
+	}
+	tick := startTestServerOn(t, ""+
+		"################\n"+
+		"#p          s  #\n"+
+		"#              #\n"+
+		"#  s       #####\n"+
+		"#          #  f#\n"+
+		"################", listener)
+	address := NetUnixPrefix + socket
+
+	spectator, welcome := joinTestServer(t, address, NetCommand{Type: NetCommandSpectate})
+	if welcome.Id != 0 || welcome.Token != "" || welcome.State == nil || !welcome.State.Full || len(welcome.State.Players) != 0 {
+		t.Fatalf("expected the whole world without a player of its own, got %+v", welcome)
+	}
+	if squirrels := welcome.State.Squirrels; len(squirrels) != 2 || squirrels[0].Id == squirrels[1].Id {
+		t.Errorf("expected two squirrels told apart by id, got %+v", squirrels)
+	}
+
+	anna, _ := joinTestServer(t, address, NetCommand{Name: "Anna"})
+	spectator.send(NetCommand{Type: NetCommandAction, Action: BotActionMove, Dir: "left"})
+	if message := spectator.receive(); message.Error != "spectators can't act" {
+		t.Errorf("expected the spectator's action to be refused, got %+v", message)
+	}
+	spectator.send(NetCommand{Type: NetCommandJoin, Name: "Bertil"})
+	if message := spectator.receive(); message.Error != "already joined" {
+		t.Errorf("expected a spectator not to join as a player too, got %+v", message)
+	}
+
+	// The spectator sees Anna move, and nobody else took a turn.
+	anna.send(NetCommand{Type: NetCommandAction, Action: BotActionMove, Dir: "down"})
+	state := spectator.tickUntil(tick, func(state NetState) bool {
+		return len(state.Players) == 1 && state.Players[0].Y == 2
+	})
+	if state.Players[0].X != 1 {
+		t.Errorf("expected only Anna's move, got %+v", state)
+	}
+}
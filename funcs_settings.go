@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func DefaultSettings() Settings {
+	return Settings{
+		VisionRadius: DefaultVisionRadius,
+		TickRate:     TickRate,
+		Difficulty:   DifficultyNormal,
+		Custom:       rulesetPresets[DifficultyNormal],
+		Players:      1,
+		Mode:         GameModeCoop,
+		Camera:       CameraSplit,
+		tiles:        DefaultTiles(),
+	}
+}
+
+// Returns the path of the settings file in the user config directory.
+func SettingsFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, SettingsFileName), nil
+}
+
+// Reads settings from the given file. Settings missing from the file, or a missing file, give the defaults. They are
+// validated once command line flags have been applied, since those may fix them, e.g. with a --maps-dir holding the map.
+func LoadSettings(fileName string) (Settings, error) {
+	settings := DefaultSettings()
+
+	buffer, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	} else if err != nil {
+		return settings, err
+	}
+
+	if err := json.Unmarshal(buffer, &settings); err != nil {
+		return settings, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	return settings, nil
+}
+
+// Writes the custom rules to the settings file, leaving the rest of the file as it is, since the other settings in use
+// may come from flags.
+func (settings *Settings) SaveCustom() error {
+	if settings.SettingsFile == "" {
+		return nil
+	}
+
+	contents := make(map[string]json.RawMessage)
+	buffer, err := os.ReadFile(settings.SettingsFile)
+	if err == nil {
+		err = json.Unmarshal(buffer, &contents)
+	} else if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if contents["custom"], err = json.Marshal(settings.Custom); err != nil {
+		return err
+	}
+
+	buffer, err = json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(settings.SettingsFile), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(settings.SettingsFile, buffer, 0644)
+}
+
+// Overrides the settings with command line flags.
+func ParseFlags(args []string, settings Settings) (Settings, error) {
+	flags := flag.NewFlagSet("skogshuggare", flag.ContinueOnError)
+	flags.IntVar(&settings.VisionRadius, "vision", settings.VisionRadius, "maximum distance from the player that is drawn")
+	flags.StringVar(&settings.Map, "map", settings.Map, "map file to start right away, skipping the title menu")
+	flags.Int64Var(&settings.Seed, "seed", settings.Seed, "seed for new games, or 0 for a random seed each game")
+	flags.IntVar(&settings.TickRate, "tick-rate", settings.TickRate, "milliseconds between ticks")
+	flags.StringVar(&settings.MapsDir, "maps-dir", settings.MapsDir, "directory to search for maps before the user and built-in maps")
+	flags.StringVar(&settings.Difficulty, "difficulty", settings.Difficulty, "one of "+strings.Join(difficultyOrder, ", "))
+	flags.IntVar(&settings.Players, "players", settings.Players, fmt.Sprintf("number of players sharing the keyboard, up to %d", MaxLocalPlayers))
+	flags.StringVar(&settings.Mode, "mode", settings.Mode, "game mode for several players, one of "+strings.Join(multiplayerModeOrder, ", "))
+	flags.StringVar(&settings.Camera, "camera", settings.Camera, "how several players share the screen, one of "+strings.Join(cameraOrder, ", "))
+	flags.BoolVar(&settings.Bot, "bot", settings.Bot, "let a program play the map given with --map, reading actions from standard input and writing observations to standard output")
+	flags.StringVar(&settings.Record, "record", settings.Record, "record the games played to the file as an asciinema cast, e.g. out.cast")
+	flags.StringVar(&settings.Web, "web", settings.Web, "serve the map given with --map to web browsers on the address, e.g. :8080 for this computer only")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare [flags]\n\nDefaults are read from %s in the user config directory.\n\nFlags:\n", filepath.Join(ConfigDirName, SettingsFileName))
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return settings, err
+	}
+	if flags.NArg() > 0 {
+		return settings, fmt.Errorf("unexpected argument %q, use --vision to set the vision radius", flags.Arg(0))
+	}
+	if err := settings.Validate(); err != nil {
+		return settings, err
+	}
+	if settings.Bot && settings.Map == "" {
+		return settings, fmt.Errorf("--bot needs a map to play, given with --map")
+	}
+	if settings.Web != "" && settings.Map == "" {
+		return settings, fmt.Errorf("--web needs a map to play, given with --map")
+	}
+	if settings.Record != "" && (settings.Bot || settings.Web != "") {
+		return settings, fmt.Errorf("--record needs a game played in the terminal, not with --bot or --web")
+	}
+	if settings.Web != "" && settings.Bot {
+		return settings, fmt.Errorf("--web and --bot can't be used together")
+	}
+
+	return settings, nil
+}
+
+func (settings *Settings) Validate() error {
+	if settings.VisionRadius <= 0 {
+		return fmt.Errorf("vision radius must be positive, got %d", settings.VisionRadius)
+	}
+	if settings.TickRate <= 0 {
+		return fmt.Errorf("tick rate must be positive, got %d", settings.TickRate)
+	}
+
+	if !IsOneOf(settings.Difficulty, difficultyOrder) {
+		return fmt.Errorf("unknown difficulty %q, expected one of %s", settings.Difficulty, strings.Join(difficultyOrder, ", "))
+	}
+	if settings.Players < 1 || settings.Players > MaxLocalPlayers {
+		return fmt.Errorf("number of players must be from 1 to %d, got %d", MaxLocalPlayers, settings.Players)
+	}
+	if !IsOneOf(settings.Mode, multiplayerModeOrder) {
+		return fmt.Errorf("unknown mode %q, expected one of %s", settings.Mode, strings.Join(multiplayerModeOrder, ", "))
+	}
+	if !IsOneOf(settings.Camera, cameraOrder) {
+		return fmt.Errorf("unknown camera %q, expected one of %s", settings.Camera, strings.Join(cameraOrder, ", "))
+	}
+
+	if err := settings.Custom.Validate(); err != nil {
+		return fmt.Errorf("custom difficulty: %v", err)
+	}
+
+	if settings.Map != "" {
+		if _, err := FindMap(settings.MapSources(), settings.Map); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func IsOneOf(value string, options []string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the seed for a new game: the configured one, or the given random one if none is configured.
+func (settings *Settings) NewSeed(random int64) int64 {
+	if settings.Seed != 0 {
+		return settings.Seed
+	}
+
+	return random
+}
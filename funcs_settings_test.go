@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSettingsFileAndFlags(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(fileName, []byte(`{"vision": 20, "seed": 7, "difficulty": "hard"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.VisionRadius != 20 || settings.Seed != 7 || settings.Difficulty != DifficultyHard || settings.TickRate != TickRate {
+		t.Errorf("file should override only the settings it contains, got %+v", settings)
+	}
+
+	settings, err = ParseFlags([]string{"--vision", "30", "--map", "liten_skog.karta", "--tick-rate=50"}, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.VisionRadius != 30 || settings.Map != "liten_skog.karta" || settings.TickRate != 50 || settings.Seed != 7 {
+		t.Errorf("flags should override the file, got %+v", settings)
+	}
+	if seed := settings.NewSeed(123); seed != 7 {
+		t.Errorf("configured seed should be used, got %d", seed)
+	}
+}
+
+func TestSettingsValidatedAfterFlags(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(fileName, []byte(`{"map": "egen.karta", "vision": 0}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "egen.karta"), []byte("###\n#p#\n###"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseFlags(nil, settings); err == nil {
+		t.Errorf("expected an error for a map missing from the map directories and a vision of 0")
+	}
+	if _, err := ParseFlags([]string{"--maps-dir", dir, "--vision", "10"}, settings); err != nil {
+		t.Errorf("flags should fix the settings file, got %v", err)
+	}
+}
+
+func TestSettingsRejectInvalidValues(t *testing.T) {
+	for _, args := range [][]string{
+		{"--vision", "O"},
+		{"--vision", "0"},
+		{"--tick-rate", "-1"},
+		{"--difficulty", "impossible"},
+		{"--map", "missing.karta"},
+		{"--record", "out.cast", "--bot", "--map", "liten_skog.karta"},
+		{"20"},
+	} {
+		if _, err := ParseFlags(args, DefaultSettings()); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestSaveCustomRules(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(fileName, []byte(`{"vision": 20}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.SettingsFile = fileName
+	settings.VisionRadius = 30 // As if given by flag
+	settings.Custom.DamageFire = 3
+	if err := settings.SaveCustom(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Custom.DamageFire != 3 || saved.VisionRadius != 20 {
+		t.Errorf("expected only the custom rules to be saved, got %+v", saved)
+	}
+}
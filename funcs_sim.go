@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Reads the options of the sim subcommand. Maps are looked for in the same places as for playing,
+// and the difficulty and custom rules from the settings are where the rules start out.
+func ParseSimFlags(args []string, settings Settings) (SimOptions, error) {
+	options := SimOptions{seeds: SimDefaultSeeds, firstSeed: 1, ticks: SimDefaultTicks, workers: runtime.NumCPU(), format: SimFormatCSV}
+	var maps, difficulties, scriptFileName string
+	var autopilot bool
+	var variations RuleVariations
+	flags := flag.NewFlagSet("skogshuggare "+SimCommand, flag.ContinueOnError)
+	flags.StringVar(&maps, "maps", "", "comma-separated map files to simulate, or every map if empty")
+	flags.StringVar(&settings.MapsDir, "maps-dir", settings.MapsDir, "directory to search for maps before the user and built-in maps")
+	flags.IntVar(&options.seeds, "seeds", options.seeds, "number of seeds to run each map and set of rules with")
+	flags.Int64Var(&options.firstSeed, "seed", options.firstSeed, "first seed, the others count up from it")
+	flags.IntVar(&options.ticks, "ticks", options.ticks, "ticks to run each game for, unless the player burns first")
+	flags.StringVar(&difficulties, "difficulty", settings.Difficulty, "comma-separated difficulties to start the rules from, of "+strings.Join(difficultyOrder, ", "))
+	flags.Var(&variations, "vary", "rule to try several values of, e.g. fireSpreadChance=0.05,0.1 (can be repeated, every combination is run)")
+	flags.StringVar(&scriptFileName, "script", "", "file of player actions taken one per tick over and over, or an idle player if empty")
+	flags.BoolVar(&autopilot, "autopilot", false, "let the autopilot play, as a baseline to compare scripts with")
+	flags.IntVar(&options.workers, "workers", options.workers, "number of games to run at once")
+	flags.StringVar(&options.format, "format", options.format, "output format, "+SimFormatCSV+" or "+SimFormatJSON)
+	flags.StringVar(&options.output, "out", "", "file to write the results to, or standard output if empty")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare %s [flags]\n\nRuns games without a screen and writes statistics for each of them.\n\nFlags:\n", SimCommand)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+	if flags.NArg() > 0 {
+		return options, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if options.seeds <= 0 || options.ticks <= 0 || options.workers <= 0 {
+		return options, fmt.Errorf("seeds, ticks and workers must be positive")
+	}
+	if options.format != SimFormatCSV && options.format != SimFormatJSON {
+		return options, fmt.Errorf("unknown format %q, expected %s or %s", options.format, SimFormatCSV, SimFormatJSON)
+	}
+	if err := settings.Validate(); err != nil {
+		return options, err
+	}
+
+	paramSets, err := SimParamSets(strings.Split(difficulties, ","), settings.Custom, variations)
+	if err != nil {
+		return options, err
+	}
+	options.paramSets = paramSets
+
+	if autopilot && scriptFileName != "" {
+		return options, fmt.Errorf("autopilot and script can't be used together")
+	}
+	if autopilot {
+		options.script = []int{ActionAutopilot}
+	}
+	if scriptFileName != "" {
+		data, err := os.ReadFile(scriptFileName)
+		if err != nil {
+			return options, err
+		}
+		if options.script, err = ParseSimScript(string(data)); err != nil {
+			return options, fmt.Errorf("%s: %v", scriptFileName, err)
+		}
+	}
+
+	if maps != "" {
+		options.maps = strings.Split(maps, ",")
+	}
+	options.sources = settings.MapSources()
+	options.tiles = settings.tiles
+
+	return options, nil
+}
+
+func (variations *RuleVariations) String() string {
+	return strings.Join(*variations, " ")
+}
+
+func (variations *RuleVariations) Set(value string) error {
+	*variations = append(*variations, value)
+	return nil
+}
+
+// Returns the rules of each difficulty, with every combination of values of the varied rules.
+// Variations are written as a rule key of settings.json and its values, e.g. "fireSpreadChance=0.05,0.1".
+func SimParamSets(difficulties []string, custom Ruleset, variations []string) ([]SimParams, error) {
+	var paramSets []SimParams
+	for _, difficulty := range difficulties {
+		settings := Settings{Difficulty: difficulty, Custom: custom}
+		if _, isPreset := rulesetPresets[difficulty]; !isPreset && difficulty != DifficultyCustom {
+			return nil, fmt.Errorf("unknown difficulty %q, expected one of %s", difficulty, strings.Join(difficultyOrder, ", "))
+		}
+		paramSets = append(paramSets, SimParams{difficulty, settings.Ruleset()})
+	}
+
+	for _, variation := range variations {
+		key, values, _ := strings.Cut(variation, "=")
+		if _, isRule := RuleValues(Ruleset{})[key]; !isRule {
+			return nil, fmt.Errorf("unknown rule %q, expected one of %s", key, strings.Join(RuleKeys(), ", "))
+		}
+
+		var varied []SimParams
+		for _, paramSet := range paramSets {
+			for _, value := range strings.Split(values, ",") {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return nil, fmt.Errorf("%s: %q is not a number", key, value)
+				}
+				rules := paramSet.rules
+				if err := json.Unmarshal([]byte(fmt.Sprintf("{%q: %s}", key, value)), &rules); err != nil {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+				if err := rules.Validate(); err != nil {
+					return nil, err
+				}
+				varied = append(varied, SimParams{paramSet.name + " " + key + "=" + value, rules})
+			}
+		}
+		paramSets = varied
+	}
+
+	return paramSets, nil
+}
+
+// Reads a script of player actions, named as in controls.json and separated by whitespace. Wait does nothing for a tick,
+// and Autopilot takes the action the autopilot would.
+func ParseSimScript(text string) ([]int, error) {
+	var script []int
+	for _, name := range strings.Fields(text) {
+		if name == SimWait {
+			script = append(script, ActionNone)
+			continue
+		}
+		action, found := ActionByName(name)
+		if !found {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		script = append(script, action)
+	}
+	if len(script) == 0 {
+		return nil, fmt.Errorf("script has no actions")
+	}
+
+	return script, nil
+}
+
+// Runs a game of every map, set of rules and seed, spread over the workers, and writes the results in order.
+func RunSim(options SimOptions, output io.Writer) error {
+	var mapFiles []MapFile
+	if len(options.maps) == 0 {
+		mapFiles = FindMaps(options.sources)
+	}
+	for _, name := range options.maps {
+		mapFile, err := FindMap(options.sources, name)
+		if err != nil {
+			return err
+		}
+		mapFiles = append(mapFiles, mapFile)
+	}
+
+	var runs []SimRun
+	for _, mapFile := range mapFiles {
+		for _, paramSet := range options.paramSets {
+			for i := 0; i < options.seeds; i++ {
+				runs = append(runs, SimRun{mapFile, paramSet, options.firstSeed + int64(i), options.tiles})
+			}
+		}
+	}
+
+	// Each worker takes the index of the next run, and puts its result at the same index.
+	results := make([]SimResult, len(runs))
+	errs := make([]error, len(runs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < options.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job], errs[job] = runs[job].Play(options.ticks, options.script)
+			}
+		}()
+	}
+	for job := range runs {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return WriteSimResults(output, options.format, results)
+}
+
+// Plays the game for the given number of ticks, or until the player burns, while keeping statistics.
+// The player follows the script, or stands still if there is none.
+func (run SimRun) Play(ticks int, script []int) (SimResult, error) {
+	settings := DefaultSettings()
+	settings.Difficulty = DifficultyCustom
+	settings.Custom = run.params.rules
+	settings.tiles = run.tiles
+	game, err := NewGame(nil, nil, &settings, run.mapFile, run.seed)
+	if err != nil {
+		return SimResult{}, err
+	}
+
+	result := SimResult{Map: run.mapFile.fileName, Params: run.params.name, Seed: run.seed, Rules: run.params.rules, BurnoutTick: -1, SquirrelsStart: len(game.squirrels)}
+	firesAtStart := game.CountFires()
+	burning := false
+	for game.ticks < ticks && !game.exit {
+		if len(script) > 0 {
+			action := script[game.ticks%len(script)]
+			if action == ActionAutopilot {
+				action = game.AutopilotAction()
+			}
+			game.HandleAction(action)
+		}
+		game.UpdateWorld()
+
+		fires := game.CountFires()
+		if fires > result.PeakFires {
+			result.PeakFires = fires
+		}
+		if fires > 0 {
+			burning = true
+		} else if burning && result.BurnoutTick < 0 {
+			result.BurnoutTick = game.ticks
+		}
+	}
+
+	result.Ticks = game.ticks
+	result.PlayerBurned = game.endCause == EndCauseBurned
+	result.Score = game.player.score
+	result.ForestCover = append(game.stats.forestCover, game.ForestCover())
+	result.FiresStarted = firesAtStart + game.stats.firesStarted
+	result.FiresBurntOut = game.stats.firesBurntOut
+	result.SquirrelsSurvived = len(game.squirrels)
+
+	return result, nil
+}
+
+// Writes the results as a JSON array, or as CSV with a row per game and the forest cover samples separated by spaces.
+func WriteSimResults(output io.Writer, format string, results []SimResult) error {
+	if format == SimFormatJSON {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	keys := RuleKeys()
+	writer := csv.NewWriter(output)
+	header := []string{"map", "params", "seed"}
+	header = append(header, keys...)
+	header = append(header, "ticks", "playerBurned", "score", "firesStarted", "firesBurntOut", "peakFires", "burnoutTick", "squirrelsStart", "squirrelsSurvived", "finalForestCover", "forestCover")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		rules := RuleValues(result.Rules)
+		cover := make([]string, len(result.ForestCover))
+		for i, sample := range result.ForestCover {
+			cover[i] = strconv.FormatFloat(sample, 'f', 4, 64)
+		}
+
+		record := []string{result.Map, result.Params, strconv.FormatInt(result.Seed, 10)}
+		for _, key := range keys {
+			record = append(record, rules[key])
+		}
+		record = append(record,
+			strconv.Itoa(result.Ticks),
+			strconv.FormatBool(result.PlayerBurned),
+			strconv.Itoa(result.Score),
+			strconv.Itoa(result.FiresStarted),
+			strconv.Itoa(result.FiresBurntOut),
+			strconv.Itoa(result.PeakFires),
+			strconv.Itoa(result.BurnoutTick),
+			strconv.Itoa(result.SquirrelsStart),
+			strconv.Itoa(result.SquirrelsSurvived),
+			cover[len(cover)-1],
+			strings.Join(cover, " "),
+		)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
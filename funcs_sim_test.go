@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestSimParamSets(t *testing.T) {
+	paramSets, err := SimParamSets([]string{DifficultyEasy, DifficultyHard}, rulesetPresets[DifficultyNormal], []string{"fireSpreadChance=0.05,0.2", "damageFire=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paramSets) != 4 {
+		t.Fatalf("expected every combination of difficulty and values, got %d sets", len(paramSets))
+	}
+	last := paramSets[3]
+	if last.name != "hard fireSpreadChance=0.2 damageFire=2" || last.rules.FireSpreadChance != 0.2 || last.rules.DamageFire != 2 {
+		t.Errorf("unexpected last set %q: %+v", last.name, last.rules)
+	}
+	if last.rules.FireBurnoutHalflife != rulesetPresets[DifficultyHard].FireBurnoutHalflife {
+		t.Errorf("rules that are not varied should come from the difficulty, got %+v", last.rules)
+	}
+
+	for _, variation := range []string{"fireSpread=0.1", "damageFire=0.5", "fireSpreadChance=2", "fireSpreadChance=", "growthChanceSeed"} {
+		if _, err := SimParamSets([]string{DifficultyNormal}, rulesetPresets[DifficultyNormal], []string{variation}); err == nil {
+			t.Errorf("expected an error for %q", variation)
+		}
+	}
+}
+
+func TestParseSimScript(t *testing.T) {
+	script, err := ParseSimScript("MoveRight Wait\n ChopOmni Autopilot\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script) != 4 || script[0] != ActionMoveRight || script[1] != ActionNone || script[2] != ActionChopOmni || script[3] != ActionAutopilot {
+		t.Errorf("unexpected script %v", script)
+	}
+	for _, text := range []string{"", "Jump"} {
+		if _, err := ParseSimScript(text); err == nil {
+			t.Errorf("expected an error for %q", text)
+		}
+	}
+}
+
+func TestRunSimIsRepeatable(t *testing.T) {
+	settings := DefaultSettings()
+	paramSets, err := SimParamSets([]string{DifficultyNormal}, settings.Custom, []string{"fireSpreadChance=0.05,0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := SimOptions{maps: []string{"liten_skog.karta", "skog.karta"}, seeds: 3, firstSeed: 1, ticks: 300, script: []int{ActionMoveRight, ActionChopOmni, ActionMoveLeft, ActionDigOmni}, paramSets: paramSets, format: SimFormatCSV, sources: settings.MapSources(), tiles: settings.tiles}
+
+	// The same seeds give the same games, however many run at once.
+	var outputs [2]bytes.Buffer
+	for i, workers := range []int{1, 4} {
+		options.workers = workers
+		if err := RunSim(options, &outputs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if outputs[0].String() != outputs[1].String() {
+		t.Errorf("results differ between runs:\n%s\n%s", outputs[0].String(), outputs[1].String())
+	}
+
+	records, err := csv.NewReader(&outputs[0]).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1+2*2*3 {
+		t.Fatalf("expected a header and a row per game, got %d records", len(records))
+	}
+	if records[1][0] != "liten_skog.karta" || records[1][1] != "normal fireSpreadChance=0.05" || records[1][2] != "1" {
+		t.Errorf("rows should be in order of map, rules and seed, got %v", records[1][:3])
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Reads the options of the spectate subcommand: the address of the server, and where to record to, if anywhere.
+func ParseSpectateFlags(args []string) (JoinOptions, error) {
+	options := JoinOptions{spectate: true}
+	flags := flag.NewFlagSet("skogshuggare "+SpectateCommand, flag.ContinueOnError)
+	flags.StringVar(&options.record, "record", "", "record the game to the file as an asciinema cast, e.g. out.cast")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare %s [flags] host:port | %spath\n\nWatches a game run with skogshuggare %s, without playing.\n"+
+			"Arrow keys move the camera, Tab and Shift-Tab follow the next or previous player or squirrel, m shows or hides the minimap.\n\nFlags:\n", SpectateCommand, NetUnixPrefix, ServeCommand)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+	if flags.NArg() != 1 {
+		return options, fmt.Errorf("expected the address of a server, e.g. localhost%s", ServeDefaultAddress)
+	}
+	options.address = flags.Arg(0)
+
+	return options, nil
+}
+
+// Returns the actors of the state a spectator can follow, players before squirrels.
+func SpectatorTargets(state NetState) []SpectatorTarget {
+	var targets []SpectatorTarget
+	for _, player := range state.Players {
+		targets = append(targets, SpectatorTarget{false, player.Id, player.Name, Coordinate{player.X, player.Y}})
+	}
+	for _, squirrel := range state.Squirrels {
+		targets = append(targets, SpectatorTarget{true, squirrel.Id, "Squirrel " + strconv.Itoa(squirrel.Id+1), Coordinate{squirrel.X, squirrel.Y}})
+	}
+
+	return targets
+}
+
+// Returns the index of the target among the spectator's targets, or -1 if it isn't in the game anymore.
+func (spectator *Spectator) IndexOf(target SpectatorTarget) int {
+	for i, other := range spectator.targets {
+		if other.squirrel == target.squirrel && other.id == target.id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Takes in the actors of a new state, and moves the camera along with the actor it follows. Until the spectator
+// chooses an actor, the first one is followed, or the camera stays in the middle of the world while there is none.
+// The camera stays where it is once the actor it follows is gone.
+func (game *Game) UpdateSpectator(targets []SpectatorTarget) {
+	spectator := game.spectator
+	spectator.targets = targets
+	if !spectator.following {
+		return
+	}
+
+	i := spectator.IndexOf(spectator.target)
+	switch {
+	case !spectator.chosen && len(targets) > 0:
+		spectator.target = targets[0]
+		spectator.center = targets[0].position
+	case !spectator.chosen:
+		spectator.center = Coordinate{game.world.width / 2, game.world.height / 2}
+	case i >= 0:
+		spectator.target = targets[i]
+		spectator.center = targets[i].position
+	default: // The actor followed is gone
+		spectator.following = false
+		game.AppendToMenuMessages("Lost sight of " + spectator.target.name)
+	}
+}
+
+// Follows the actor after the one followed last, or before it for a negative step, wrapping around.
+func (game *Game) FollowNext(step int) {
+	spectator := game.spectator
+	if len(spectator.targets) == 0 {
+		return
+	}
+
+	i := spectator.IndexOf(spectator.target)
+	if i < 0 && step < 0 {
+		i = 0
+	}
+	i = ((i+step)%len(spectator.targets) + len(spectator.targets)) % len(spectator.targets)
+	spectator.target = spectator.targets[i]
+	spectator.center = spectator.target.position
+	spectator.following, spectator.chosen = true, true
+	game.AppendToMenuMessages("Following " + spectator.target.name)
+}
+
+// Moves the free camera a few tiles in the direction, keeping its center within the world. Moving stops following.
+func (game *Game) PanSpectator(dir int) {
+	spectator := game.spectator
+	for i := 0; i < SpectatorPanStep; i++ {
+		spectator.center = Step(spectator.center, dir)
+	}
+	spectator.center.x = Clamp(spectator.center.x, 0, game.world.width-1)
+	spectator.center.y = Clamp(spectator.center.y, 0, game.world.height-1)
+	spectator.following = false
+}
+
+// Handles a spectator's key: arrows or the movement keys move the camera, Tab and Shift-Tab follow the next or
+// previous actor, and m shows or hides the minimap. Every other key is ignored, since spectators can't act.
+func (game *Game) HandleSpectatorKey(ev *tcell.EventKey) {
+	switch {
+	case ev.Key() == tcell.KeyTab:
+		game.FollowNext(1)
+		return
+	case ev.Key() == tcell.KeyBacktab:
+		game.FollowNext(-1)
+		return
+	case ev.Key() == tcell.KeyRune && ev.Rune() == 'm':
+		game.spectator.minimap = !game.spectator.minimap
+		return
+	}
+
+	_, action := game.PlayerAction(game.controls.Action(ev))
+	for dir, move := range botActions[BotActionMove] {
+		if move == action {
+			game.PanSpectator(dir)
+		}
+	}
+}
+
+// Returns the name of the actor the spectator follows, for the HUD.
+func (spectator *Spectator) Watching() string {
+	if !spectator.following {
+		return "Free camera"
+	}
+
+	return spectator.target.name
+}
+
+// Draws the whole world shrunk into the top right corner of the viewport, with the block in the middle of the view
+// highlighted. Worlds too large to fit in the viewport's corner aren't drawn.
+func (game *Game) DrawMinimap() {
+	preview := MapPreview{world: game.world, players: make(map[Coordinate]bool), squirrels: make(map[Coordinate]bool)}
+	for _, player := range game.StandingPlayers() {
+		preview.players[player.position] = true
+	}
+	for _, squirrel := range game.squirrels {
+		preview.squirrels[squirrel.position] = true
+	}
+
+	scale := preview.Scale(SpectatorMinimapWidth, SpectatorMinimapHeight)
+	width, height := (game.world.width+scale-1)/scale, (game.world.height+scale-1)/scale
+	if width+2 > game.viewport.width || height+2 > game.viewport.height {
+		return
+	}
+
+	x, y := game.viewport.position.x+game.viewport.width-width-1, game.viewport.position.y+1
+	center := game.spectator.center
+	for row := 0; row < height; row++ {
+		for column := 0; column < width; column++ {
+			char, style := ' ', tcell.StyleDefault
+			if key, found := preview.BlockKey(Coordinate{column * scale, row * scale}, scale); found {
+				char, style = game.settings.tiles[key].char, game.settings.tiles[key].style
+			}
+			if center.x/scale == column && center.y/scale == row {
+				style = style.Reverse(true)
+			}
+			game.renderer.SetContent(x+column, y+row, char, style)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpectatorFollowsActors(t *testing.T) {
+	game := Game{world: NewWorld(20, 10, map[Coordinate]any{}), menu: Menu{messages: []string{}}, spectator: &Spectator{following: true}}
+	state := NetState{Players: []NetPlayer{{Id: 1, Name: "Anna", X: 2, Y: 3}}, Squirrels: []NetSquirrel{{Id: 4, X: 5, Y: 6}}}
+
+	game.UpdateSpectator(nil)
+	if game.spectator.center != (Coordinate{10, 5}) || !game.spectator.following {
+		t.Errorf("expected the camera in the middle while there is nobody to follow, got %+v", game.spectator)
+	}
+	game.UpdateSpectator(SpectatorTargets(state))
+	if game.spectator.center != (Coordinate{2, 3}) || game.spectator.Watching() != "Anna" {
+		t.Errorf("expected the first player to be followed, got %+v", game.spectator)
+	}
+
+	// Tab goes on to the squirrel and wraps around, Shift-Tab goes back.
+	for _, step := range []struct {
+		step int
+		want string
+	}{{1, "Squirrel 5"}, {1, "Anna"}, {-1, "Squirrel 5"}} {
+		game.FollowNext(step.step)
+		if game.spectator.Watching() != step.want {
+			t.Errorf("expected to follow %s, got %s", step.want, game.spectator.Watching())
+		}
+	}
+
+	state.Squirrels[0].X = 6
+	game.UpdateSpectator(SpectatorTargets(state))
+	if game.spectator.center != (Coordinate{6, 6}) {
+		t.Errorf("expected the camera to move with the squirrel, got %v", game.spectator.center)
+	}
+	state.Squirrels = nil
+	game.UpdateSpectator(SpectatorTargets(state))
+	if game.spectator.following || game.spectator.center != (Coordinate{6, 6}) || game.menu.messages[1] != "Lost sight of Squirrel 5" {
+		t.Errorf("expected the camera to stay once the squirrel is gone, got %+v and %q", game.spectator, game.menu.messages)
+	}
+
+	// Moving the camera stops following, and keeps it in the world.
+	game.FollowNext(1)
+	game.PanSpectator(DirLeft)
+	if game.spectator.following || game.spectator.center != (Coordinate{0, 3}) {
+		t.Errorf("expected a free camera at the left edge, got %+v", game.spectator)
+	}
+}
+
+func TestClientSpectates(t *testing.T) {
+	address, tick := startTestServer(t, ""+
+		"################\n"+
+		"#p          s  #\n"+
+		"#              #\n"+
+		"#  s       #####\n"+
+		"#          #  f#\n"+
+		"################")
+
+	settings := DefaultSettings()
+	spectator, err := JoinServer(nil, nil, &settings, JoinOptions{address: address, spectate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spectator.conn.Close()
+	player, err := JoinServer(nil, nil, &settings, JoinOptions{address: address, name: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer player.conn.Close()
+
+	tick <- time.Now()
+	var message NetMessage
+	if err := spectator.decoder.Decode(&message); err != nil {
+		t.Fatal(err)
+	}
+	if err := spectator.Apply(message); err != nil {
+		t.Fatal(err)
+	}
+
+	game := &spectator.game
+	if game.player.position != (Coordinate{1, 1}) || len(game.others) != 0 || game.names[0] != "Anna" {
+		t.Errorf("expected Anna as the spectator's player one, got %v named %q", game.player.position, game.names)
+	}
+	if game.spectator.Watching() != "Anna" || len(game.squirrels) != 2 {
+		t.Errorf("expected to follow Anna among two squirrels, got %q and %d squirrels", game.spectator.Watching(), len(game.squirrels))
+	}
+	if camera := game.Cameras()[0]; camera.center != game.player.position || !camera.CanSee(Coordinate{14, 4}) {
+		t.Errorf("expected a camera on Anna that sees the whole world, got %+v", camera)
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+// Returns the path of the SSH host key in the user config directory.
+func SshHostKeyFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, SshHostKeyFileName), nil
+}
+
+// Reads the options of the ssh subcommand. Sessions start out with the settings, which flags can override like when
+// playing locally. A shared world is served like with the serve subcommand.
+func ParseSshFlags(args []string, settings Settings) (SshOptions, error) {
+	options := SshOptions{address: SshDefaultAddress, serve: ServeOptions{tickRate: ServeDefaultTickRate}}
+	options.hostKey, _ = SshHostKeyFilePath()
+	flags := flag.NewFlagSet("skogshuggare "+SshCommand, flag.ContinueOnError)
+	flags.StringVar(&options.address, "addr", options.address, "address to listen for SSH clients on")
+	flags.StringVar(&options.hostKey, "host-key", options.hostKey, "file of the server's private key, generated if missing")
+	flags.StringVar(&options.authorizedKeys, "authorized-keys", "", "file of the public keys allowed to play, or empty to let anyone play")
+	flags.BoolVar(&options.shared, "shared", false, "let every session play in one world on the map given with --map, instead of a game of its own")
+	flags.StringVar(&settings.Map, "map", settings.Map, "map file to start each session on, skipping the title menu, or the map of the shared world")
+	flags.StringVar(&settings.MapsDir, "maps-dir", settings.MapsDir, "directory to search for maps before the user and built-in maps")
+	flags.Int64Var(&settings.Seed, "seed", settings.Seed, "seed for new games, or 0 for a random seed each game")
+	flags.StringVar(&settings.Difficulty, "difficulty", settings.Difficulty, "one of "+strings.Join(difficultyOrder, ", "))
+	flags.StringVar(&settings.Mode, "mode", settings.Mode, "game mode of the shared world, one of "+strings.Join(multiplayerModeOrder, ", "))
+	flags.IntVar(&options.serve.tickRate, "tick-rate", options.serve.tickRate, "milliseconds between ticks of the shared world")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: skogshuggare %s [flags]\n\nServes the game to SSH clients, each session in a terminal of its own.\n\nFlags:\n", SshCommand)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return options, err
+	}
+	if flags.NArg() > 0 {
+		return options, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if options.hostKey == "" {
+		return options, fmt.Errorf("no config directory for the host key, use --host-key to give one")
+	}
+	if options.shared && settings.Map == "" {
+		return options, fmt.Errorf("a shared world needs a map, use --map to pick one")
+	}
+	if options.serve.tickRate <= 0 {
+		return options, fmt.Errorf("tick rate must be positive, got %d", options.serve.tickRate)
+	}
+	if err := settings.Validate(); err != nil {
+		return options, err
+	}
+	options.serve.settings = settings
+
+	return options, nil
+}
+
+// Listens for SSH clients on the address of the options, until the process is stopped.
+func ServeSsh(options SshOptions) error {
+	config, err := NewSshConfig(options.hostKey, options.authorizedKeys)
+	if err != nil {
+		return err
+	}
+	server := &SshServer{config: config, settings: options.serve.settings}
+
+	// A shared world is a game server on the loopback interface, which each session joins as a client.
+	if options.shared {
+		if server.shared, err = ServeLoopback(options.serve); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("tcp", options.address)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving SSH on %s\n", listener.Addr())
+
+	return server.Run(listener)
+}
+
+// Returns the configuration of the SSH server, with the host key from the file, which is generated if missing.
+// Only clients with a key in the authorized keys file are let in, unless no file is given.
+func NewSshConfig(hostKeyFileName string, authorizedKeysFileName string) (*ssh.ServerConfig, error) {
+	hostKey, err := LoadHostKey(hostKeyFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: authorizedKeysFileName == ""}
+	config.AddHostKey(hostKey)
+	if authorizedKeysFileName != "" {
+		authorizedKeys, err := LoadAuthorizedKeys(authorizedKeysFileName)
+		if err != nil {
+			return nil, err
+		}
+		config.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			for _, authorized := range authorizedKeys {
+				if bytes.Equal(key.Marshal(), authorized.Marshal()) {
+					return nil, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown public key for %s", conn.User())
+		}
+	}
+
+	return config, nil
+}
+
+// Reads the host key from the file, or generates one and writes it there if the file doesn't exist yet.
+func LoadHostKey(fileName string) (ssh.Signer, error) {
+	buffer, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		if buffer, err = GenerateHostKey(fileName); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	return signer, nil
+}
+
+func GenerateHostKey(fileName string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(key, "skogshuggare")
+	if err != nil {
+		return nil, err
+	}
+	buffer := pem.EncodeToMemory(block)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return nil, err
+	}
+
+	return buffer, os.WriteFile(fileName, buffer, 0600)
+}
+
+// Reads public keys in the format of OpenSSH's authorized_keys.
+func LoadAuthorizedKeys(fileName string) ([]ssh.PublicKey, error) {
+	buffer, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []ssh.PublicKey
+	for len(bytes.TrimSpace(buffer)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(buffer)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+		keys = append(keys, key)
+		buffer = rest
+	}
+
+	return keys, nil
+}
+
+// Accepts SSH clients on the listener until it is closed.
+func (server *SshServer) Run(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		go server.HandleConn(conn)
+	}
+}
+
+// Runs a session for each session channel the client opens. Other channels, like port forwarding, are rejected.
+func (server *SshServer) HandleConn(conn net.Conn) {
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, server.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go server.HandleSession(channel, channelRequests, sshConn.User())
+	}
+}
+
+// Handles the requests of a session: a terminal, then a shell, which starts the game, and then changes of the window size.
+// Commands, another shell and sessions without a terminal are turned away.
+func (server *SshServer) HandleSession(channel ssh.Channel, requests <-chan *ssh.Request, user string) {
+	var tty *SessionTty
+	var term string
+	started := false
+	for request := range requests {
+		switch request.Type {
+		case "pty-req":
+			var pty PtyRequest
+			if err := ssh.Unmarshal(request.Payload, &pty); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			tty, term = NewSessionTty(channel, int(pty.Columns), int(pty.Rows)), pty.Term
+			request.Reply(true, nil)
+		case "window-change":
+			var size WindowChange
+			if err := ssh.Unmarshal(request.Payload, &size); err == nil && tty != nil {
+				tty.Resize(int(size.Columns), int(size.Rows))
+			}
+			request.Reply(true, nil)
+		case "shell":
+			if started {
+				request.Reply(false, nil)
+				continue
+			}
+			started = true
+			request.Reply(true, nil)
+			go func() {
+				status := uint32(0)
+				if err := server.Play(tty, term, user); err != nil {
+					fmt.Fprintf(channel.Stderr(), "%v\r\n", err)
+					status = 1
+				}
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				channel.Close()
+			}()
+		default:
+			request.Reply(false, nil)
+		}
+	}
+}
+
+// Plays in the session's terminal, like a game started locally, but without saving games or controls.
+// In a shared world, the session joins it under the user's name.
+func (server *SshServer) Play(tty *SessionTty, term string, user string) error {
+	if tty == nil {
+		return fmt.Errorf("no terminal, connect with ssh -t")
+	}
+	if term == "" {
+		term = SshTerminal
+	}
+	terminfo, err := tcell.LookupTerminfo(term)
+	if err != nil {
+		return fmt.Errorf("terminal %q: %v", term, err)
+	}
+	screen, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, terminfo)
+	if err == nil {
+		err = InitScreen(screen)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Once the session is gone, every screen the game shows ends, like when the terminal of a local game is closed.
+	// The error is posted again and again, since the event queue may be full, and the game may show several screens.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-tty.ended:
+		case <-done:
+			return
+		}
+		for {
+			screen.PostEvent(tcell.NewEventError(io.ErrClosedPipe))
+			select {
+			case <-time.After(SshEndedRepeat):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	settings := server.settings
+	controls, _ := NewControls(PresetDefault)
+	if server.shared != "" {
+		client, err := JoinServer(screen, &controls, &settings, JoinOptions{address: server.shared, name: user})
+		if err == nil {
+			err = client.Run()
+		}
+		screen.Fini()
+		return err
+	}
+
+	game, err := Play(screen, &controls, &settings)
+	screen.Fini()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(tty, "Game over. Final score: %d\r\n", game.Score())
+
+	return nil
+}
+
+// Returns a terminal for a session of the given size, which reads the session's input as soon as it arrives.
+func NewSessionTty(channel ssh.Channel, width int, height int) *SessionTty {
+	tty := &SessionTty{channel: channel, input: make(chan []byte), closed: make(chan struct{}), ended: make(chan struct{}), width: width, height: height}
+	go func() {
+		for {
+			buffer := make([]byte, 256)
+			n, err := channel.Read(buffer)
+			if n > 0 {
+				select {
+				case tty.input <- buffer[:n]:
+				case <-tty.closed:
+					return
+				}
+			}
+			if err != nil {
+				close(tty.input)
+				tty.End()
+				return
+			}
+		}
+	}()
+
+	return tty
+}
+
+func (tty *SessionTty) Start() error {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+	tty.stop = make(chan struct{})
+
+	return nil
+}
+
+func (tty *SessionTty) Stop() error {
+	return nil
+}
+
+// Wakes up a blocked Read, since screens wait for their reader to stop.
+func (tty *SessionTty) Drain() error {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+	close(tty.stop)
+
+	return nil
+}
+
+// Stops reading from the session. The session itself is closed once the game has ended.
+func (tty *SessionTty) Close() error {
+	close(tty.closed)
+	return nil
+}
+
+func (tty *SessionTty) Read(buffer []byte) (int, error) {
+	tty.mutex.Lock()
+	stop := tty.stop
+	tty.mutex.Unlock()
+
+	if len(tty.pending) == 0 {
+		select {
+		case data, open := <-tty.input:
+			if !open {
+				return 0, io.EOF
+			}
+			tty.pending = data
+		case <-stop:
+			return 0, io.EOF
+		case <-tty.ended:
+			return 0, io.EOF
+		}
+	}
+	n := copy(buffer, tty.pending)
+	tty.pending = tty.pending[n:]
+
+	return n, nil
+}
+
+func (tty *SessionTty) Write(buffer []byte) (int, error) {
+	n, err := tty.channel.Write(buffer)
+	if err != nil {
+		tty.End()
+	}
+
+	return n, err
+}
+
+// Marks the session as gone, because its channel was closed or can't be written to.
+func (tty *SessionTty) End() {
+	tty.endOnce.Do(func() { close(tty.ended) })
+}
+
+func (tty *SessionTty) NotifyResize(onResize func()) {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+	tty.onResize = onResize
+}
+
+func (tty *SessionTty) WindowSize() (int, int, error) {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+
+	return tty.width, tty.height, nil
+}
+
+// Changes the size of the terminal, as the client's window changed size.
+func (tty *SessionTty) Resize(width int, height int) {
+	tty.mutex.Lock()
+	tty.width, tty.height = width, height
+	onResize := tty.onResize
+	tty.mutex.Unlock()
+
+	if onResize != nil {
+		onResize()
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Starts an SSH server on a free localhost port, with a host key generated in a temporary directory.
+func startTestSshServer(t *testing.T, settings Settings, authorizedKeys string) string {
+	config, err := NewSshConfig(filepath.Join(t.TempDir(), SshHostKeyFileName), authorizedKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &SshServer{config: config, settings: settings}
+	go server.Run(listener)
+
+	return listener.Addr().String()
+}
+
+func dialTestSshServer(address string, auth ...ssh.AuthMethod) (*ssh.Client, error) {
+	return ssh.Dial("tcp", address, &ssh.ClientConfig{User: "anna", Auth: auth, HostKeyCallback: ssh.InsecureIgnoreHostKey(), Timeout: 5 * time.Second})
+}
+
+// Reads from the reader until the text shows up, or fails the test after a while.
+func waitForOutput(t *testing.T, reader io.Reader, text string) string {
+	output := make(chan string)
+	go func() {
+		var buffer bytes.Buffer
+		chunk := make([]byte, 1024)
+		for {
+			n, err := reader.Read(chunk)
+			buffer.Write(chunk[:n])
+			if strings.Contains(buffer.String(), text) || err != nil {
+				output <- buffer.String()
+				return
+			}
+		}
+	}()
+
+	select {
+	case received := <-output:
+		if !strings.Contains(received, text) {
+			t.Fatalf("expected %q in the output, got %q", text, received)
+		}
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q", text)
+	}
+
+	return ""
+}
+
+func TestSshSessionPlaysGame(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Map = "liten_skog.karta"
+	client, err := dialTestSshServer(startTestSshServer(t, settings, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	if err := session.RequestPty("xterm-256color", 30, 100, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForOutput(t, stdout, "Score")
+	if err := session.WindowChange(24, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing the input ends the game, like a dropped connection.
+	stdin.Close()
+	waitForOutput(t, stdout, "Game over. Final score: 0")
+	if err := session.Wait(); err != nil {
+		t.Errorf("expected the session to end cleanly, got %v", err)
+	}
+}
+
+func TestSshSessionNeedsTerminal(t *testing.T) {
+	client, err := dialTestSshServer(startTestSshServer(t, DefaultSettings(), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	var exitErr *ssh.ExitError
+	if err := session.Wait(); !errors.As(err, &exitErr) || exitErr.ExitStatus() != 1 {
+		t.Errorf("expected exit status 1, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "ssh -t") {
+		t.Errorf("expected a hint to ask for a terminal, got %q", stderr.String())
+	}
+}
+
+func TestSshAuthorizedKeys(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	authorized, stranger := newSigner(), newSigner()
+	authorizedKeys := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(authorizedKeys, ssh.MarshalAuthorizedKey(authorized.PublicKey()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	address := startTestSshServer(t, DefaultSettings(), authorizedKeys)
+
+	if client, err := dialTestSshServer(address, ssh.PublicKeys(stranger)); err == nil {
+		client.Close()
+		t.Errorf("expected a key that isn't authorized to be turned away")
+	}
+	client, err := dialTestSshServer(address, ssh.PublicKeys(authorized))
+	if err != nil {
+		t.Fatalf("expected an authorized key to be let in, got %v", err)
+	}
+	client.Close()
+}
+
+func TestLoadHostKeyGeneratesOnce(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "keys", SshHostKeyFileName)
+	generated, err := LoadHostKey(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHostKey(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(generated.PublicKey().Marshal(), loaded.PublicKey().Marshal()) {
+		t.Errorf("expected the generated key to be read back")
+	}
+}
+
+func TestSshSessionTakesOneShell(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Map = "liten_skog.karta"
+	client, err := dialTestSshServer(startTestSshServer(t, settings, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.StdinPipe() // Left open, as closing the input ends the game
+	stdout, _ := session.StdoutPipe()
+	if err := session.RequestPty("xterm-256color", 30, 100, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, stdout, "Score")
+
+	if ok, err := session.SendRequest("shell", true, nil); ok || err != nil {
+		t.Errorf("expected a second shell to be turned away, got %v and %v", ok, err)
+	}
+}
+
+// A session channel that can be read from until closed, and can't be written to.
+type brokenChannel struct {
+	ssh.Channel
+	closed chan struct{}
+}
+
+func (channel brokenChannel) Read(buffer []byte) (int, error) {
+	<-channel.closed
+	return 0, io.EOF
+}
+
+func (channel brokenChannel) Write(buffer []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestSessionTtyEndsOnFailedWrite(t *testing.T) {
+	channel := brokenChannel{closed: make(chan struct{})}
+	defer close(channel.closed)
+	tty := NewSessionTty(channel, 80, 24)
+
+	if _, err := tty.Write([]byte("x")); err == nil {
+		t.Fatalf("expected the write to fail")
+	}
+	select {
+	case <-tty.ended:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the session to end once it can't be written to")
+	}
+	tty.Start()
+	if _, err := tty.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected reading to stop, so the screen gets an error event, got %v", err)
+	}
+}
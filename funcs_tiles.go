@@ -0,0 +1,211 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+//go:embed tiles.json
+var defaultTilesData []byte
+
+// Returns the tile registry of the built-in tiles, indexed by tile key.
+func DefaultTiles() map[int]Tile {
+	registry, err := ParseTiles(defaultTilesData, nil)
+	if err != nil {
+		panic("built-in tiles.json: " + err.Error())
+	}
+
+	return registry
+}
+
+// Returns the path of the user's tile definitions in the user config directory.
+func TilesFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, ConfigDirName, TilesFileName), nil
+}
+
+// Returns the registry with the tiles defined in the given file added, replacing tiles with the same id.
+// A missing file gives the registry as it is.
+func LoadTiles(fileName string, base map[int]Tile) (map[int]Tile, error) {
+	buffer, err := os.ReadFile(fileName)
+	if fileName == "" || errors.Is(err, os.ErrNotExist) {
+		return base, nil
+	} else if err != nil {
+		return base, err
+	}
+
+	registry, err := ParseTiles(buffer, base)
+	if err != nil {
+		return base, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	return registry, nil
+}
+
+// Parses a JSON list of tile definitions on top of a copy of the given registry.
+// Tiles the game refers to directly keep their keys, and new tiles are given keys after the existing ones.
+func ParseTiles(data []byte, base map[int]Tile) (map[int]Tile, error) {
+	var definitions []TileDefinition
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, err
+	}
+
+	registry := make(map[int]Tile)
+	nextKey := 0
+	for key, tile := range base {
+		registry[key] = tile
+		if key >= nextKey {
+			nextKey = key + 1
+		}
+	}
+	for _, key := range tileKeys {
+		if key >= nextKey {
+			nextKey = key + 1
+		}
+	}
+
+	for _, definition := range definitions {
+		tile, err := definition.Tile()
+		if err != nil {
+			return nil, err
+		}
+
+		key, found := TileKeyById(registry, tile.id)
+		if !found {
+			if key, found = tileKeys[tile.id]; !found {
+				key = nextKey
+				nextKey++
+			}
+		}
+		registry[key] = tile
+	}
+
+	// Every tile the game refers to must be defined, and no two tiles may share a map character.
+	for id, key := range tileKeys {
+		if _, found := registry[key]; !found {
+			return nil, fmt.Errorf("missing tile %q", id)
+		}
+	}
+	mapChars := make(map[rune]string)
+	for _, tile := range registry {
+		if tile.mapChar == 0 {
+			continue
+		}
+		if other, taken := mapChars[tile.mapChar]; taken {
+			return nil, fmt.Errorf("tiles %q and %q both use map character %q", other, tile.id, tile.mapChar)
+		}
+		mapChars[tile.mapChar] = tile.id
+	}
+
+	return registry, nil
+}
+
+// Checks a tile definition and turns it into a tile.
+func (definition TileDefinition) Tile() (Tile, error) {
+	tile := Tile{
+		id:         definition.Id,
+		name:       definition.Name,
+		aboveActor: definition.AboveActor,
+		style:      tcell.StyleDefault,
+		collidable: definition.Collidable,
+		flammable:  definition.Flammable,
+		plantable:  definition.Plantable,
+		fuel:       definition.Fuel,
+		scatter:    definition.Scatter,
+	}
+
+	if tile.id == "" {
+		return tile, fmt.Errorf("tile %q has no id", definition.Name)
+	}
+	if tile.name == "" {
+		tile.name = tile.id
+	}
+	if utf8.RuneCountInString(definition.Glyph) != 1 {
+		return tile, fmt.Errorf("tile %q: glyph must be a single character, got %q", tile.id, definition.Glyph)
+	}
+	tile.char, _ = utf8.DecodeRuneInString(definition.Glyph)
+	if definition.MapChar != "" {
+		if utf8.RuneCountInString(definition.MapChar) != 1 {
+			return tile, fmt.Errorf("tile %q: map character must be a single character, got %q", tile.id, definition.MapChar)
+		}
+		tile.mapChar, _ = utf8.DecodeRuneInString(definition.MapChar)
+	}
+	if tile.fuel == 0 {
+		tile.fuel = 1
+	} else if tile.fuel < 0 {
+		return tile, fmt.Errorf("tile %q: fuel must be positive, got %v", tile.id, tile.fuel)
+	}
+	if tile.scatter < 0 {
+		return tile, fmt.Errorf("tile %q: scatter must not be negative, got %d", tile.id, tile.scatter)
+	}
+
+	if definition.Foreground != "" {
+		color := tcell.GetColor(definition.Foreground)
+		if color == tcell.ColorDefault {
+			return tile, fmt.Errorf("tile %q: unknown colour %q", tile.id, definition.Foreground)
+		}
+		tile.style = tile.style.Foreground(color)
+	}
+	if definition.Background != "" {
+		color := tcell.GetColor(definition.Background)
+		if color == tcell.ColorDefault {
+			return tile, fmt.Errorf("tile %q: unknown colour %q", tile.id, definition.Background)
+		}
+		tile.style = tile.style.Background(color)
+	}
+
+	return tile, nil
+}
+
+func TileKeyById(registry map[int]Tile, id string) (int, bool) {
+	for key, tile := range registry {
+		if tile.id == id {
+			return key, true
+		}
+	}
+
+	return 0, false
+}
+
+// Returns the keys of the tiles that can be placed in map files, by map character.
+func TileKeysByMapChar(registry map[int]Tile) map[rune]int {
+	keys := make(map[rune]int)
+	for key, tile := range registry {
+		if tile.mapChar != 0 {
+			keys[tile.mapChar] = key
+		}
+	}
+
+	return keys
+}
+
+// Returns an object for the given tile of the registry, with the properties of the tile.
+func NewObject(registry map[int]Tile, key int) Object {
+	tile := registry[key]
+	return Object{key, tile.collidable, tile.flammable, tile.plantable}
+}
+
+// Returns the fuel of whatever is at the given coordinate, i.e. what a fire starting there would burn.
+func (game *Game) FuelAt(coord Coordinate) float64 {
+	switch content := game.world.content[coord].(type) {
+	case Object:
+		return game.settings.tiles[content.key].fuel
+	case *Tree:
+		return game.settings.tiles[content.Key()].fuel
+	case *Fire:
+		return content.fuel
+	}
+
+	return 1
+}
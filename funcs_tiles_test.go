@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTilesAddsAndReplacesTiles(t *testing.T) {
+	data := `[
+		{"id": "rock", "name": "Rock", "glyph": "o", "fg": "gray", "collidable": true, "mapChar": "r"},
+		{"id": "wall", "name": "Stone wall", "glyph": "#", "fg": "#808080", "collidable": true, "mapChar": "#"}
+	]`
+	registry, err := ParseTiles([]byte(data), DefaultTiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if registry[KeyWall].name != "Stone wall" {
+		t.Errorf("wall should have been replaced, got %q", registry[KeyWall].name)
+	}
+	key, found := TileKeyById(registry, "rock")
+	if !found || key <= KeyFirebreak {
+		t.Fatalf("rock should have been added after the built-in tiles, got key %d", key)
+	}
+	if rock := registry[key]; !rock.collidable || rock.mapChar != 'r' || rock.fuel != 1 {
+		t.Errorf("rock properties were not parsed, got %+v", rock)
+	}
+}
+
+func TestParseTilesRejectsInvalidTiles(t *testing.T) {
+	for _, data := range []string{
+		`[{"id": "rock", "glyph": "oo"}]`,
+		`[{"id": "rock", "glyph": "o", "fg": "blurple"}]`,
+		`[{"id": "rock", "glyph": "o", "mapChar": "#"}]`,
+		`[{"id": "rock", "glyph": "o", "fuel": -1}]`,
+		`[{"glyph": "o"}]`,
+	} {
+		if _, err := ParseTiles([]byte(data), DefaultTiles()); err == nil {
+			t.Errorf("expected an error parsing %s", data)
+		}
+	}
+
+	if _, err := ParseTiles([]byte(`[{"id": "wall", "glyph": "#"}]`), nil); err == nil {
+		t.Errorf("expected an error for missing built-in tiles")
+	}
+}
+
+func TestReadMapPlacesCustomTiles(t *testing.T) {
+	dir := t.TempDir()
+	tilesFileName := filepath.Join(dir, "tiles.json")
+	if err := os.WriteFile(tilesFileName, []byte(`[{"id": "mud", "name": "Mud", "glyph": "~", "fg": "brown", "plantable": true, "mapChar": "m"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := LoadTiles(tilesFileName, DefaultTiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world, player, squirrels, err := ParseMap([]byte("#####\n#pms#\n#####\n"), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player != (Coordinate{1, 1}) || len(squirrels) != 1 {
+		t.Errorf("player and squirrel were not read, got %v and %v", player, squirrels)
+	}
+	mud, isObject := world.content[Coordinate{2, 1}].(Object)
+	if !isObject || registry[mud.key].id != "mud" || !mud.plantable || mud.collidable {
+		t.Errorf("mud was not placed, got %v", world.content[Coordinate{2, 1}])
+	}
+}
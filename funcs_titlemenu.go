@@ -1,11 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/gdamore/tcell"
+	"github.com/gdamore/tcell/v2"
 )
 
 func (titleMenu *TitleMenu) Draw(screen tcell.Screen) {
@@ -23,7 +25,10 @@ func (titleMenu *TitleMenu) DrawPage(screen tcell.Screen, pageState int) {
 	pageItems := currentPage.titleMenuItems
 
 	currentY := strings.Count(pageContent[0], "\n")
-	widthScreen, _ := screen.Size()
+	if pageState == NewGamePageOrder {
+		titleMenu.DrawMapPreview(screen, currentY)
+	}
+	widthScreen, heightScreen := screen.Size()
 	currentAnimation := pageContent[currentPage.animationState]
 
 	for x := 0; x < len(currentAnimation); x++ {
@@ -31,9 +36,25 @@ func (titleMenu *TitleMenu) DrawPage(screen tcell.Screen, pageState int) {
 		screen.SetContent(x+centerX, 0, rune(currentAnimation[x]), nil, tcell.StyleDefault) // coords to center: (x + centerX, 0)
 	}
 
-	for i := 0; i < len(pageItems); i++ { // This ensures order
+	// Pages with more items than fit below the header scroll to keep the cursor in sight.
+	rows := heightScreen - currentY
+	if titleMenu.status != "" {
+		rows -= 2
+	}
+	if len(pageItems) <= rows || rows <= 0 {
+		titleMenu.scroll = 0
+	} else if currentPage.cursorState < titleMenu.scroll {
+		titleMenu.scroll = currentPage.cursorState
+	} else if currentPage.cursorState >= titleMenu.scroll+rows {
+		titleMenu.scroll = currentPage.cursorState - rows + 1
+	}
+	if titleMenu.scroll > 0 && titleMenu.scroll > len(pageItems)-rows {
+		titleMenu.scroll = len(pageItems) - rows
+	}
+
+	for i := titleMenu.scroll; i < len(pageItems); i++ { // This ensures order
 		pageItem := pageItems[i]
-		centerX := (widthScreen / 2) - (len(pageItem.text))
+		centerX := (widthScreen / 2) - utf8.RuneCountInString(pageItem.text)
 		if i == currentPage.cursorState {
 			screen.SetContent(centerX-1, currentY, '>', nil, tcell.StyleDefault)
 		}
@@ -42,53 +63,156 @@ func (titleMenu *TitleMenu) DrawPage(screen tcell.Screen, pageState int) {
 		}
 		currentY++
 	}
+
+	if titleMenu.status != "" {
+		currentY++
+		centerX := (widthScreen / 2) - (len(titleMenu.status) / 2)
+		for i, c := range titleMenu.status {
+			screen.SetContent(i+centerX, currentY, c, nil, tcell.StyleDefault)
+		}
+	}
 }
 
-func (titleMenu *TitleMenu) AnimationHandler() {
-	for {
-		if titleMenu.exit {
-			return
+// Shows the title menu until a game is picked or the player quits.
+// The menu state is only touched by this goroutine. Input is read by a separate goroutine and passed in over a
+// channel, and that goroutine has stopped by the time Run returns, so it can't take events meant for the game.
+func (titleMenu *TitleMenu) Run(screen tcell.Screen) {
+	events := make(chan tcell.Event)
+	done := make(chan struct{})
+	stopped := make(chan []tcell.Event, 1)
+	go PollEvents(screen, events, done, stopped)
+
+	animationTicker := time.NewTicker(TitleMenuAnimationPeriod)
+	defer animationTicker.Stop()
+
+	// Left idle on the main menu, the title menu exits to let the autopilot play a demo.
+	demoTimer := time.NewTimer(TitleMenuDemoDelay)
+	defer demoTimer.Stop()
+
+	lost := false
+	titleMenu.Draw(screen)
+	for !titleMenu.exit {
+		select {
+		case ev := <-events:
+			if _, isError := ev.(*tcell.EventError); isError || ev == nil { // The screen was closed, or its terminal is gone
+				titleMenu.quit = true
+				titleMenu.exit = true
+				lost = true
+				continue
+			}
+			titleMenu.HandleEvent(screen, ev)
+			if !demoTimer.Stop() {
+				<-demoTimer.C
+			}
+			demoTimer.Reset(TitleMenuDemoDelay)
+		case <-animationTicker.C:
+			titleMenu.Animate()
+		case <-demoTimer.C:
+			if titleMenu.pageState == MainMenuPageOrder {
+				titleMenu.demo = true
+				titleMenu.exit = true
+			} else {
+				demoTimer.Reset(TitleMenuDemoDelay)
+			}
 		}
-		time.Sleep(100 * time.Millisecond)
-		currentPage, found := titleMenu.titleMenuPages[titleMenu.pageState]
-		if !found {
-			currentPage = titleMenu.titleMenuPages[MainMenuPageOrder]
+		titleMenu.Draw(screen)
+	}
+	screen.Clear()
+
+	// Wake the input goroutine up from waiting for an event, and wait for it to stop. Whatever it read in the meantime
+	// is kept for the game. If the terminal is gone the goroutine has already stopped by itself.
+	close(done)
+	if !lost {
+		screen.PostEventWait(tcell.NewEventInterrupt(StopPolling{}))
+	}
+	titleMenu.unhandled = <-stopped
+}
+
+// Reads events from the screen and sends them on the events channel, until it reads the StopPolling interrupt.
+// Events read after done is closed are sent on stopped when polling stops, in the order they were read. A nil event
+// is sent if the screen is closed, and an error event if its terminal is gone, and polling stops after either.
+func PollEvents(screen tcell.Screen, events chan<- tcell.Event, done <-chan struct{}, stopped chan<- []tcell.Event) {
+	var unhandled []tcell.Event
+	defer func() { stopped <- unhandled }()
+	for {
+		ev := screen.PollEvent()
+		if interrupt, isInterrupt := ev.(*tcell.EventInterrupt); isInterrupt {
+			if _, stop := interrupt.Data().(StopPolling); stop {
+				return
+			}
 		}
 
-		if currentPage.animationState >= len(currentPage.content)-1 {
-			currentPage.animationState = 0
-		} else {
-			currentPage.animationState++ // Increment animation state after drawing it
+		select {
+		case events <- ev:
+		case <-done:
+			unhandled = append(unhandled, ev)
+		}
+		if _, isError := ev.(*tcell.EventError); isError || ev == nil {
+			return
 		}
 	}
 }
 
-func (titleMenu *TitleMenu) Update(screen tcell.Screen) {
+// Moves the header animation of the current page on by one frame.
+func (titleMenu *TitleMenu) Animate() {
+	currentPage, found := titleMenu.titleMenuPages[titleMenu.pageState]
+	if !found {
+		currentPage = titleMenu.titleMenuPages[MainMenuPageOrder]
+	}
 
+	if currentPage.animationState >= len(currentPage.content)-1 {
+		currentPage.animationState = 0
+	} else {
+		currentPage.animationState++
+	}
 }
 
-func (titleMenu *TitleMenu) InputHandler(screen tcell.Screen) {
-	for {
-		if titleMenu.exit {
+func (titleMenu *TitleMenu) HandleEvent(screen tcell.Screen, ev tcell.Event) {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if titleMenu.rebindAction != ActionNone {
+			titleMenu.HandleRebindEvent(ev)
 			return
 		}
-		ev := screen.PollEvent()
-		switch ev := ev.(type) {
-		case *tcell.EventKey:
-			switch ev.Key() {
-			case tcell.KeyEscape:
-				screen.Clear()
-				os.Exit(0)
-			case tcell.KeyUp:
-				titleMenu.MoveMenuCursor(DirUp)
-			case tcell.KeyDown:
-				titleMenu.MoveMenuCursor(DirDown)
-			case tcell.KeyEnter:
-				titleMenu.HandleEnterEvent()
-			}
-		case *tcell.EventResize:
-			screen.Sync()
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			titleMenu.quit = true
+			titleMenu.exit = true
+		case tcell.KeyUp:
+			titleMenu.MoveMenuCursor(DirUp)
+		case tcell.KeyDown:
+			titleMenu.MoveMenuCursor(DirDown)
+		case tcell.KeyEnter:
+			titleMenu.HandleEnterEvent()
+		case tcell.KeyLeft:
+			titleMenu.HandleAdjustEvent(-1)
+		case tcell.KeyRight:
+			titleMenu.HandleAdjustEvent(1)
+		}
+	case *tcell.EventMouse:
+		if titleMenu.rebindAction == ActionNone {
+			titleMenu.HandleMouseEvent(ev)
 		}
+	case *tcell.EventResize:
+		screen.Sync()
+	}
+}
+
+// Hovering over a menu item moves the cursor to it, and clicking it selects it.
+func (titleMenu *TitleMenu) HandleMouseEvent(ev *tcell.EventMouse) {
+	_, y := ev.Position()
+	pressed := ev.Buttons() &^ titleMenu.mouseButtons // Only buttons that went down with this event, not ones held since before
+	titleMenu.mouseButtons = ev.Buttons()
+
+	currentPage := titleMenu.titleMenuPages[titleMenu.pageState]
+	item := y - strings.Count(currentPage.content[0], "\n") + titleMenu.scroll // Items are drawn one per row below the header
+	if item < 0 || item >= len(currentPage.titleMenuItems) {
+		return
+	}
+
+	currentPage.cursorState = item
+	if pressed&tcell.Button1 != 0 {
+		titleMenu.HandleEnterEvent()
 	}
 }
 
@@ -116,25 +240,177 @@ func (titleMenu *TitleMenu) HandleEnterEvent() {
 	// Change to next place based on current cursor position and current view
 	pageCursorState := titleMenu.titleMenuPages[titleMenu.pageState].cursorState
 	pageItems := titleMenu.titleMenuPages[titleMenu.pageState].titleMenuItems
+	titleMenu.status = ""
 	switch pageItems[pageCursorState].text {
 	case "Exit":
-		os.Exit(0)
+		titleMenu.quit = true
+		titleMenu.exit = true
 	case "New game":
+		titleMenu.settings.Players = 1
+		titleMenu.pageState = NewGamePageOrder
+	case "Two players":
+		titleMenu.settings.Players = MaxLocalPlayers
+		titleMenu.pageState = PlayersPageOrder
+		titleMenu.RefreshPlayersPage()
+	case "Pick a map":
 		titleMenu.pageState = NewGamePageOrder
+	case "Load game":
+		if _, err := os.Stat(titleMenu.settings.SaveFile); titleMenu.settings.SaveFile == "" || err != nil {
+			titleMenu.status = "No saved game found"
+			return
+		}
+		titleMenu.loadGame = true
+		titleMenu.exit = true
+	case "High scores":
+		titleMenu.pageState = HighScoresPageOrder
+		titleMenu.RefreshHighScoresPage(0)
+	case "Controls":
+		titleMenu.pageState = ControlsPageOrder
+	case "Reset to defaults":
+		titleMenu.controls.SetPreset(PresetDefault)
+		titleMenu.SaveControls()
+	case "Start game":
+		titleMenu.settings.Difficulty = DifficultyCustom
+		titleMenu.exit = true
 	case "Go back":
-		titleMenu.pageState = MainMenuPageOrder
+		switch titleMenu.pageState {
+		case DifficultyPageOrder:
+			titleMenu.pageState = NewGamePageOrder
+		case CustomDifficultyPageOrder:
+			titleMenu.pageState = DifficultyPageOrder
+		case NewGamePageOrder:
+			titleMenu.pageState = MainMenuPageOrder
+			if titleMenu.settings.Players > 1 {
+				titleMenu.pageState = PlayersPageOrder
+			}
+		default:
+			titleMenu.pageState = MainMenuPageOrder
+		}
 	default:
-		if pageItems[pageCursorState].value != nil {
-			titleMenu.selectedMap = pageItems[pageCursorState].value.(string)
+		switch value := pageItems[pageCursorState].value.(type) {
+		case MapFile: // Pick a map, and then a difficulty
+			titleMenu.selectedMap = value
+			titleMenu.pageState = DifficultyPageOrder
+			for i, difficulty := range difficultyOrder {
+				if difficulty == titleMenu.settings.Difficulty {
+					titleMenu.titleMenuPages[DifficultyPageOrder].cursorState = i
+				}
+			}
+		case DifficultyChoice:
+			if value == DifficultyCustom {
+				titleMenu.pageState = CustomDifficultyPageOrder
+				titleMenu.RefreshCustomDifficultyPage()
+				return
+			}
+			titleMenu.settings.Difficulty = string(value)
 			titleMenu.exit = true
+		case RulesetFieldIndex:
+			titleMenu.AdjustCustomRule(int(value), 1)
+		case PlayersOption:
+			titleMenu.AdjustPlayersOption(value, 1)
+		case int: // Rebind an action
+			titleMenu.rebindAction = value
+			titleMenu.RefreshControlsPage()
+		case ControlsPreset:
+			titleMenu.controls.SetPreset(string(value))
+			titleMenu.SaveControls()
+		case HighScoreTable:
+			titleMenu.RefreshHighScoresPage(int(value))
+		}
+	}
+}
+
+// Binds the pressed key to the action being rebound. Escape cancels rebinding.
+func (titleMenu *TitleMenu) HandleRebindEvent(ev *tcell.EventKey) {
+	if ev.Key() != tcell.KeyEscape {
+		if !titleMenu.controls.Bind(titleMenu.rebindAction, KeyName(ev)) {
+			titleMenu.status = KeyName(ev) + " is reserved for menus"
+			return
+		}
+		titleMenu.SaveControls()
+	}
+
+	titleMenu.rebindAction = ActionNone
+	titleMenu.RefreshControlsPage()
+}
+
+func (titleMenu *TitleMenu) SaveControls() {
+	if err := titleMenu.controls.Save(); err != nil {
+		titleMenu.status = "Could not save controls: " + err.Error()
+	}
+	titleMenu.RefreshControlsPage()
+}
+
+// Reads the high score file and lists the scores of one of its tables.
+func (titleMenu *TitleMenu) RefreshHighScoresPage(tableIndex int) {
+	page := titleMenu.titleMenuPages[HighScoresPageOrder]
+	page.cursorState = 0
+
+	var scores []HighScore
+	fileName, err := HighScoreFilePath()
+	if err == nil {
+		scores, err = LoadHighScores(fileName)
+	}
+	if err != nil {
+		titleMenu.status = "Could not read high scores: " + err.Error()
+	}
+
+	page.titleMenuItems = GenerateHighScoreList(scores, tableIndex)
+}
+
+// Changes the rule under the cursor on the custom difficulty page by the given number of steps.
+func (titleMenu *TitleMenu) HandleAdjustEvent(steps int) {
+	page := titleMenu.titleMenuPages[titleMenu.pageState]
+	switch value := page.titleMenuItems[page.cursorState].value.(type) {
+	case RulesetFieldIndex:
+		titleMenu.AdjustCustomRule(int(value), steps)
+	case PlayersOption:
+		titleMenu.AdjustPlayersOption(value, steps)
+	}
+}
+
+// Switches the multiplayer mode or camera to the next or previous one.
+func (titleMenu *TitleMenu) AdjustPlayersOption(option PlayersOption, steps int) {
+	setting, order := &titleMenu.settings.Mode, multiplayerModeOrder
+	if option == PlayersOptionCamera {
+		setting, order = &titleMenu.settings.Camera, cameraOrder
+	}
+	for i, value := range order {
+		if value == *setting {
+			*setting = order[((i+steps)%len(order)+len(order))%len(order)]
+			break
 		}
 	}
+	titleMenu.RefreshPlayersPage()
 }
 
-func GenerateTitleMenu() TitleMenu {
+func (titleMenu *TitleMenu) RefreshPlayersPage() {
+	titleMenu.titleMenuPages[PlayersPageOrder].titleMenuItems = GeneratePlayersList(titleMenu.settings)
+}
+
+func (titleMenu *TitleMenu) AdjustCustomRule(field int, steps int) {
+	titleMenu.settings.Custom.StepField(field, steps)
+	if err := titleMenu.settings.SaveCustom(); err != nil {
+		titleMenu.status = "Could not save settings: " + err.Error()
+	}
+	titleMenu.RefreshCustomDifficultyPage()
+}
+
+func (titleMenu *TitleMenu) RefreshCustomDifficultyPage() {
+	titleMenu.titleMenuPages[CustomDifficultyPageOrder].titleMenuItems = GenerateCustomDifficultyList(&titleMenu.settings.Custom)
+}
+
+func (titleMenu *TitleMenu) RefreshControlsPage() {
+	titleMenu.titleMenuPages[ControlsPageOrder].titleMenuItems = GenerateControlsList(titleMenu.controls, titleMenu.rebindAction)
+}
+
+func GenerateTitleMenu(controls *Controls, settings *Settings) TitleMenu {
 	newGamePageItem := TitleMenuItem{0, "New game", nil}
-	loadGamePageItem := TitleMenuItem{1, "Load game", nil}
-	exitGameItem := TitleMenuItem{2, "Exit", nil}
+	twoPlayersPageItem := TitleMenuItem{1, "Two players", nil}
+	loadGamePageItem := TitleMenuItem{2, "Load game", nil}
+	highScoresPageItem := TitleMenuItem{3, "High scores", nil}
+	controlsPageItem := TitleMenuItem{4, "Controls", nil}
+	exitGameItem := TitleMenuItem{5, "Exit", nil}
 
 	titleHeaderAnimation := []string{TitleMenuHeaderAnim1, TitleMenuHeaderAnim2, TitleMenuHeaderAnim3, TitleMenuHeaderAnim4, TitleMenuHeaderAnim5, TitleMenuHeaderAnim6,
 		TitleMenuHeaderAnim7, TitleMenuHeaderAnim8, TitleMenuHeaderAnim9, TitleMenuHeaderAnim10, TitleMenuHeaderAnim11, TitleMenuHeaderAnim12, TitleMenuHeaderAnim13}
@@ -145,8 +421,11 @@ func GenerateTitleMenu() TitleMenu {
 		0,
 		map[int]TitleMenuItem{
 			0: newGamePageItem,
-			1: loadGamePageItem,
-			2: exitGameItem,
+			1: twoPlayersPageItem,
+			2: loadGamePageItem,
+			3: highScoresPageItem,
+			4: controlsPageItem,
+			5: exitGameItem,
 		},
 	}
 
@@ -155,29 +434,78 @@ func GenerateTitleMenu() TitleMenu {
 		titleHeaderAnimation,
 		0,
 		0,
-		GenerateNewGameMapList(),
+		GenerateNewGameMapList(settings.MapSources()),
 	}
 
-	tm := TitleMenu{0, MainMenuPageOrder, map[int]*TitleMenuPage{MainMenuPageOrder: &mainMenu, NewGamePageOrder: &newGamePage}, "", false}
-	return tm
-}
+	controlsPage := TitleMenuPage{
+		ControlsPageOrder,
+		titleHeaderAnimation,
+		0,
+		0,
+		GenerateControlsList(controls, ActionNone),
+	}
 
-func GenerateNewGameMapList() map[int]TitleMenuItem {
+	highScoresPage := TitleMenuPage{
+		HighScoresPageOrder,
+		titleHeaderAnimation,
+		0,
+		0,
+		map[int]TitleMenuItem{0: {0, "Go back", nil}},
+	}
 
-	titleMenuItems := make(map[int]TitleMenuItem)
-	files, err := os.ReadDir("kartor/")
+	difficultyPage := TitleMenuPage{
+		DifficultyPageOrder,
+		titleHeaderAnimation,
+		0,
+		0,
+		GenerateDifficultyList(),
+	}
 
-	if err != nil {
-		return nil
+	customDifficultyPage := TitleMenuPage{
+		CustomDifficultyPageOrder,
+		titleHeaderAnimation,
+		0,
+		0,
+		GenerateCustomDifficultyList(&settings.Custom),
 	}
 
+	playersPage := TitleMenuPage{
+		PlayersPageOrder,
+		titleHeaderAnimation,
+		0,
+		0,
+		GeneratePlayersList(settings),
+	}
+
+	tm := TitleMenu{
+		pageState: MainMenuPageOrder,
+		titleMenuPages: map[int]*TitleMenuPage{
+			MainMenuPageOrder:         &mainMenu,
+			NewGamePageOrder:          &newGamePage,
+			ControlsPageOrder:         &controlsPage,
+			HighScoresPageOrder:       &highScoresPage,
+			DifficultyPageOrder:       &difficultyPage,
+			CustomDifficultyPageOrder: &customDifficultyPage,
+			PlayersPageOrder:          &playersPage,
+		},
+		settings:     settings,
+		controls:     controls,
+		rebindAction: ActionNone,
+	}
+	return tm
+}
+
+// Lists the maps from all sources, by the name in their metadata and where they were found.
+func GenerateNewGameMapList(sources []MapSource) map[int]TitleMenuItem {
+
+	titleMenuItems := make(map[int]TitleMenuItem)
 	maxI := 0
 
-	for i, file := range files {
+	for i, mapFile := range FindMaps(sources) {
 		titleMenuItems[i] = TitleMenuItem{
 			i,
-			file.Name(),
-			file.Name(),
+			mapFile.Title() + " (" + mapFile.source + ")",
+			mapFile,
 		}
 		maxI++
 	}
@@ -190,3 +518,89 @@ func GenerateNewGameMapList() map[int]TitleMenuItem {
 
 	return titleMenuItems
 }
+
+// Lists each action with its keys, followed by the preset switcher.
+// The action being rebound, if any, asks for a key instead of listing its keys.
+func GenerateControlsList(controls *Controls, rebindAction int) map[int]TitleMenuItem {
+	titleMenuItems := make(map[int]TitleMenuItem)
+
+	for i, action := range actionOrder {
+		text := actionLabels[action] + ": " + controls.KeysString(action)
+		if action == rebindAction {
+			text = actionLabels[action] + ": press a key (Esc to cancel)"
+		}
+		titleMenuItems[i] = TitleMenuItem{i, text, action}
+	}
+
+	maxI := len(actionOrder)
+	nextPreset := presetOrder[0]
+	for i, preset := range presetOrder {
+		if preset == controls.preset {
+			nextPreset = presetOrder[(i+1)%len(presetOrder)]
+		}
+	}
+	titleMenuItems[maxI] = TitleMenuItem{maxI, "Preset: " + controls.preset, ControlsPreset(nextPreset)}
+	titleMenuItems[maxI+1] = TitleMenuItem{maxI + 1, "Reset to defaults", nil}
+	titleMenuItems[maxI+2] = TitleMenuItem{maxI + 2, "Go back", nil}
+
+	return titleMenuItems
+}
+
+// Lists the scores of one map and game mode. The first item switches to the next map and game mode.
+func GenerateHighScoreList(scores []HighScore, tableIndex int) map[int]TitleMenuItem {
+	titleMenuItems := make(map[int]TitleMenuItem)
+	tables := HighScoreTables(scores)
+	if len(tables) == 0 {
+		titleMenuItems[0] = TitleMenuItem{0, "No high scores yet", nil}
+		titleMenuItems[1] = TitleMenuItem{1, "Go back", nil}
+		return titleMenuItems
+	}
+
+	tableIndex = tableIndex % len(tables)
+	table := tables[tableIndex]
+	titleMenuItems[0] = TitleMenuItem{0, "< " + table[0] + " (" + table[1] + ") >", HighScoreTable(tableIndex + 1)}
+
+	maxI := 1
+	for rank, score := range HighScoresFor(scores, table[0], table[1]) {
+		text := fmt.Sprintf("%2d. %4d points %6d ticks  %s  seed %d", rank+1, score.Score, score.Ticks, score.Date.Local().Format("2006-01-02"), score.Seed)
+		titleMenuItems[maxI] = TitleMenuItem{maxI, text, nil}
+		maxI++
+	}
+	titleMenuItems[maxI] = TitleMenuItem{maxI, "Go back", nil}
+
+	return titleMenuItems
+}
+
+func GenerateDifficultyList() map[int]TitleMenuItem {
+	titleMenuItems := make(map[int]TitleMenuItem)
+	for i, difficulty := range difficultyOrder {
+		titleMenuItems[i] = TitleMenuItem{i, strings.Title(difficulty), DifficultyChoice(difficulty)}
+	}
+	titleMenuItems[len(difficultyOrder)] = TitleMenuItem{len(difficultyOrder), "Go back", nil}
+
+	return titleMenuItems
+}
+
+// Lists each rule of the custom difficulty. Left and right change the rule under the cursor.
+func GenerateCustomDifficultyList(rules *Ruleset) map[int]TitleMenuItem {
+	titleMenuItems := make(map[int]TitleMenuItem)
+	for i, field := range rulesetFields {
+		titleMenuItems[i] = TitleMenuItem{i, field.label + ": < " + rules.FieldString(i) + " >", RulesetFieldIndex(i)}
+	}
+
+	maxI := len(rulesetFields)
+	titleMenuItems[maxI] = TitleMenuItem{maxI, "Start game", nil}
+	titleMenuItems[maxI+1] = TitleMenuItem{maxI + 1, "Go back", nil}
+
+	return titleMenuItems
+}
+
+// Lists the options for a game of several players. Left and right switch the option under the cursor.
+func GeneratePlayersList(settings *Settings) map[int]TitleMenuItem {
+	return map[int]TitleMenuItem{
+		0: {0, "Mode: < " + optionLabels[settings.Mode] + " >", PlayersOption(PlayersOptionMode)},
+		1: {1, "Camera: < " + optionLabels[settings.Camera] + " >", PlayersOption(PlayersOptionCamera)},
+		2: {2, "Pick a map", nil},
+		3: {3, "Go back", nil},
+	}
+}
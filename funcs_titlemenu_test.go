@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Runs the title menu on a simulation screen with the given keys already queued, and waits for it to finish.
+func runTitleMenu(t *testing.T, keys ...tcell.Key) (*TitleMenu, tcell.SimulationScreen) {
+	var events []tcell.Event
+	for _, key := range keys {
+		events = append(events, tcell.NewEventKey(key, 0, tcell.ModNone))
+	}
+	return runTitleMenuEvents(t, events...)
+}
+
+// Runs the title menu on a simulation screen with the given events already queued, and waits for it to finish.
+func runTitleMenuEvents(t *testing.T, events ...tcell.Event) (*TitleMenu, tcell.SimulationScreen) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // Only the built-in maps
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(screen.Fini)
+	screen.SetSize(80, 24)
+
+	controls, err := NewControls(PresetDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := DefaultSettings()
+	titleMenu := GenerateTitleMenu(&controls, &settings)
+
+	finished := make(chan struct{})
+	go func() {
+		titleMenu.Run(screen)
+		close(finished)
+	}()
+	for _, ev := range events {
+		if err := screen.PostEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("title menu did not finish")
+	}
+
+	return &titleMenu, screen
+}
+
+func TestTitleMenuPicksMapAndDifficulty(t *testing.T) {
+	// New game, the second map, and then the difficulty after normal.
+	titleMenu, screen := runTitleMenu(t, tcell.KeyEnter, tcell.KeyDown, tcell.KeyEnter, tcell.KeyDown, tcell.KeyEnter)
+
+	if titleMenu.quit || titleMenu.selectedMap.fileName != "liten_skog.karta" {
+		t.Errorf("expected liten_skog.karta to be picked, got %q", titleMenu.selectedMap.fileName)
+	}
+	if titleMenu.settings.Difficulty != DifficultyHard {
+		t.Errorf("expected hard difficulty, got %s", titleMenu.settings.Difficulty)
+	}
+
+	// Input after the menu has finished is left for the game.
+	screen.InjectKey(tcell.KeyRune, 'w', tcell.ModNone)
+	if ev, isKey := screen.PollEvent().(*tcell.EventKey); !isKey || ev.Rune() != 'w' {
+		t.Errorf("key pressed after the title menu should reach the game, got %v", ev)
+	}
+}
+
+func TestTitleMenuLeavesLaterKeysForTheGame(t *testing.T) {
+	// New game, the first map and normal difficulty, then keys for the game, pressed before the menu finished.
+	titleMenu, screen := runTitleMenu(t, tcell.KeyEnter, tcell.KeyEnter, tcell.KeyEnter, tcell.KeyRight, tcell.KeyLeft)
+
+	// Keys read by the menu after it exited are kept, and the rest are still waiting on the screen.
+	events := titleMenu.unhandled
+	for len(events) < 2 {
+		events = append(events, screen.PollEvent())
+	}
+	for i, want := range []tcell.Key{tcell.KeyRight, tcell.KeyLeft} {
+		if ev, isKey := events[i].(*tcell.EventKey); !isKey || ev.Key() != want {
+			t.Errorf("expected key %d to be %v, got %v", i, want, events[i])
+		}
+	}
+}
+
+func TestTitleMenuQuits(t *testing.T) {
+	titleMenu, _ := runTitleMenu(t, tcell.KeyDown, tcell.KeyEscape)
+	if !titleMenu.quit {
+		t.Errorf("escape should quit")
+	}
+}
+
+func TestTitleMenuStopsPollingWhenTheTerminalIsGone(t *testing.T) {
+	titleMenu, screen := runTitleMenuEvents(t, tcell.NewEventError(io.EOF))
+	if !titleMenu.quit {
+		t.Fatal("title menu should quit")
+	}
+
+	// The input goroutine has stopped, so nothing takes the key off the screen.
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	time.Sleep(50 * time.Millisecond)
+	if !screen.HasPendingEvent() {
+		t.Errorf("the title menu is still polling for input after its terminal is gone")
+	}
+}
+
+func TestTitleMenuTwoPlayers(t *testing.T) {
+	// Two players, competitive, a shared camera, Pick a map, the first map, and normal difficulty.
+	titleMenu, _ := runTitleMenu(t, tcell.KeyDown, tcell.KeyEnter, tcell.KeyRight, tcell.KeyDown, tcell.KeyLeft, tcell.KeyDown, tcell.KeyEnter,
+		tcell.KeyEnter, tcell.KeyDown, tcell.KeyEnter)
+
+	settings := titleMenu.settings
+	if titleMenu.quit || settings.Players != 2 || settings.Mode != GameModeVersus || settings.Camera != CameraShared {
+		t.Errorf("expected a competitive game of two players with a shared camera, got %d players, %s, %s", settings.Players, settings.Mode, settings.Camera)
+	}
+}
+
+func TestTitleMenuScrollsLongPages(t *testing.T) {
+	// Controls, then up to the last item, Go back, which is below the bottom of the screen.
+	_, screen := runTitleMenu(t, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter, tcell.KeyUp, tcell.KeyEscape)
+
+	cells, width, height := screen.GetContents()
+	row := ""
+	for x := 0; x < width; x++ {
+		row += string(cells[(height-1)*width+x].Runes)
+	}
+	if !strings.Contains(row, ">Go back") {
+		t.Errorf("expected the cursor on Go back in the last row, got %q", row)
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"math/rand"
+	"sort"
 )
 
 func (game *Game) PlantSeed(coordinate Coordinate) bool {
@@ -20,10 +20,10 @@ func (game *Game) PopulateTrees() int {
 		TreeStateSapling,
 		TreeStateAdult,
 	}
-	maxTreeCount := rand.Intn(5) + 3
+	maxTreeCount := game.random.Intn(5) + 3
 	treeCount := 0
 	for i := 0; i < maxTreeCount; i++ {
-		state := states[rand.Intn(len(states))]
+		state := states[game.random.Intn(len(states))]
 		coordinate := game.GetRandomPlantableCoordinate()
 		game.world.content[coordinate] = &Tree{coordinate, state}
 		treeCount++
@@ -32,32 +32,65 @@ func (game *Game) PopulateTrees() int {
 	return treeCount
 }
 
+// Scatters grass, and any other tiles with a scatter weight, over the map.
 func (game *Game) PopulateGrass() int {
-	keys := []int{
-		KeyGrassLight,
-		KeyGrassHeavy,
+	var keys []int
+	totalWeight := 0
+	for key, tile := range game.settings.tiles {
+		if tile.scatter > 0 {
+			keys = append(keys, key)
+			totalWeight += tile.scatter
+		}
+	}
+	if totalWeight == 0 {
+		return 0
 	}
-	maxGrassCount := rand.Intn(10) + 6
+	sort.Ints(keys) // Same map and seed give the same grass
+
+	maxGrassCount := game.random.Intn(10) + 6
 	grassCount := 0
 	for i := 0; i < maxGrassCount; i++ {
-		key := keys[rand.Intn(len(keys))]
+		weight := game.random.Intn(totalWeight)
+		key := keys[0]
+		for _, key = range keys {
+			if weight < game.settings.tiles[key].scatter {
+				break
+			}
+			weight -= game.settings.tiles[key].scatter
+		}
 		coordinate := game.GetRandomPlantableCoordinate()
-		game.world.content[coordinate] = Object{key, false, true, false}
+		game.world.content[coordinate] = NewObject(game.settings.tiles, key)
 		grassCount++
 	}
 
 	return grassCount
 }
 
+// Returns the tile key for the tree's trunk, stump or sapling. Adult trees also have leaves above the trunk.
+func (tree *Tree) Key() int {
+	switch tree.state {
+	case TreeStateSeed:
+		return KeyTreeSeed
+	case TreeStateSapling:
+		return KeyTreeSapling
+	case TreeStateStump:
+		return KeyTreeStump
+	case TreeStateStumpling:
+		return KeyTreeStumpling
+	default:
+		return KeyTreeTrunk
+	}
+}
+
 func (game *Game) GrowTrees() int {
 	growthCount := 0
 
-	for _, content := range game.world.content {
-		switch content := content.(type) {
+	for _, position := range game.world.Coordinates() {
+		switch content := game.world.content[position].(type) {
 		case *Tree:
-			if growthInfo, exists := treeGrowingStages[content.state]; exists {
-				if rand.Float64() <= growthInfo.chance {
-					content.state = growthInfo.newState
+			if newState, exists := treeGrowingStages[content.state]; exists {
+				if game.random.Float64() <= game.rules.GrowthChanceSeed {
+					content.state = newState
 					growthCount++
 				}
 			}
@@ -67,7 +100,32 @@ func (game *Game) GrowTrees() int {
 	return growthCount
 }
 
-func (game *Game) DecrementTree(position Coordinate, stages int) bool {
+// Returns the fraction [0-1] of walkable tiles inside the borders that are covered by saplings or adult trees.
+func (game *Game) ForestCover() float64 {
+	area := (game.world.width - 2) * (game.world.height - 2)
+	treeCount := 0
+	for coord, content := range game.world.content {
+		switch content := content.(type) {
+		case Object:
+			if _, isBorder := game.world.borders[coord]; content.collidable && !isBorder { // The borders aren't part of the area
+				area--
+			}
+		case *Tree:
+			if content.state == TreeStateSapling || content.state == TreeStateAdult {
+				treeCount++
+			}
+		}
+	}
+
+	if area <= 0 {
+		return 0
+	}
+
+	return float64(treeCount) / float64(area)
+}
+
+// Chops the tree at the position down by the given number of stages. The player is credited with a tree chopped down to nothing.
+func (game *Game) DecrementTree(player *Actor, position Coordinate, stages int) bool {
 	content, exists := game.world.content[position]
 
 	if !exists { // No content of any type at this location
@@ -78,16 +136,30 @@ func (game *Game) DecrementTree(position Coordinate, stages int) bool {
 	case *Tree:
 		var exists bool
 		newState := content.state
+		choppedStates := []int{}
 		for i := 0; i < stages; i++ { // We move down the harvesting stages one or more times
+			choppedStates = append(choppedStates, newState)
 			newState, exists = treeHarvestingStages[newState]
 			if !exists {
 				return false
 			}
 		}
 
+		if game.stats.chopped == nil {
+			game.stats.chopped = make(map[int]int)
+		}
+		for _, state := range choppedStates {
+			game.stats.chopped[state]++
+		}
+
 		if newState == TreeStateRemoved {
 			delete(game.world.content, position)
-			game.player.score++ // Increase player score when tree is felled
+			player.score++ // Increase player score when tree is felled
+			if content.state == TreeStateSeed {
+				player.inventory.seeds++
+			} else {
+				player.inventory.wood++
+			}
 		}
 
 		content.state = newState
@@ -97,23 +169,23 @@ func (game *Game) DecrementTree(position Coordinate, stages int) bool {
 	}
 }
 
-func (game *Game) Chop(dir int, stages int) int {
+func (game *Game) Chop(player *Actor, dir int, stages int) int {
 	// Determine which coordinates to check for chopping based on direction and player position.
 	var targetCoordinates [4]Coordinate
 	switch dir {
 	case DirOmni:
-		targetCoordinates[0] = Translate(game.player.position, 0, -1)
-		targetCoordinates[1] = Translate(game.player.position, 1, 0)
-		targetCoordinates[2] = Translate(game.player.position, 0, 1)
-		targetCoordinates[3] = Translate(game.player.position, -1, 0)
+		targetCoordinates[0] = Translate(player.position, 0, -1)
+		targetCoordinates[1] = Translate(player.position, 1, 0)
+		targetCoordinates[2] = Translate(player.position, 0, 1)
+		targetCoordinates[3] = Translate(player.position, -1, 0)
 	case DirUp:
-		targetCoordinates[0] = Translate(game.player.position, 0, -1)
+		targetCoordinates[0] = Translate(player.position, 0, -1)
 	case DirRight:
-		targetCoordinates[0] = Translate(game.player.position, 1, 0)
+		targetCoordinates[0] = Translate(player.position, 1, 0)
 	case DirDown:
-		targetCoordinates[0] = Translate(game.player.position, 0, 1)
+		targetCoordinates[0] = Translate(player.position, 0, 1)
 	case DirLeft:
-		targetCoordinates[0] = Translate(game.player.position, -1, 0)
+		targetCoordinates[0] = Translate(player.position, -1, 0)
 	}
 
 	// Chop trees that are within the target coordinate(s)
@@ -122,7 +194,7 @@ func (game *Game) Chop(dir int, stages int) int {
 		if content, exists := game.world.content[targetCoordinate]; exists {
 			switch content.(type) {
 			case *Tree:
-				if game.DecrementTree(targetCoordinate, stages) {
+				if game.DecrementTree(player, targetCoordinate, stages) {
 					choppedCount++
 				}
 			}
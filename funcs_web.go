@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/net/websocket"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// Serves the browser client on the address of the settings, with a game on the settings' map that every page joins.
+// Pages play with the keys of the controls. An address without a host only listens on this computer.
+func ServeWeb(settings Settings, controls Controls) error {
+	address, localOnly, err := WebListenAddress(settings.Web)
+	if err != nil {
+		return err
+	}
+	gameAddress, err := ServeLoopback(ServeOptions{tickRate: ServeDefaultTickRate, settings: settings})
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving %s on http://%s/\n", settings.Map, listener.Addr())
+
+	server := &WebServer{game: gameAddress, config: NewWebConfig(settings.tiles, controls), localOnly: localOnly}
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: WebReadHeaderTimeout}
+	return httpServer.Serve(listener)
+}
+
+// Returns the address to listen on for the address given with --web, and whether it is on the loopback interface.
+// An address without a host, like ":8080", listens on the loopback interface.
+func WebListenAddress(address string) (string, bool, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", false, fmt.Errorf("web address %q: %v", address, err)
+	}
+	if host == "" {
+		host = WebDefaultHost
+	}
+	ip := net.ParseIP(host)
+
+	return net.JoinHostPort(host, port), host == "localhost" || (ip != nil && ip.IsLoopback()), nil
+}
+
+// Returns how the browser client draws each tile of the registry, and which keys it sends actions for.
+func NewWebConfig(registry map[int]Tile, controls Controls) WebConfig {
+	config := WebConfig{Tiles: make(map[string]WebTile), Squirrel: registry[KeySquirrel].id, Leaves: registry[KeyTreeLeaves].id, Canopy: treeStateNames[TreeStateAdult], Keys: make(map[string]BotAction)}
+	for _, tile := range registry {
+		foreground, background, _ := tile.style.Decompose()
+		config.Tiles[tile.id] = WebTile{string(tile.char), CssColor(foreground), CssColor(background), tile.aboveActor}
+	}
+	for _, key := range playerTileKeys {
+		config.Players = append(config.Players, registry[key].id)
+	}
+	for name, actions := range botActions {
+		for dir, action := range actions {
+			for _, key := range controls.bindings[action] {
+				if webKey, found := webKeyNames[key]; found {
+					key = webKey
+				}
+				config.Keys[key] = BotAction{name, directionNames[dir]}
+			}
+		}
+	}
+
+	return config
+}
+
+// Returns the colour as a CSS colour, or an empty string for the default colour.
+func CssColor(color tcell.Color) string {
+	rgb := color.Hex()
+	if color == tcell.ColorDefault || rgb < 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("#%06x", rgb)
+}
+
+// Returns the handler of the web server: the client's files, its config, and the WebSocket the client plays over.
+func (server *WebServer) Handler() http.Handler {
+	files, _ := fs.Sub(webFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(server.config)
+	})
+	mux.Handle("/play", websocket.Server{Handler: server.Bridge, Handshake: server.CheckOrigin})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.localOnly && !IsLocalHost(r.Host) {
+			http.Error(w, "only served to this computer", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Returns whether the host of a request's Host header names this computer.
+func IsLocalHost(hostPort string) bool {
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// Only lets pages served by this server open the WebSocket, so that other sites can't play through a visitor's browser.
+func (server *WebServer) CheckOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host != r.Host {
+		return fmt.Errorf("page from another site")
+	}
+	config.Origin = origin
+
+	return nil
+}
+
+// Connects a page's WebSocket to the game server. Each message from the page is sent to the server as a command,
+// and each line from the server to the page as a message, so pages speak the same protocol as skogshuggare join.
+func (server *WebServer) Bridge(ws *websocket.Conn) {
+	defer ws.Close()
+	ws.MaxPayloadBytes = WebMaxCommandBytes
+	conn, err := net.Dial("tcp", server.game)
+	if err != nil {
+		websocket.JSON.Send(ws, NetMessage{Error: err.Error()})
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		defer ws.Close()
+		lines := bufio.NewReader(conn) // Lines of whole worlds can be longer than a bufio.Scanner takes
+		for {
+			line, err := lines.ReadString('\n')
+			if err != nil || websocket.Message.Send(ws, line) != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var command string
+		if err := websocket.Message.Receive(ws, &command); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte(strings.ReplaceAll(command, "\n", " ") + "\n")); err != nil {
+			return
+		}
+	}
+}
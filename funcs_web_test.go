@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Starts the web server in front of a test game server, and returns its URL and the game's ticks.
+func startTestWebServer(t *testing.T) (string, chan<- time.Time) {
+	address, tick := startTestServer(t, ""+
+		"################\n"+
+		"#p          s  #\n"+
+		"#              #\n"+
+		"#  s       #####\n"+
+		"#          #  f#\n"+
+		"################")
+	controls, err := NewControls(PresetDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &WebServer{game: address, config: NewWebConfig(DefaultTiles(), controls), localOnly: true}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	return httpServer.URL, tick
+}
+
+func TestWebConfig(t *testing.T) {
+	controls, err := NewControls(PresetVi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewWebConfig(DefaultTiles(), controls)
+
+	if fire := config.Tiles["fire1"]; fire.Glyph != "▓" || fire.Foreground != "#ffa500" || fire.Background != "#ff4500" || !fire.AboveActor {
+		t.Errorf("expected fire in orange on orangered, above actors, got %+v", fire)
+	}
+	if water := config.Tiles["waterLight"]; water.Foreground != "" {
+		t.Errorf("expected water without a colour of its own, got %+v", water)
+	}
+	for key, want := range map[string]BotAction{"ArrowUp": {"move", "up"}, "k": {"move", "up"}, "a": {"chop", "left"}, "S": {"dig", "down"}} {
+		if action := config.Keys[key]; action != want {
+			t.Errorf("expected %q to send %+v, got %+v", key, want, action)
+		}
+	}
+	if len(config.Players) != len(playerTileKeys) || config.Players[0] != "player" || config.Squirrel != "squirrel" {
+		t.Errorf("expected the player and squirrel tiles, got %q and %q", config.Players, config.Squirrel)
+	}
+}
+
+func TestWebPlays(t *testing.T) {
+	url, tick := startTestWebServer(t)
+
+	response, err := http.Get(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if !strings.Contains(string(page), "client.js") {
+		t.Errorf("expected the page to load the client, got %q", page)
+	}
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(url, "http")+"/play", "", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := websocket.JSON.Send(ws, NetCommand{Type: NetCommandJoin, Name: "Anna"}); err != nil {
+		t.Fatal(err)
+	}
+	var welcome NetMessage
+	if err := websocket.JSON.Receive(ws, &welcome); err != nil {
+		t.Fatal(err)
+	}
+	if welcome.Id != 1 || welcome.State == nil || !welcome.State.Full || len(welcome.State.Tiles) == 0 {
+		t.Fatalf("expected to join with the whole world, got %+v", welcome)
+	}
+
+	websocket.JSON.Send(ws, NetCommand{Type: NetCommandAction, Action: BotActionMove, Dir: "right"})
+	for i := 0; i < 20; i++ {
+		tick <- time.Now()
+		var message NetMessage
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.State != nil && message.State.Players[0].X == 2 {
+			return
+		}
+	}
+	t.Errorf("expected the page's player to move right")
+}
+
+func TestWebRefusesOtherSites(t *testing.T) {
+	url, _ := startTestWebServer(t)
+
+	if ws, err := websocket.Dial("ws"+strings.TrimPrefix(url, "http")+"/play", "", "http://example.com"); err == nil {
+		ws.Close()
+		t.Errorf("expected a page from another site not to be let in")
+	}
+
+	// A site whose name points at this computer is still another site.
+	request, _ := http.NewRequest("GET", url+"/config", nil)
+	request.Host = "example.com"
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("expected requests for another host to be refused, got %s", response.Status)
+	}
+}
+
+func TestWebListenAddress(t *testing.T) {
+	tests := []struct {
+		address   string
+		want      string
+		localOnly bool
+	}{
+		{":8080", "127.0.0.1:8080", true},
+		{"localhost:8080", "localhost:8080", true},
+		{"0.0.0.0:8080", "0.0.0.0:8080", false},
+	}
+	for _, test := range tests {
+		if address, localOnly, err := WebListenAddress(test.address); err != nil || address != test.want || localOnly != test.localOnly {
+			t.Errorf("%q: expected %q local only %v, got %q %v %v", test.address, test.want, test.localOnly, address, localOnly, err)
+		}
+	}
+	if _, _, err := WebListenAddress("8080"); err == nil {
+		t.Errorf("expected an address without a port to be refused")
+	}
+}
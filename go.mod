@@ -2,13 +2,18 @@ module github.com/blaine-t-bush/skogshuggare
 
 go 1.18
 
-require github.com/gdamore/tcell v1.4.0
+require (
+	github.com/gdamore/tcell/v2 v2.5.4
+	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.22.0
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.13 // indirect
+	github.com/mattn/go-runewidth v0.0.14 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20220412211240-33da011f77ad // indirect
-	golang.org/x/text v0.3.7 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/term v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -1,249 +1,489 @@
-package main
-
-import (
-	"bufio"
-	"fmt"
-	"io/ioutil"
-	"math/rand"
-	"os"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
-
-	"github.com/gdamore/tcell"
-)
-
-func main() {
-	// Attempt to get vision radius from command line args.
-	visionRadius := 100
-	if len(os.Args) >= 2 { // Make sure there are arguments before accessing slices
-		visionRadius, _ = strconv.Atoi(os.Args[1])
-	}
-
-	// Seed randomizer.
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	// Initialize game state.
-	var game Game
-	var err error
-
-	// Initialize tcell.
-	tcell.SetEncodingFallback(tcell.EncodingFallbackASCII)
-	game.screen, err = tcell.NewScreen()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
-	}
-	if err = game.screen.Init(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-	}
-
-	// Set default style and clear terminal.
-	game.screen.SetStyle(tcell.StyleDefault)
-	game.screen.Clear()
-
-	// Draw and handle menu inputs before initializing and drawing the game itself
-	titleMenu := GenerateTitleMenu() // Generate title menu
-	var twg sync.WaitGroup
-	twg.Add(1)
-	go TitleMenuHandler(&twg, game.screen, &titleMenu)
-	twg.Wait()
-
-	// Read map to initialize game state.
-	mapName := titleMenu.selectedMap
-	worldContent, playerPosition, squirrelPositions := ReadMap("kartor/" + mapName)
-	squirrels := make(map[int]*Actor)
-	for index, position := range squirrelPositions {
-		squirrels[index] = &Actor{position: position, visionRadius: 100, score: 0, hitPointsCurrent: MaxHitPointsSquirrel, hitPointsMax: MaxHitPointsSquirrel}
-	}
-	game.player = Actor{position: playerPosition, visionRadius: visionRadius, score: 0, hitPointsCurrent: MaxHitPointsPlayer, hitPointsMax: MaxHitPointsPlayer}
-	game.squirrels = squirrels
-	game.world = worldContent
-	game.menu = Menu{15, 5, Coordinate{0, 0}, []string{}}
-	game.exit = false
-
-	// Randomly seed map with trees in various states.
-	game.PopulateTrees()
-	game.PopulateGrass()
-
-	// Wait for Loop() goroutine to finish before moving on.
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go game.Ticker(&wg)
-	wg.Wait()
-	game.screen.Fini()
-
-	fmt.Println("Game over. Final score:", game.player.score)
-}
-
-func TitleMenuHandler(wg *sync.WaitGroup, screen tcell.Screen, titleMenu *TitleMenu) { // TODO make sure variables are not changed at the same time w/ mutex or channels
-	defer wg.Done()
-
-	// Initialize game menu update ticker.
-	ticker := time.NewTicker(TickRate * time.Millisecond)
-
-	// Start the input handler
-	go titleMenu.InputHandler(screen)
-	// Start title menu animation handler
-	go titleMenu.AnimationHandler()
-
-	for range ticker.C {
-		titleMenu.Update(screen)
-		titleMenu.Draw(screen)
-		if titleMenu.exit {
-			screen.Clear()
-			return
-		}
-	}
-}
-
-func ReadMap(fileName string) (World, Coordinate, []Coordinate) {
-	filebuffer, err := ioutil.ReadFile(fileName)
-	worldContent := make(map[Coordinate]interface{})
-
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	filedata := string(filebuffer)
-	data := bufio.NewScanner(strings.NewReader(filedata))
-	data.Split(bufio.ScanLines)
-	width := 0
-	height := 0
-	var playerPosition Coordinate
-	var squirrelPositions []Coordinate
-	for data.Scan() {
-		// Check if width needs to be updated. It's determined by the longest line.
-		lineWidth := len(data.Text())
-		if lineWidth > width {
-			width = lineWidth
-		}
-
-		// Update the worldContent map according to special characters.
-		for i := 0; i < lineWidth; i++ {
-			switch data.Text()[i] {
-			case MapPlayer:
-				playerPosition = Coordinate{i, height}
-			case MapSquirrel:
-				squirrelPositions = append(squirrelPositions, Coordinate{i, height})
-			case MapWall:
-				worldContent[Coordinate{i, height}] = Object{KeyWall, true, false, false}
-			case MapWaterLight:
-				worldContent[Coordinate{i, height}] = Object{KeyWaterLight, true, false, false}
-			case MapWaterHeavy:
-				worldContent[Coordinate{i, height}] = Object{KeyWaterHeavy, true, false, false}
-			case MapFire:
-				worldContent[Coordinate{i, height}] = &Fire{Coordinate{i, height}, 0}
-			}
-		}
-
-		// Increment the height once for each row.
-		height++
-	}
-
-	_borders := make(map[Coordinate]int)
-
-	for c := range worldContent {
-		if border, isBorder := IsBorder(width, height, c); isBorder {
-			_borders[c] = border
-		}
-	}
-
-	return World{width, height, _borders, worldContent}, playerPosition, squirrelPositions
-}
-
-func (game *Game) Ticker(wg *sync.WaitGroup) {
-	// Initialize game update ticker.
-	ticker := time.NewTicker(TickRate * time.Millisecond)
-
-	// Update game state and re-draw on every tick.
-	for range ticker.C {
-		game.Draw()
-		game.Update()
-		if game.exit {
-			wg.Done()
-			return
-		}
-	}
-}
-
-func (game *Game) Update() {
-	// Listen for keyboard events for player actions,
-	// or terminal resizing events to re-draw the screen.
-	ev := game.screen.PollEvent()
-	switch ev := ev.(type) {
-	case *tcell.EventKey:
-		switch ev.Key() {
-		case tcell.KeyEscape:
-			game.exit = true
-			return
-		case tcell.KeyUp:
-			game.MovePlayer(1, DirUp)
-		case tcell.KeyRight:
-			game.MovePlayer(1, DirRight)
-		case tcell.KeyDown:
-			game.MovePlayer(1, DirDown)
-		case tcell.KeyLeft:
-			game.MovePlayer(1, DirLeft)
-		case tcell.KeyRune:
-			switch ev.Rune() {
-			case rune('q'):
-				game.Chop(DirOmni, 1)
-			case rune('w'):
-				game.Chop(DirUp, 1)
-			case rune('d'):
-				game.Chop(DirRight, 1)
-			case rune('s'):
-				game.Chop(DirDown, 1)
-			case rune('a'):
-				game.Chop(DirLeft, 1)
-			case rune('Q'):
-				game.Dig(DirOmni)
-			case rune('W'):
-				game.Dig(DirUp)
-			case rune('D'):
-				game.Dig(DirRight)
-			case rune('S'):
-				game.Dig(DirDown)
-			case rune('A'):
-				game.Dig(DirLeft)
-			}
-		}
-	case *tcell.EventResize:
-		game.screen.Sync()
-	}
-
-	// Give the squirrel a destination if it doesn't alreasdy have one,
-	// or update its destination if it's blocked.
-	// FIXME determine why squirrels sometimes stop even when there seem to be nearby available plantable coordinates
-	for key, squirrel := range game.squirrels {
-		if (Coordinate{0, 0} == squirrel.destination) || game.IsPathBlocked(squirrel.destination) {
-			squirrel.destination = game.GetRandomPlantableCoordinate()
-			squirrel.path = game.FindPath(squirrel.position, squirrel.destination)
-		}
-
-		// If squirrel is one move away from its destination, then it plants the seed at the destination,
-		// i.e. one tile away, and then picks a new destination.
-		// Otherwise, it just moves towards its current destination.
-		if squirrel.IsAdjacentToDestination() && !game.IsPathBlocked(squirrel.destination) {
-			game.PlantSeed(squirrel.destination)
-			squirrel.destination = game.GetRandomPlantableCoordinate()
-		} else {
-			nextDirection := game.FindNextDirection(key)
-			if nextDirection == DirNone { // No path found, or on top of destination. Get a new one.
-				squirrel.destination = game.GetRandomPlantableCoordinate()
-			}
-			game.MoveSquirrel(1, nextDirection, key)
-			game.UpdatePath(key)
-		}
-	}
-
-	// Update trees.
-	game.GrowTrees()
-
-	// Update fire.
-	game.UpdateFire()
-	game.CheckFireDamage()
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func main() {
+	// Read settings from the settings file, and then let command line flags override them.
+	settingsFileName, _ := SettingsFilePath()
+	settings, err := LoadSettings(settingsFileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	// Add the user's own tiles, if any, to the built-in ones. Maps are read with them, so before flags choose one.
+	tilesFileName, _ := TilesFilePath()
+	if settings.tiles, err = LoadTiles(tilesFileName, settings.tiles); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	// The sim, serve and ssh subcommands play games without a screen of their own, and like join and spectate have flags of their own.
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	var simOptions SimOptions
+	var serveOptions ServeOptions
+	var joinOptions JoinOptions
+	var sshOptions SshOptions
+	switch command {
+	case SimCommand:
+		simOptions, err = ParseSimFlags(os.Args[2:], settings)
+	case ServeCommand:
+		serveOptions, err = ParseServeFlags(os.Args[2:], settings)
+	case JoinCommand:
+		joinOptions, err = ParseJoinFlags(os.Args[2:])
+	case SpectateCommand:
+		joinOptions, err = ParseSpectateFlags(os.Args[2:])
+	case SshCommand:
+		sshOptions, err = ParseSshFlags(os.Args[2:], settings)
+	default:
+		settings, err = ParseFlags(os.Args[1:], settings)
+	}
+	if err == nil && (command == JoinCommand || command == SpectateCommand) {
+		err = settings.Validate() // The other commands validate the settings once their flags are applied
+	}
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	// Bots play without a screen, one tick per action, and alone.
+	if settings.Bot {
+		settings.Players = 1
+		mapFile, err := FindMap(settings.MapSources(), settings.Map)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		game, err := NewGame(nil, nil, &settings, mapFile, settings.NewSeed(time.Now().UTC().UnixNano()))
+		if err == nil {
+			err = game.RunBot(os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == SimCommand {
+		output := os.Stdout
+		if simOptions.output != "" {
+			if output, err = os.Create(simOptions.output); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		}
+		err = RunSim(simOptions, output)
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == ServeCommand {
+		if err := Serve(serveOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == SshCommand {
+		if err := ServeSsh(sshOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load key bindings. Without a config directory the defaults are used and changes are not saved.
+	controlsFileName, _ := ControlsFilePath()
+	controls, err := LoadControls(controlsFileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// Browsers play with the same keys, but on a page of their own.
+	if settings.Web != "" {
+		if err := ServeWeb(settings, controls); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Screenshots are saved to the working directory. A recording is opened before the screen takes over the terminal,
+	// so that it can't fail once playing.
+	settings.ScreenshotDir = "."
+	if command == JoinCommand || command == SpectateCommand {
+		settings.Record = joinOptions.record
+	}
+	if settings.Record != "" {
+		file, err := os.Create(settings.Record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		settings.recorder = NewRecorder(file)
+	}
+
+	// Initialize tcell.
+	tcell.SetEncodingFallback(tcell.EncodingFallbackASCII)
+	screen, err := tcell.NewScreen()
+	if err == nil {
+		err = InitScreen(screen)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	// Network games are drawn like local ones, but played on the server.
+	if command == JoinCommand || command == SpectateCommand {
+		client, err := JoinServer(screen, &controls, &settings, joinOptions)
+		if err == nil {
+			err = client.Run()
+		}
+		screen.Fini()
+		if closeErr := settings.recorder.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	settings.SaveFile, _ = SaveFilePath()
+	settings.SettingsFile = settingsFileName
+	game, err := Play(screen, &controls, &settings)
+	screen.Fini()
+	if closeErr := settings.recorder.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Game over. Final score:", game.Score())
+}
+
+// Sets the default style, enables the mouse and clears the terminal.
+func InitScreen(screen tcell.Screen) error {
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	screen.SetStyle(tcell.StyleDefault)
+	screen.EnableMouse()
+	screen.Clear()
+
+	return nil
+}
+
+// Alternates between the title menu and the game until the player quits from the game, and returns the last game.
+// A map given in the settings skips the title menu for the first game.
+func Play(screen tcell.Screen, controls *Controls, settings *Settings) (Game, error) {
+	var game Game
+	retry := false                 // Whether to replay the last game instead of showing the title menu
+	titlePage := MainMenuPageOrder // Title menu page to start on
+	for {
+		if retry || settings.Map != "" {
+			// Replay the last map with the same seed, or start the map given in the settings.
+			mapName, seed := game.mapName, game.seed
+			if !retry {
+				mapName, seed = settings.Map, settings.NewSeed(time.Now().UTC().UnixNano())
+				settings.Map = ""
+			}
+			mapFile, err := FindMap(settings.MapSources(), mapName)
+			if err == nil {
+				game, err = NewGame(screen, controls, settings, mapFile, seed)
+			}
+			if err != nil {
+				return game, err
+			}
+		} else {
+			// Draw and handle menu inputs before initializing and drawing the game itself
+			titleMenu := GenerateTitleMenu(controls, settings) // Generate title menu
+			titleMenu.pageState = titlePage
+			titleMenu.Run(screen)
+			if titleMenu.quit {
+				return game, nil
+			}
+
+			// Initialize game state, either from a saved game or by reading a map.
+			var err error
+			if titleMenu.demo {
+				game, err = NewDemoGame(screen, controls, settings, time.Now().UTC().UnixNano())
+			} else if titleMenu.loadGame {
+				game, err = LoadGame(screen, controls, settings, settings.SaveFile)
+			} else {
+				game, err = NewGame(screen, controls, settings, titleMenu.selectedMap, settings.NewSeed(time.Now().UTC().UnixNano()))
+			}
+			if err != nil {
+				return game, err
+			}
+			game.events = titleMenu.unhandled
+		}
+
+		// Wait for Loop() goroutine to finish before moving on.
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go game.Ticker(&wg)
+		wg.Wait()
+
+		// Finished games are recorded as high scores, but not ones left for the title menu, or demos.
+		if game.demo {
+			screen.Clear()
+			continue
+		}
+		if game.endCause != EndCauseNone {
+			game.RecordHighScore()
+		}
+
+		// After dying, the results screen decides whether to play again.
+		retry = false
+		titlePage = MainMenuPageOrder
+		if game.endCause == EndCauseBurned {
+			switch game.ShowResults() {
+			case ResultsRetry:
+				retry = true
+				continue
+			case ResultsPickMap:
+				titlePage = NewGamePageOrder
+				continue
+			}
+		}
+
+		if !game.quitToTitle {
+			break
+		}
+		screen.Clear()
+	}
+
+	return game, nil
+}
+
+// Reads the map to set up a new game, and randomly seeds it with trees and grass.
+// The same map and seed always give the same starting trees and grass.
+func NewGame(screen tcell.Screen, controls *Controls, settings *Settings, mapFile MapFile, seed int64) (Game, error) {
+	worldContent, playerPosition, squirrelPositions, err := mapFile.Read(settings.tiles)
+	if err != nil {
+		return Game{}, err
+	}
+	squirrels := make(map[int]*Actor)
+	for index, position := range squirrelPositions {
+		squirrels[index] = &Actor{position: position, visionRadius: 100, score: 0, hitPointsCurrent: MaxHitPointsSquirrel, hitPointsMax: MaxHitPointsSquirrel}
+	}
+
+	var game Game
+	game.screen = screen
+	game.controls = controls
+	game.settings = settings
+	game.mapName = mapFile.fileName
+	game.mode = GameModeClassic
+	if settings.Players > 1 {
+		game.mode = settings.Mode
+	}
+	game.camera = settings.Camera
+	game.difficulty = settings.Difficulty
+	game.seed = seed
+	game.random = rand.New(rand.NewSource(seed))
+	game.rules = settings.Ruleset()
+	game.player = Actor{position: playerPosition, visionRadius: settings.VisionRadius, score: 0, hitPointsCurrent: game.rules.MaxHitPointsPlayer, hitPointsMax: game.rules.MaxHitPointsPlayer}
+	game.squirrels = squirrels
+	game.world = worldContent
+	game.menu = Menu{messages: []string{}}
+	game.exit = false
+	if screen != nil { // Simulated games have no screen
+		game.UpdateLayout()
+	}
+
+	// Randomly seed map with trees in various states.
+	game.PopulateTrees()
+	game.PopulateGrass()
+
+	// Other players start on the free tiles nearest to player one.
+	for i := 1; i < settings.Players; i++ {
+		player := game.player
+		player.position = game.SpawnPosition(playerPosition)
+		game.others = append(game.others, &player)
+	}
+
+	return game, nil
+}
+
+func NewWorld(width int, height int, worldContent map[Coordinate]interface{}) World {
+	_borders := make(map[Coordinate]int)
+
+	for c := range worldContent {
+		if border, isBorder := IsBorder(width, height, c); isBorder {
+			_borders[c] = border
+		}
+	}
+
+	return World{width, height, _borders, worldContent}
+}
+
+func (game *Game) Ticker(wg *sync.WaitGroup) {
+	// Initialize game update ticker.
+	ticker := time.NewTicker(time.Duration(game.settings.TickRate) * time.Millisecond)
+
+	// Update game state and re-draw on every tick.
+	for range ticker.C {
+		game.Draw()
+		game.Update()
+		if game.exit {
+			wg.Done()
+			return
+		}
+	}
+}
+
+func (game *Game) Update() {
+	// Listen for keyboard and mouse events for player actions,
+	// or terminal resizing events to re-draw the screen.
+	// The world only advances when the event was a player action.
+	ev := game.NextEvent()
+	if game.HandleEvent(ev) {
+		game.UpdateWorld()
+	}
+	game.QueueAutopilotStep() // In case the queue was full when last posted
+}
+
+// Returns the next input event: one read before the game started, like by the title menu, or else one from the screen.
+func (game *Game) NextEvent() tcell.Event {
+	if len(game.events) > 0 {
+		ev := game.events[0]
+		game.events = game.events[1:]
+		return ev
+	}
+
+	return game.screen.PollEvent()
+}
+
+// Handles an input event, and returns true if it was a player action that should advance the world by one tick.
+func (game *Game) HandleEvent(ev tcell.Event) bool {
+	if _, lost := ev.(*tcell.EventError); lost || ev == nil { // The terminal is gone, e.g. with a dropped SSH session
+		game.exit = true
+		return false
+	}
+
+	if game.demo && EndsDemo(ev) {
+		game.exit = true
+		game.quitToTitle = true
+		return false
+	}
+
+	if game.paused {
+		if interrupt, isInterrupt := ev.(*tcell.EventInterrupt); isInterrupt {
+			if _, isStep := interrupt.Data().(AutopilotStep); isStep {
+				game.stepQueued = false // Queued again once the game is resumed
+			}
+		}
+		game.HandlePauseEvent(ev)
+		if !game.paused {
+			game.QueueAutopilotStep()
+		}
+		return false
+	}
+
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			game.Pause()
+			return false
+		default:
+			action := game.controls.Action(ev)
+			if action == ActionNone { // Keys bound to nothing don't take up a tick either
+				return false
+			}
+			if action == ActionAutopilot || action == ActionHint || action == ActionScreenshot { // None of them takes up a tick
+				game.HandleAction(action)
+				game.QueueAutopilotStep()
+				return false
+			}
+			if player, _ := game.PlayerAction(action); player != nil {
+				player.path = nil                             // Keyboard input interrupts walking to a clicked tile
+				if player == &game.player && game.autopilot { // The player takes over
+					game.HandleAction(ActionAutopilot)
+				}
+			}
+			game.HandleAction(action)
+		}
+	case *tcell.EventMouse:
+		return game.HandleMouseEvent(ev)
+	case *tcell.EventInterrupt:
+		if _, isStep := ev.Data().(AutopilotStep); isStep {
+			return game.StepAutopilot()
+		}
+		return game.WalkPlayers()
+	case *tcell.EventResize:
+		game.screen.Sync()
+		game.UpdateLayout()
+		game.renderer.Invalidate()
+		return false
+	}
+
+	return true
+}
+
+// Advances the world by one tick.
+func (game *Game) UpdateWorld() {
+	if game.ticks%ForestCoverSampleRate == 0 {
+		game.stats.forestCover = append(game.stats.forestCover, game.ForestCover())
+	}
+	game.ticks++
+
+	// Give the squirrel a destination if it doesn't alreasdy have one,
+	// or update its destination if it's blocked.
+	// FIXME determine why squirrels sometimes stop even when there seem to be nearby available plantable coordinates
+	for _, key := range game.SquirrelKeys() {
+		squirrel := game.squirrels[key]
+		if (Coordinate{0, 0} == squirrel.destination) || game.IsPathBlocked(squirrel.destination) {
+			squirrel.destination = game.GetRandomPlantableCoordinate()
+			squirrel.path = game.FindPath(squirrel.position, squirrel.destination)
+		}
+
+		// If squirrel is one move away from its destination, then it plants the seed at the destination,
+		// i.e. one tile away, and then picks a new destination.
+		// Otherwise, it just moves towards its current destination.
+		if squirrel.IsAdjacentToDestination() && !game.IsPathBlocked(squirrel.destination) {
+			game.PlantSeed(squirrel.destination)
+			squirrel.destination = game.GetRandomPlantableCoordinate()
+		} else {
+			nextDirection := game.FindNextDirection(key)
+			if nextDirection == DirNone { // No path found, or on top of destination. Get a new one.
+				squirrel.destination = game.GetRandomPlantableCoordinate()
+			}
+			game.MoveSquirrel(1, nextDirection, key)
+			game.UpdatePath(key)
+		}
+	}
+
+	// Update trees.
+	game.GrowTrees()
+
+	// Update fire.
+	game.UpdateFire()
+	game.CheckFireDamage()
+}
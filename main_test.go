@@ -1,203 +1,250 @@
-package main
-
-import (
-	"testing"
-)
-
-func TestPlaceholder(t *testing.T) {
-	if 1 == 2 {
-		t.Errorf("uh oh")
-	}
-}
-
-// // Helper function for comparing closeness of float64 values.
-// // Returns true if the difference between two numbers is within a small threshold value,
-// // and false otherwise.
-// func EqualFloats(a, b float64) bool {
-// 	return math.Abs(a-b) <= 1e-10
-// }
-
-// func TestPoint(t *testing.T) {
-// 	type test struct {
-// 		x     float64
-// 		y     float64
-// 		point Point
-// 	}
-
-// 	tests := []test{
-// 		{x: 0, y: 0, point: Point{x: 0, y: 0}},
-// 		{x: 1, y: 0, point: Point{x: 1, y: 0}},
-// 		{x: 0, y: 1, point: Point{x: 0, y: 1}},
-// 		{x: 2, y: 2, point: Point{x: 2, y: 2}},
-// 		{x: 2, y: -5.7, point: Point{x: 2, y: -5.7}},
-// 		{x: 2.31, y: -2, point: Point{x: 2.31, y: -2}},
-// 	} // FIXME dynamically generate Points instead of writing values twice.
-
-// 	for _, testCase := range tests {
-// 		if reflect.TypeOf(testCase.point).Name() != "Point" {
-// 			t.Errorf("p is not of type Point, got type %s", reflect.TypeOf(testCase.point).Name())
-// 		}
-
-// 		if !EqualFloats(testCase.point.x, testCase.x) {
-// 			t.Errorf("p does not have x value %f, got value %f", testCase.x, testCase.point.x)
-// 		}
-
-// 		if !EqualFloats(testCase.point.y, testCase.y) {
-// 			t.Errorf("p does not have y value %f, got value %f", testCase.y, testCase.point.y)
-// 		}
-// 	}
-// }
-
-// func TestVector(t *testing.T) {
-// 	type test struct {
-// 		x      float64
-// 		y      float64
-// 		vector Vector
-// 	}
-
-// 	tests := []test{
-// 		{x: 0, y: 0, vector: Vector{x: 0, y: 0}},
-// 		{x: 1, y: 0, vector: Vector{x: 1, y: 0}},
-// 		{x: 0, y: 1, vector: Vector{x: 0, y: 1}},
-// 		{x: 2, y: 2, vector: Vector{x: 2, y: 2}},
-// 		{x: 2, y: -5.7, vector: Vector{x: 2, y: -5.7}},
-// 		{x: 2.31, y: -2, vector: Vector{x: 2.31, y: -2}},
-// 	} // FIXME dynamically generate Points instead of writing values twice.
-
-// 	for _, testCase := range tests {
-// 		if reflect.TypeOf(testCase.vector).Name() != "Vector" {
-// 			t.Errorf("v is not of type Vector, got type %s", reflect.TypeOf(testCase.vector).Name())
-// 		}
-
-// 		if !EqualFloats(testCase.vector.x, testCase.x) {
-// 			t.Errorf("v does not have x value %f, got value %f", testCase.x, testCase.vector.x)
-// 		}
-
-// 		if !EqualFloats(testCase.vector.y, testCase.y) {
-// 			t.Errorf("v does not have y value %f, got value %f", testCase.y, testCase.vector.y)
-// 		}
-// 	}
-// }
-// func TestToVector(t *testing.T) {
-// 	tests := []Point{
-// 		{x: 0, y: 0},
-// 		{x: 1, y: 0},
-// 		{x: 0, y: 1},
-// 		{x: 2, y: 2},
-// 		{x: 2, y: -5.7},
-// 		{x: 2.31, y: -2},
-// 	} // FIXME dynamically generate Points instead of writing values twice.
-
-// 	for _, testCase := range tests {
-// 		got := testCase.ToVector()
-
-// 		if reflect.TypeOf(got).Name() != "Vector" {
-// 			t.Errorf("v1 is not of type Vector, got type %s", reflect.TypeOf(got).Name())
-// 		}
-
-// 		if !EqualFloats(got.x, testCase.x) {
-// 			t.Errorf("v1 does not have x value %f, got value %f", testCase.x, got.x)
-// 		}
-
-// 		if !EqualFloats(got.y, testCase.y) {
-// 			t.Errorf("v1 does not have y value %f, got value %f", testCase.y, got.y)
-// 		}
-
-// 	}
-// }
-
-// func TestTranslate(t *testing.T) {
-// 	type test struct {
-// 		point  Point
-// 		vector Vector
-// 		want   Point
-// 	}
-
-// 	tests := []test{
-// 		{point: Point{x: 0, y: 0}, vector: Vector{x: 0, y: 0}, want: Point{x: 0, y: 0}},
-// 		{point: Point{x: 0, y: 0}, vector: Vector{x: 1, y: 0}, want: Point{x: 1, y: 0}},
-// 		{point: Point{x: 0, y: 0}, vector: Vector{x: 1, y: 7.2}, want: Point{x: 1, y: 7.2}},
-// 	}
-
-// 	for _, testCase := range tests {
-// 		got := testCase.point.Translate(&testCase.vector)
-
-// 		if !EqualFloats(testCase.want.x, got.x) {
-// 			t.Errorf("point does not have x value %v, got value %v instead", testCase.want.x, got.x)
-// 		}
-
-// 		if !EqualFloats(testCase.want.y, got.y) {
-// 			t.Errorf("point does not have y value %v, got value %v instead", testCase.want.y, got.y)
-// 		}
-// 	}
-// }
-
-// func TestLen(t *testing.T) {
-// 	type test struct {
-// 		vector Vector
-// 		len    float64
-// 	}
-
-// 	tests := []test{
-// 		{vector: Vector{x: 0, y: 0}, len: 0},
-// 		{vector: Vector{x: 1, y: 0}, len: 1},
-// 		{vector: Vector{x: 0, y: 2.5}, len: 2.5},
-// 		{vector: Vector{x: -7.9, y: -7.9}, len: math.Sqrt(124.82)},
-// 	}
-
-// 	for _, testCase := range tests {
-// 		got := testCase.vector.Len()
-
-// 		if !EqualFloats(got, testCase.len) {
-// 			t.Errorf("vector does not have length %f, got %f instead", testCase.len, got)
-// 		}
-// 	}
-// }
-
-// func TestDir(t *testing.T) {
-// 	type test struct {
-// 		vector Vector
-// 		dir    float64
-// 	}
-
-// 	tests := []test{
-// 		{vector: Vector{x: 0, y: 0}, dir: 0},
-// 		{vector: Vector{x: 1, y: 0}, dir: 0},
-// 		{vector: Vector{x: 0, y: 1}, dir: math.Pi / 2},
-// 		{vector: Vector{x: -7.9, y: -7.9}, dir: 5 * math.Pi / 4},
-// 	}
-
-// 	for _, testCase := range tests {
-// 		got := testCase.vector.Dir()
-
-// 		if !EqualFloats(got, testCase.dir) {
-// 			t.Errorf("vector does not have direction %f, got %f instead", testCase.dir, got)
-// 		}
-// 	}
-// }
-
-// func TestAdd(t *testing.T) {
-// 	type test struct {
-// 		vector1 Vector
-// 		vector2 Vector
-// 		want    Vector
-// 	}
-
-// 	tests := []test{
-// 		{vector1: Vector{x: 0, y: 0}, vector2: Vector{x: 0, y: 0}, want: Vector{x: 0, y: 0}},
-// 		{vector1: Vector{x: 0, y: 0}, vector2: Vector{x: 1, y: 0}, want: Vector{x: 1, y: 0}},
-// 		{vector1: Vector{x: 0, y: 0}, vector2: Vector{x: 1, y: 7.2}, want: Vector{x: 1, y: 7.2}},
-// 	}
-
-// 	for _, testCase := range tests {
-// 		got := testCase.vector1.Add(&testCase.vector2)
-
-// 		if !EqualFloats(testCase.want.x, got.x) {
-// 			t.Errorf("vector does not have x value %f, got value %f instead", testCase.want.x, got.x)
-// 		}
-
-// 		if !EqualFloats(testCase.want.y, got.y) {
-// 			t.Errorf("vector does not have y value %f, got value %f instead", testCase.want.y, got.y)
-// 		}
-// 	}
-// }
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden frames in testdata/golden instead of comparing against them")
+
+// Characters for placing trees in test maps. Trees have no map characters of their own, since maps never contain them.
+var goldenTrees = map[rune]int{
+	'T': TreeStateAdult,
+	't': TreeStateSapling,
+	',': TreeStateSeed,
+	'u': TreeStateStump,
+}
+
+// Builds a game from an inline map on a simulation screen of the given size.
+// Besides the usual map characters, the map can place trees with the characters in goldenTrees and grass with g.
+func newGoldenGame(t *testing.T, width int, height int, karta string) (*Game, tcell.SimulationScreen) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(screen.Fini)
+	screen.SetSize(width, height)
+
+	settings := DefaultSettings()
+	world, playerPosition, squirrelPositions, err := ParseMap([]byte(karta), settings.tiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y, line := range strings.Split(karta, "\n") {
+		for x, char := range []rune(line) {
+			coord := Coordinate{x, y}
+			if state, isTree := goldenTrees[char]; isTree {
+				world.content[coord] = &Tree{coord, state}
+			} else if char == 'g' {
+				world.content[coord] = NewObject(settings.tiles, KeyGrassLight)
+			}
+		}
+	}
+
+	game := &Game{screen: screen, random: rand.New(rand.NewSource(1)), settings: &settings, rules: rulesetPresets[DifficultyNormal], world: world, menu: Menu{messages: []string{}}}
+	game.player = Actor{position: playerPosition, visionRadius: 100, hitPointsCurrent: MaxHitPointsPlayer, hitPointsMax: MaxHitPointsPlayer}
+	game.squirrels = make(map[int]*Actor)
+	for index, position := range squirrelPositions {
+		game.squirrels[index] = &Actor{position: position, visionRadius: 100, hitPointsCurrent: MaxHitPointsSquirrel, hitPointsMax: MaxHitPointsSquirrel}
+	}
+	game.UpdateLayout()
+
+	return game, screen
+}
+
+// Renders the viewport and the HUD, and returns the screen as text: the characters, then a letter per cell
+// for its style, then what each letter stands for. Cells in the default style are shown as dots.
+func renderGoldenFrame(game *Game, screen tcell.SimulationScreen) string {
+	w, h := screen.Size()
+	game.renderer.Begin(w, h)
+	game.DrawViewport()
+	game.DrawMenu()
+	game.renderer.Flush(screen)
+	screen.Show()
+
+	cells, width, height := screen.GetContents()
+	var chars, styles, legend strings.Builder
+	letters := make(map[tcell.Style]rune)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			cell := cells[y*width+x]
+			char := ' '
+			if len(cell.Runes) > 0 {
+				char = cell.Runes[0]
+			}
+			chars.WriteRune(char)
+
+			if cell.Style == tcell.StyleDefault {
+				styles.WriteRune('.')
+				continue
+			}
+			letter, found := letters[cell.Style]
+			if !found {
+				letter = rune('a' + len(letters))
+				letters[cell.Style] = letter
+				fg, bg, attributes := cell.Style.Decompose()
+				fmt.Fprintf(&legend, "%c: fg %s, bg %s", letter, colorName(fg), colorName(bg))
+				if attributes&tcell.AttrBold != 0 {
+					legend.WriteString(", bold")
+				}
+				legend.WriteString("\n")
+			}
+			styles.WriteRune(letter)
+		}
+		chars.WriteRune('\n')
+		styles.WriteRune('\n')
+	}
+
+	return chars.String() + "\n" + styles.String() + "\n" + legend.String()
+}
+
+func colorName(color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return "default"
+	}
+	name := ""
+	for colorName, named := range tcell.ColorNames {
+		if named == color && (name == "" || colorName < name) { // Some colours have several names
+			name = colorName
+		}
+	}
+	if name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("#%06x", color.Hex())
+}
+
+// Compares the frame with the golden file of the given name, or rewrites the file when testing with -update.
+func checkGoldenFrame(t *testing.T, name string, frame string) {
+	fileName := filepath.Join("testdata", "golden", name+".txt")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(frame), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if string(want) != frame {
+		t.Errorf("frame differs from %s (run go test -update to accept it)\nwant:\n%s\ngot:\n%s", fileName, want, frame)
+	}
+}
+
+func TestGoldenFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		karta  string
+		setup  func(game *Game) // Changes to the game before rendering, if any
+	}{
+		{
+			// Borders along the edges of the map, and walls inside it.
+			name:  "borders",
+			width: 80, height: 12,
+			karta: "" +
+				"##########\n" +
+				"#        #\n" +
+				"#  ##  p #\n" +
+				"#  #     #\n" +
+				"#        #\n" +
+				"##########",
+		},
+		{
+			// Adult trees have leaves over the row above the trunk, which cover the player and squirrels.
+			// Grass under squirrels is hidden.
+			name:  "canopy",
+			width: 80, height: 12,
+			karta: "" +
+				"###########\n" +
+				"#   s  p  #\n" +
+				"#   T  T t#\n" +
+				"#  gs   u,#\n" +
+				"#wW   f   #\n" +
+				"###########",
+			setup: func(game *Game) {
+				for _, squirrel := range game.squirrels {
+					if squirrel.position == (Coordinate{4, 3}) {
+						squirrel.position = Coordinate{3, 3} // Onto the grass
+					}
+				}
+			},
+		},
+		{
+			// Narrow screens get the HUD along the bottom, in columns.
+			name:  "hud-bottom",
+			width: 44, height: 14,
+			karta: "" +
+				"#######\n" +
+				"#  p  #\n" +
+				"#  T  #\n" +
+				"#######",
+		},
+		{
+			// Split screens give each player half of the viewport, following them, with their own colour.
+			name:  "split-screen",
+			width: 80, height: 12,
+			karta: "" +
+				"##############\n" +
+				"# p  T    f  #\n" +
+				"#   g    T   #\n" +
+				"##############",
+			setup: func(game *Game) {
+				addPlayer(game, Coordinate{10, 2}, GameModeCoop, CameraSplit)
+			},
+		},
+		{
+			// A shared camera is centered between the players, and the HUD lists each player.
+			name:  "shared-camera",
+			width: 80, height: 12,
+			karta: "" +
+				"##############\n" +
+				"# p  T    f  #\n" +
+				"#   g    T   #\n" +
+				"##############",
+			setup: func(game *Game) {
+				addPlayer(game, Coordinate{11, 2}, GameModeVersus, CameraShared)
+			},
+		},
+		{
+			// A spectator sees the whole world around their free camera, beyond the player's vision,
+			// with the minimap in the corner marking the middle of the view.
+			name:  "spectator",
+			width: 80, height: 12,
+			karta: "" +
+				"##############\n" +
+				"# p  T    f  #\n" +
+				"#   g  s T   #\n" +
+				"##############",
+			setup: func(game *Game) {
+				game.player.visionRadius = 1
+				game.names = []string{"Anna"}
+				game.spectator = &Spectator{center: Coordinate{8, 2}, minimap: true}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, screen := newGoldenGame(t, test.width, test.height, test.karta)
+			if test.setup != nil {
+				test.setup(game)
+			}
+			checkGoldenFrame(t, test.name, renderGoldenFrame(game, screen))
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A scenario is a map, then a line of dashes, then a script with one statement per line.
+// The map can place trees and grass like the golden frame tests. Statements are:
+//
+//	seed N                   seed the game's random number generator (the default is 1)
+//	rules {JSON}             change rules of the normal difficulty, using the keys of settings.json (fires only spawn on their own if set here)
+//	place ID X,Y [X2,Y2]     put the tile with the given id at a coordinate, or over a rectangle
+//	fire X,Y                 set fire to a coordinate
+//	plant X,Y                plant a seed at a coordinate, like a squirrel does
+//	do ACTION...             let the player do each action, one tick each, using the action names of controls.json
+//	wait N                   let N ticks pass without the player doing anything
+//	expect tree X,Y STATE    tree state name as shown to the player, or none
+//	expect tile X,Y ID       tile id of the object at the coordinate
+//	expect fire X,Y [X2,Y2]  fire at the coordinate, or everywhere in the rectangle
+//	expect nofire X,Y [X2,Y2]
+//	expect player X,Y
+//	expect hp N
+//	expect score N
+//	expect squirrels N
+//
+// Blank lines and lines starting with # are ignored.
+func runScenario(t *testing.T, scenario string) *Game {
+	t.Helper()
+	karta, script, found := strings.Cut(scenario, "\n---\n")
+	if !found {
+		t.Fatal("scenario has no line of dashes between the map and the script")
+	}
+
+	game, _ := newGoldenGame(t, 80, 24, strings.TrimPrefix(karta, "\n"))
+	game.rules.FireSpawnChance = 0
+
+	for i, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := runStatement(game, line); err != nil {
+			t.Fatalf("line %d, %q: %v", i+1, line, err)
+		}
+	}
+
+	return game
+}
+
+// Runs one statement of a scenario script. Failed expectations are returned as errors too.
+func runStatement(game *Game, line string) error {
+	command, args, _ := strings.Cut(line, " ")
+	fields := strings.Fields(args)
+	switch command {
+	case "seed":
+		seed, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			return err
+		}
+		game.random = rand.New(rand.NewSource(seed))
+	case "rules":
+		return json.Unmarshal([]byte(args), &game.rules)
+	case "place":
+		if len(fields) < 2 {
+			return fmt.Errorf("expected a tile id and a coordinate")
+		}
+		key, found := TileKeyById(game.settings.tiles, fields[0])
+		if !found {
+			return fmt.Errorf("unknown tile %q", fields[0])
+		}
+		return forEachCoordinate(fields[1:], func(coord Coordinate) error {
+			game.world.content[coord] = NewObject(game.settings.tiles, key)
+			return nil
+		})
+	case "fire":
+		return forEachCoordinate(fields, func(coord Coordinate) error {
+			game.world.content[coord] = NewFire(coord, 1)
+			return nil
+		})
+	case "plant":
+		return forEachCoordinate(fields, func(coord Coordinate) error {
+			game.PlantSeed(coord)
+			return nil
+		})
+	case "do":
+		for _, name := range fields {
+			action, found := ActionByName(name)
+			if !found {
+				return fmt.Errorf("unknown action %q", name)
+			}
+			game.HandleAction(action)
+			game.UpdateWorld()
+		}
+	case "wait":
+		ticks, err := strconv.Atoi(args)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < ticks; i++ {
+			game.UpdateWorld()
+		}
+	case "expect":
+		return checkExpectation(game, fields)
+	default:
+		return fmt.Errorf("unknown statement %q", command)
+	}
+
+	return nil
+}
+
+func checkExpectation(game *Game, fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("expected what to check and a value")
+	}
+
+	switch fields[0] {
+	case "tree":
+		coord, err := parseCoordinate(fields[1])
+		if err != nil {
+			return err
+		}
+		want := strings.Join(fields[2:], " ")
+		got := "none"
+		if tree, isTree := game.world.content[coord].(*Tree); isTree {
+			got = treeStateNames[tree.state]
+		}
+		if got != want {
+			return fmt.Errorf("expected tree at %v to be %s, got %s", coord, want, got)
+		}
+	case "tile":
+		coord, err := parseCoordinate(fields[1])
+		if err != nil || len(fields) != 3 {
+			return fmt.Errorf("expected a coordinate and a tile id")
+		}
+		object, isObject := game.world.content[coord].(Object)
+		if !isObject || game.settings.tiles[object.key].id != fields[2] {
+			return fmt.Errorf("expected %s at %v, got %s", fields[2], coord, game.DescribeTile(coord))
+		}
+	case "fire", "nofire":
+		return forEachCoordinate(fields[1:], func(coord Coordinate) error {
+			if _, isFire := game.world.content[coord].(*Fire); isFire != (fields[0] == "fire") {
+				return fmt.Errorf("expected %s at %v, got %s", fields[0], coord, game.DescribeTile(coord))
+			}
+			return nil
+		})
+	case "player":
+		coord, err := parseCoordinate(fields[1])
+		if err != nil {
+			return err
+		}
+		if game.player.position != coord {
+			return fmt.Errorf("expected player at %v, got %v", coord, game.player.position)
+		}
+	case "hp", "score", "squirrels":
+		want, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		got := map[string]int{"hp": game.player.hitPointsCurrent, "score": game.player.score, "squirrels": len(game.squirrels)}[fields[0]]
+		if got != want {
+			return fmt.Errorf("expected %s %d, got %d", fields[0], want, got)
+		}
+	default:
+		return fmt.Errorf("unknown expectation %q", fields[0])
+	}
+
+	return nil
+}
+
+// Parses a coordinate written as X,Y.
+func parseCoordinate(text string) (Coordinate, error) {
+	var coord Coordinate
+	if _, err := fmt.Sscanf(text, "%d,%d", &coord.x, &coord.y); err != nil {
+		return coord, fmt.Errorf("bad coordinate %q", text)
+	}
+
+	return coord, nil
+}
+
+// Calls f for a single coordinate, or for every coordinate of the rectangle between two corners.
+func forEachCoordinate(fields []string, f func(Coordinate) error) error {
+	if len(fields) != 1 && len(fields) != 2 {
+		return fmt.Errorf("expected a coordinate or two corners of a rectangle")
+	}
+	from, err := parseCoordinate(fields[0])
+	if err != nil {
+		return err
+	}
+	to := from
+	if len(fields) == 2 {
+		if to, err = parseCoordinate(fields[1]); err != nil {
+			return err
+		}
+	}
+
+	for x := from.x; x <= to.x; x++ {
+		for y := from.y; y <= to.y; y++ {
+			if err := f(Coordinate{x, y}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func TestScenarioChopping(t *testing.T) {
+	game := runScenario(t, `
+#######
+#pT   #
+#t    #
+#######
+---
+rules {"growthChanceSeed": 0}
+
+# Adult trees go through trunk and stump before they are gone.
+do ChopRight
+expect tree 2,1 Felled tree trunk
+do ChopRight
+expect tree 2,1 Stump
+expect score 0
+do ChopRight
+expect tree 2,1 none
+expect score 1
+
+# Saplings leave a sapling stump.
+do ChopDown
+expect tree 1,2 Sapling stump
+do ChopDown
+expect tree 1,2 none
+expect score 2
+`)
+	if game.player.inventory.wood != 2 {
+		t.Errorf("expected 2 wood, got %d", game.player.inventory.wood)
+	}
+}
+
+func TestScenarioCollision(t *testing.T) {
+	runScenario(t, `
+######
+#pT  #
+#w   #
+######
+---
+do MoveLeft MoveUp MoveRight MoveDown
+expect player 1,1
+do ChopRight ChopRight ChopRight MoveRight
+expect player 2,1
+do MoveDown MoveLeft
+expect player 2,2
+`)
+}
+
+func TestScenarioDigging(t *testing.T) {
+	runScenario(t, `
+#######
+#     #
+# pT  #
+#     #
+#######
+---
+fire 2,1
+do DigUp
+expect tile 2,1 firebreak
+expect nofire 2,1
+
+# Trees and walls can't be dug up.
+do DigRight
+expect tree 3,2 Adult tree
+do MoveDown DigDown
+expect tile 2,4 wall
+do DigLeft
+expect tile 1,3 firebreak
+`)
+}
+
+func TestScenarioFireStopsAtFirebreak(t *testing.T) {
+	runScenario(t, `
+############
+#          #
+#  f     p #
+#          #
+############
+---
+rules {"fireSpreadChance": 1, "fireBurnoutHalflife": 10000}
+place firebreak 6,1 6,3
+seed 7
+wait 100
+expect fire 3,2
+expect tile 6,1 firebreak
+expect tile 6,3 firebreak
+expect nofire 7,1 10,3
+expect hp 3
+`)
+}
+
+func TestScenarioFireDamage(t *testing.T) {
+	game := runScenario(t, `
+#########
+#p    #s#
+#     #f#
+#########
+---
+rules {"fireSpreadChance": 1, "fireBurnoutHalflife": 10000}
+
+# The squirrel is trapped next to fire, and dies as soon as it spreads onto it.
+expect squirrels 1
+wait 40
+expect squirrels 0
+expect hp 3
+
+fire 1,1
+wait 1
+expect hp 2
+wait 1
+expect hp 1
+`)
+	if game.stats.squirrelsLost != 1 {
+		t.Errorf("expected the squirrel to be counted as lost, got %d", game.stats.squirrelsLost)
+	}
+
+	runStatement(game, "wait 1")
+	if !game.exit || game.endCause != EndCauseBurned {
+		t.Errorf("player at 0 HP should end the game")
+	}
+}
+
+func TestScenarioPlantingAndGrowth(t *testing.T) {
+	runScenario(t, `
+#######
+#p T  #
+#######
+---
+rules {"growthChanceSeed": 0}
+plant 2,1
+expect tree 2,1 Seed
+
+# Seeds can't be planted on walls or trees.
+plant 0,0
+expect tile 0,0 wall
+plant 3,1
+expect tree 3,1 Adult tree
+
+rules {"growthChanceSeed": 1}
+wait 1
+expect tree 2,1 Sapling
+wait 1
+expect tree 2,1 Adult tree
+`)
+}
+
+// Fire spreads as far in every direction over grass, whatever order the fires are handled in.
+func TestFireSpreadIsSymmetric(t *testing.T) {
+	const size, ticks, seeds = 31, 15, 200
+	karta := strings.Repeat("#", size) + "\n#p" + strings.Repeat("g", size-3) + "#\n" +
+		strings.Repeat("#"+strings.Repeat("g", size-2)+"#\n", size-3) + strings.Repeat("#", size)
+	center := Coordinate{size / 2, size / 2}
+	game, _ := newGoldenGame(t, 80, 24, karta)
+	game.rules.FireSpawnChance = 0
+	game.rules.FireSpreadChance = 0.5
+	grass := game.world.content
+
+	var left, right, up, down int
+	for seed := int64(1); seed <= seeds; seed++ {
+		game.random = rand.New(rand.NewSource(seed))
+		game.world.content = make(map[Coordinate]any)
+		for coord, content := range grass {
+			game.world.content[coord] = content
+		}
+		game.world.content[center] = NewFire(center, 1)
+		for i := 0; i < ticks; i++ {
+			game.UpdateFire()
+		}
+
+		for coord, content := range game.world.content {
+			_, isFire := content.(*Fire)
+			if object, isObject := content.(Object); !isFire && !(isObject && object.key == KeyBurnt) {
+				continue
+			}
+			switch {
+			case coord.x < center.x:
+				left++
+			case coord.x > center.x:
+				right++
+			}
+			switch {
+			case coord.y < center.y:
+				up++
+			case coord.y > center.y:
+				down++
+			}
+		}
+	}
+
+	for _, sides := range [][2]int{{left, right}, {up, down}} {
+		if difference := sides[0] - sides[1]; difference*10 > sides[0]+sides[1] || -difference*10 > sides[0]+sides[1] {
+			t.Errorf("expected fire to spread about as far either way, got left %d, right %d, up %d, down %d", left, right, up, down)
+			break
+		}
+	}
+}
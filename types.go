@@ -1,6 +1,17 @@
 package main
 
-import "github.com/gdamore/tcell"
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/ssh"
+)
 
 type Coordinate struct {
 	x int
@@ -15,6 +26,12 @@ type Actor struct {
 	score            int
 	hitPointsCurrent int
 	hitPointsMax     int
+	inventory        Inventory
+}
+
+type Inventory struct {
+	wood  int // Collected from felling saplings and adult trees
+	seeds int // Collected from removing seeds
 }
 
 type Tree struct {
@@ -24,7 +41,9 @@ type Tree struct {
 
 type Fire struct {
 	position Coordinate
-	age      int // Number of game update ticks since fire was created
+	age      int     // Number of game update ticks since fire was created
+	phase    int     // Offset into the animation, so that neighbouring fires flicker out of step
+	fuel     float64 // Fuel of the tile that is burning, which stretches how long the fire lasts
 }
 
 type Object struct {
@@ -42,46 +61,244 @@ type World struct {
 }
 
 type Game struct {
-	screen    tcell.Screen
-	player    Actor
-	squirrels map[int]*Actor
-	world     World
-	menu      Menu
-	exit      bool
+	screen      tcell.Screen
+	events      []tcell.Event // Events read before the game started, like by the title menu, handled before polling the screen
+	controls    *Controls
+	settings    *Settings
+	mapName     string
+	mode        string // One of the game mode constants, high scores are kept separately for each
+	difficulty  string // One of the difficulty constants
+	rules       Ruleset
+	seed        int64      // Seed for the randomizer when the game was created, so it can be retried
+	random      *rand.Rand // Source of everything random in the game, so that games running side by side don't affect each other
+	player      Actor
+	others      []*Actor // Players besides the first in a local multiplayer game, each with their own keys
+	camera      string   // One of the camera constants, for how several players are shown
+	names       []string // Names of the players in the order of Players() in a network game, or empty for local games
+	squirrels   map[int]*Actor
+	world       World
+	renderer    Renderer
+	viewport    Viewport
+	menu        Menu
+	mouse       Mouse
+	ticks       int // Number of game update ticks since the game started
+	paused      bool
+	pauseMenu   PauseMenu
+	stats       Stats
+	exit        bool
+	endCause    int        // Why the game exited, one of the end cause constants
+	rank        int        // Place in the high score table once the game is over, or 0 if it did not make the table
+	rankErr     error      // Why the game could not be recorded in the high score table
+	quitToTitle bool       // Whether to return to the title menu instead of quitting once the game exits
+	autopilot   bool       // Whether the autopilot plays instead of the player
+	stepQueued  bool       // Whether an AutopilotStep interrupt is waiting to be handled
+	demo        bool       // Whether the game is a demo on the title screen, which any input ends
+	spectator   *Spectator // Free camera of a client watching a network game without playing, or nil when playing
+}
+
+type Spectator struct {
+	center    Coordinate        // World coordinate in the middle of the view
+	following bool              // Whether the camera follows the target, instead of staying where it was moved to
+	chosen    bool              // Whether the spectator chose the target, instead of following whoever comes first
+	target    SpectatorTarget   // Actor followed, or last followed
+	targets   []SpectatorTarget // Actors to follow as of the latest state, players before squirrels
+	minimap   bool
+}
+
+type SpectatorTarget struct {
+	squirrel bool // Whether the id is a squirrel's, instead of a player's
+	id       int
+	name     string
+	position Coordinate
+}
+
+type AutopilotStep struct{} // Interrupt data for the autopilot to take its next action
+
+type Stats struct {
+	chopped           map[int]int // Number of times trees were chopped, keyed by the state they were chopped from
+	firebreaksDug     int
+	firesStarted      int // Fires spawned or spread to a new tile
+	firesBurntOut     int
+	firesExtinguished int // Fires put out by digging a firebreak on them
+	squirrelsLost     int
+	forestCover       []float64 // Sampled every ForestCoverSampleRate ticks
+}
+
+type PauseMenu struct {
+	page   int // One of the pause menu page constants
+	cursor int
+	status string // Shown below the menu items, e.g. to confirm saving
+}
+
+type Renderer struct {
+	width    int
+	height   int
+	current  []Cell // Frame being built
+	previous []Cell // Frame last written to the screen
+	full     bool   // Write every cell on the next flush instead of only changed ones
+}
+
+type Cell struct {
+	char  rune
+	style tcell.Style
+}
+
+type Mouse struct {
+	position Coordinate       // Screen coordinate of the pointer
+	hovering bool             // Whether the pointer is over the viewport
+	buttons  tcell.ButtonMask // Buttons held down at the last mouse event, to tell clicks from drags
+}
+
+type Viewport struct {
+	width    int
+	height   int
+	position Coordinate // Top-left screen coordinate
+}
+
+type Camera struct {
+	viewport Viewport   // Part of the screen the camera draws to
+	center   Coordinate // World coordinate shown in the middle of the viewport
+	viewers  []*Actor   // Players whose vision the camera shows
 }
 
 type Menu struct {
 	width    int
 	height   int
-	position Coordinate
+	position Coordinate // Top-left screen coordinate
+	layout   int        // HudLayoutSide or HudLayoutBottom
 	messages []string
 }
 
-type Symbol struct {
+type HudLine struct {
+	label    string
+	segments []HudSegment
+}
+
+type HudSegment struct {
+	text  string
+	style tcell.Style
+}
+
+type Tile struct {
+	id         string // Identifies the tile in tiles.json and saved games
+	name       string // Shown when hovering over the tile with the mouse
 	char       rune
 	aboveActor bool
 	style      tcell.Style
+	collidable bool    // Are actors blocked
+	flammable  bool    // Can fire spread here
+	plantable  bool    // Can seeds be planted here
+	fuel       float64 // How long fire burns here, relative to bare ground
+	mapChar    rune    // Character placing the tile in map files, or 0 if it can't be placed
+	scatter    int     // Weight for randomly scattering the tile over new maps, like grass
+}
+
+type MapSource struct {
+	name  string // Shown next to maps from this source
+	fsys  fs.FS
+	tiles map[int]Tile // Registry the map characters are read with
 }
 
-type GrowthInfo struct {
-	newState int
-	chance   float64
+type MapFile struct {
+	fileName string // Identifies the map in saved games and high scores
+	source   string
+	data     []byte
+	metadata MapMetadata
 }
 
+type MapPreview struct {
+	world     World
+	players   map[Coordinate]bool
+	squirrels map[Coordinate]bool
+	water     float64 // Fraction of the tiles inside the borders that are water
+	metadata  MapMetadata
+	tiles     map[int]Tile // Registry the map was read with
+}
+
+type MapMetadata struct {
+	name        string
+	author      string
+	description string
+}
+
+type TileDefinition struct {
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	Glyph      string  `json:"glyph"`
+	Foreground string  `json:"fg"` // tcell colour name or #rrggbb
+	Background string  `json:"bg"`
+	AboveActor bool    `json:"aboveActor"`
+	Collidable bool    `json:"collidable"`
+	Flammable  bool    `json:"flammable"`
+	Plantable  bool    `json:"plantable"`
+	Fuel       float64 `json:"fuel"` // Defaults to 1
+	MapChar    string  `json:"mapChar"`
+	Scatter    int     `json:"scatter"`
+}
+
+type Ruleset struct {
+	FireSpawnChance     float64 `json:"fireSpawnChance"`     // Chance per update for fire to randomly spawn on an available tile
+	FireSpreadChance    float64 `json:"fireSpreadChance"`    // Chance per update for each fire to spread to a random adjacent tile
+	FireBurnoutHalflife int     `json:"fireBurnoutHalflife"` // The age at which the chance (but not cumulative chance) for fire to burn out becomes 50%
+	GrowthChanceSeed    float64 `json:"growthChanceSeed"`    // Chance per update for seeds and saplings to grow
+	MaxHitPointsPlayer  int     `json:"maxHitPointsPlayer"`
+	DamageFire          int     `json:"damageFire"`
+}
+
+type RulesetField struct {
+	label    string
+	step     float64 // How much the value changes per step in the menu
+	min      float64
+	max      float64
+	decimals int // Decimals shown in the menu. Fields without decimals are whole numbers.
+}
+
+type RulesetFieldIndex int // Value of the menu item that edits a rule of the custom difficulty
+
+type DifficultyChoice string // Value of the menu item that picks a difficulty
+
+type PlayersOption int // Value of the menu item that switches the multiplayer mode or camera
+
 type TitleMenu struct {
 	cursorState    int
 	pageState      int
 	titleMenuPages map[int]*TitleMenuPage
-	selectedMap    string
+	selectedMap    MapFile
+	settings       *Settings
+	loadGame       bool             // Whether to continue the saved game instead of starting the selected map
+	mouseButtons   tcell.ButtonMask // Buttons held down at the last mouse event, to tell clicks from drags
+	controls       *Controls
+	rebindAction   int                    // Action waiting for a key press on the controls page, or ActionNone
+	status         string                 // Shown below the menu items, e.g. to report errors
+	previews       map[string]*MapPreview // Previews of the maps on the New game page, by file name, read when first highlighted
+	quit           bool                   // Whether the player chose to quit rather than play
+	demo           bool                   // Whether the menu was left idle, for the autopilot to play a demo
+	scroll         int                    // Index of the first item shown, when the page has more items than fit on the screen
+	unhandled      []tcell.Event          // Events read after the menu exited, for the game to handle
 	exit           bool
 }
 
+type StopPolling struct{} // Interrupt event data that stops PollEvents
+
 type TitleMenuItem struct {
 	order int
 	text  string
 	value interface{}
 }
 
+type ControlsPreset string // Value of the menu item that switches to a controls preset
+
+type HighScoreTable int // Value of the menu item that switches between high score tables
+
+type HighScore struct {
+	Map   string
+	Mode  string
+	Score int
+	Ticks int
+	Date  time.Time
+	Seed  int64
+}
+
 type TitleMenuPage struct {
 	name           int
 	content        []string
@@ -89,3 +306,388 @@ type TitleMenuPage struct {
 	cursorState    int
 	titleMenuItems map[int]TitleMenuItem
 }
+
+type Controls struct {
+	preset   string
+	bindings map[int][]string // For each action, the names of the keys bound to it
+	lookup   map[string]int   // For each key name, the action it is bound to
+	fileName string           // Where changes are saved, or empty to not save them
+}
+
+type ControlsFile struct {
+	Preset   string              `json:"preset"`
+	Bindings map[string][]string `json:"bindings"` // Only actions that differ from the preset
+}
+
+type SaveFile struct {
+	Map        string
+	Mode       string
+	Difficulty string
+	Rules      Ruleset
+	Seed       int64
+	Width      int
+	Height     int
+	Ticks      int
+	Player     SavedActor
+	Others     []SavedActor `json:",omitempty"` // Players besides the first in a local multiplayer game
+	Camera     string       `json:",omitempty"`
+	Squirrels  []SavedActor
+	Objects    []SavedObject
+	Trees      []SavedTree
+	Fires      []SavedFire
+	Stats      SavedStats
+}
+
+type SavedActor struct {
+	X            int
+	Y            int
+	VisionRadius int
+	Score        int
+	HitPoints    int
+	HitPointsMax int
+	Wood         int
+	Seeds        int
+}
+
+type SavedObject struct {
+	X          int
+	Y          int
+	Name       string // Tile id, since tile keys may change between versions
+	Collidable bool
+	Flammable  bool
+	Plantable  bool
+}
+
+type SavedTree struct {
+	X     int
+	Y     int
+	State string // Tree state name, since state constants may change between versions
+}
+
+type SavedStats struct {
+	Chopped           map[string]int `json:",omitempty"` // Keyed by tree state name, like saved trees
+	FirebreaksDug     int
+	FiresStarted      int
+	FiresBurntOut     int
+	FiresExtinguished int
+	SquirrelsLost     int
+	ForestCover       []float64
+}
+
+type SavedFire struct {
+	X     int
+	Y     int
+	Age   int
+	Phase int
+	Fuel  float64
+}
+
+type Settings struct {
+	VisionRadius  int          `json:"vision"`
+	Map           string       `json:"map"`      // Map to start right away instead of showing the title menu, if any
+	Seed          int64        `json:"seed"`     // Seed for every new game, or 0 for a new random seed each game
+	TickRate      int          `json:"tickRate"` // Milliseconds between ticks
+	MapsDir       string       `json:"mapsDir"`  // Directory searched for maps before the user and built-in maps, if any
+	Difficulty    string       `json:"difficulty"`
+	Custom        Ruleset      `json:"custom"`  // Rules for the custom difficulty
+	Players       int          `json:"players"` // Number of players sharing the terminal
+	Mode          string       `json:"mode"`    // Multiplayer game mode, co-op or competitive
+	Camera        string       `json:"camera"`  // How the screen is shared between several players
+	Bot           bool         `json:"-"`       // Whether the player is controlled over standard input and output, only set by flag
+	Web           string       `json:"-"`       // Address to serve the game to web browsers on, or empty to play in the terminal, only set by flag
+	SaveFile      string       `json:"-"`       // File games are saved to and loaded from, or empty where saving is turned off
+	SettingsFile  string       `json:"-"`       // File changes to the custom rules are saved to, or empty where saving is turned off
+	Record        string       `json:"-"`       // File every frame drawn is recorded to as an asciinema cast, or empty, only set by flag
+	ScreenshotDir string       `json:"-"`       // Directory screenshots are saved to, or empty where screenshots are turned off
+	recorder      *Recorder    // Opened from Record when the game starts
+	tiles         map[int]Tile // Tile registry, indexed by tile key: the built-in tiles and the user's own
+}
+
+type SimOptions struct {
+	maps      []string // File names of the maps to simulate, or empty for all maps
+	seeds     int      // Number of seeds per map and parameter set
+	firstSeed int64
+	ticks     int
+	script    []int       // Player actions taken in turn, one per tick, or empty for an idle player
+	paramSets []SimParams // Rules to simulate with
+	workers   int
+	format    string // One of the simulation format constants
+	output    string // File to write the results to, or empty for standard output
+	sources   []MapSource
+	tiles     map[int]Tile // Registry the maps are read with
+}
+
+type RuleVariations []string // Values of the --vary flag, each a rule and the values to try
+
+type SimParams struct {
+	name  string // Difficulty, followed by any rules that were changed from it
+	rules Ruleset
+}
+
+type SimRun struct {
+	mapFile MapFile
+	params  SimParams
+	seed    int64
+	tiles   map[int]Tile
+}
+
+type SimResult struct {
+	Map               string    `json:"map"`
+	Params            string    `json:"params"`
+	Seed              int64     `json:"seed"`
+	Rules             Ruleset   `json:"rules"`
+	Ticks             int       `json:"ticks"`        // Ticks the game ran for
+	PlayerBurned      bool      `json:"playerBurned"` // Whether the game ended early with the player burning to death
+	Score             int       `json:"score"`
+	ForestCover       []float64 `json:"forestCover"`  // Sampled every ForestCoverSampleRate ticks, and at the end
+	FiresStarted      int       `json:"firesStarted"` // Number of tiles set on fire, counting fires on the map at the start
+	FiresBurntOut     int       `json:"firesBurntOut"`
+	PeakFires         int       `json:"peakFires"`   // Most tiles on fire at once
+	BurnoutTick       int       `json:"burnoutTick"` // Tick at which the first fire had burnt out completely, or -1 if it never did
+	SquirrelsStart    int       `json:"squirrelsStart"`
+	SquirrelsSurvived int       `json:"squirrelsSurvived"`
+}
+
+type BotObservation struct {
+	Tick      int           `json:"tick"`
+	Width     int           `json:"width"`
+	Height    int           `json:"height"`
+	Player    BotPlayer     `json:"player"`
+	Squirrels []BotPosition `json:"squirrels"` // Squirrels within the player's vision
+	Tiles     []BotTile     `json:"tiles"`     // Everything within the player's vision, row by row. Empty ground is left out.
+	Done      bool          `json:"done"`
+	EndCause  string        `json:"endCause,omitempty"`
+}
+
+type BotPlayer struct {
+	X     int `json:"x"`
+	Y     int `json:"y"`
+	HP    int `json:"hp"`
+	MaxHP int `json:"maxHp"`
+	Score int `json:"score"`
+	Wood  int `json:"wood"`
+	Seeds int `json:"seeds"`
+}
+
+type BotPosition struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type BotTile struct {
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Tile string `json:"tile"`           // Tile id
+	Tree string `json:"tree,omitempty"` // Tree state name, since adult and felled trees share a tile
+}
+
+type BotAction struct {
+	Action string `json:"action"` // One of the bot action constants
+	Dir    string `json:"dir"`    // Direction name, for moving, chopping and digging
+}
+
+type BotError struct {
+	Error string `json:"error"`
+}
+
+type ServeOptions struct {
+	address  string // Address to listen on, e.g. ":7777"
+	tickRate int    // Milliseconds between ticks
+	settings Settings
+}
+
+type JoinOptions struct {
+	address  string // Address of the server, e.g. "localhost:7777" or "unix:/tmp/skogshuggare.sock"
+	name     string // Name shown to the other players, or empty for one given by the server
+	spectate bool   // Whether to watch the game instead of playing
+	record   string // File the game is recorded to as an asciinema cast, or empty
+}
+
+type Server struct {
+	settings   Settings
+	mapFile    MapFile
+	seed       int64                  // Seed of the current round, each round counting up from the first
+	start      Coordinate             // Where the map places the player, around which other players spawn
+	game       Game                   // Owned by the goroutine running the server, which connections send their commands to as events
+	players    []*RemotePlayer        // In the order of game.Players()
+	spectators []*ServerClient        // Clients watching without a player
+	sent       map[Coordinate]NetTile // World as of the last state sent to the clients, or nil to send all of it next
+	messages   []string               // Messages for the clients with the next state
+	nextId     int
+	events     chan ServerEvent
+	done       chan struct{}
+}
+
+type RemotePlayer struct {
+	id      int
+	name    string
+	token   string        // Secret the client rejoins with after losing the connection
+	actor   *Actor        // Player in the server's game
+	client  *ServerClient // Connection of the player, or nil while disconnected
+	actions []int         // Actions waiting to be performed, one per tick
+}
+
+type ServerClient struct {
+	conn    net.Conn
+	send    chan NetMessage // Messages for the connection's writer, closed once the client is dropped
+	dropped bool
+}
+
+type ServerEvent struct {
+	client  *ServerClient
+	command NetCommand
+	err     error // Why the command couldn't be read, if it couldn't
+	closed  bool  // Whether the connection was closed, which ends the client's events
+}
+
+type SshOptions struct {
+	address        string // Address to listen on, e.g. ":2222"
+	hostKey        string // File of the server's private key
+	authorizedKeys string // File of the public keys allowed to connect, or empty to let anyone connect
+	shared         bool   // Whether every session joins one world, instead of playing a game of its own
+	serve          ServeOptions
+}
+
+type SshServer struct {
+	config   *ssh.ServerConfig
+	settings Settings // Settings each session starts out with
+	shared   string   // Address of the game server every session joins, or empty for a game of its own in each session
+}
+
+type SessionTty struct {
+	channel  ssh.Channel
+	input    chan []byte   // Data read from the channel
+	pending  []byte        // Data read from input that didn't fit in the last Read
+	stop     chan struct{} // Closed by Drain to wake up a blocked Read
+	closed   chan struct{} // Closed by Close to stop reading from the channel
+	ended    chan struct{} // Closed by End once the session can't be read from or written to anymore
+	endOnce  sync.Once
+	mutex    sync.Mutex // Guards the fields below, which the session's requests change while the game runs
+	width    int
+	height   int
+	onResize func()
+}
+
+type PtyRequest struct {
+	Term    string
+	Columns uint32
+	Rows    uint32
+	Width   uint32 // In pixels, unused
+	Height  uint32
+	Modes   string
+}
+
+type WindowChange struct {
+	Columns uint32
+	Rows    uint32
+	Width   uint32
+	Height  uint32
+}
+
+type WebServer struct {
+	game      string // Address of the game server each page joins
+	config    WebConfig
+	localOnly bool // Whether requests must name the loopback host, so that other sites can't reach the server through their own names
+}
+
+type WebConfig struct {
+	Tiles    map[string]WebTile   `json:"tiles"`   // By tile id
+	Players  []string             `json:"players"` // Tile id of each player in turn, the last one for any further players
+	Squirrel string               `json:"squirrel"`
+	Leaves   string               `json:"leaves"` // Tile drawn along the row above trees with a canopy
+	Canopy   string               `json:"canopy"` // Tree state of the trees with a canopy
+	Keys     map[string]BotAction `json:"keys"`   // Action of each key, by the browser's name for the key
+}
+
+type WebTile struct {
+	Glyph      string `json:"glyph"`
+	Foreground string `json:"fg,omitempty"` // CSS colour, or empty for the page's
+	Background string `json:"bg,omitempty"`
+	AboveActor bool   `json:"aboveActor"`
+}
+
+type Client struct {
+	address  string
+	name     string
+	id       int    // Id of the client's player, once joined
+	token    string // Secret to rejoin as the same player with
+	spectate bool   // Whether the client watches instead of playing, with no player of its own
+	conn     net.Conn
+	encoder  *json.Encoder
+	decoder  *json.Decoder // Kept between reads, since it reads ahead
+	game     Game          // Mirror of the server's game, drawn with the same code as local games
+}
+
+type ConnectionLost struct{ err error } // Interrupt event data for a client whose connection to the server was lost
+
+type NetCommand struct {
+	Type   string `json:"type"`             // One of the net command constants
+	Name   string `json:"name,omitempty"`   // Player name, for joining
+	Token  string `json:"token,omitempty"`  // Token of an earlier join, for joining as the same player again
+	Action string `json:"action,omitempty"` // Bot action name, for actions
+	Dir    string `json:"dir,omitempty"`
+}
+
+type NetMessage struct {
+	Id    int       `json:"id,omitempty"`    // Id of the client's player, once joined
+	Token string    `json:"token,omitempty"` // Token for joining as the same player again, once joined
+	State *NetState `json:"state,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+type NetState struct {
+	Tick      int           `json:"tick"`
+	Width     int           `json:"width"`
+	Height    int           `json:"height"`
+	Full      bool          `json:"full"`  // Whether the tiles are the whole world, instead of what changed since the last state
+	Tiles     []NetTile     `json:"tiles"` // Row by row
+	Players   []NetPlayer   `json:"players"`
+	Squirrels []NetSquirrel `json:"squirrels"`
+	Messages  []string      `json:"messages,omitempty"`
+	Done      bool          `json:"done"` // Whether the round is over, after which the next one starts with a full state
+}
+
+type NetTile struct {
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Tile string `json:"tile"`           // Tile id, or empty for ground that something was removed from
+	Tree string `json:"tree,omitempty"` // Tree state name, since adult and felled trees share a tile
+}
+
+type NetPlayer struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Vision    int    `json:"vision"`
+	HP        int    `json:"hp"`
+	MaxHP     int    `json:"maxHp"`
+	Score     int    `json:"score"`
+	Wood      int    `json:"wood"`
+	Seeds     int    `json:"seeds"`
+	Connected bool   `json:"connected"`
+}
+
+type NetSquirrel struct {
+	Id int `json:"id"` // Stays the same for the squirrel until the round ends
+	X  int `json:"x"`
+	Y  int `json:"y"`
+}
+
+type Recorder struct {
+	output io.Writer
+	start  time.Time // Time of the first frame, which every event is timed from
+	width  int
+	height int
+	frame  []Cell // Frame recorded last, which the next one is compared to
+	err    error  // First error writing the recording, returned on closing
+}
+
+type CastHeader struct {
+	Version       int     `json:"version"`
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	Timestamp     int64   `json:"timestamp"`       // Unix time the recording started
+	IdleTimeLimit float64 `json:"idle_time_limit"` // Longest pause when played back, in seconds
+	Title         string  `json:"title"`
+}
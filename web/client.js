@@ -0,0 +1,246 @@
+// Browser client for skogshuggare --web. It speaks the protocol of skogshuggare join over a WebSocket, and draws
+// the world on a canvas with the same glyphs and colours as the terminal.
+"use strict";
+
+const params = new URLSearchParams(location.search);
+const spectating = params.has("spectate"); // Watch without playing, e.g. for a demo embedded in another page
+const canvas = document.getElementById("world");
+const hud = document.getElementById("hud");
+const context = canvas.getContext("2d");
+const fontSize = 16;
+const lineHeight = Math.ceil(fontSize * 1.2);
+const messageLimit = 5;
+const reconnectAttempts = 5;
+const reconnectDelay = 1000;
+
+let config = null;
+let world = { width: 0, height: 0, tiles: new Map() }; // Tiles by "x,y", as the server sent them
+let state = { players: [], squirrels: [], tick: 0 };
+let id = 0; // Our player's id, once joined
+let token = sessionStorage.getItem("token") || "";
+let messages = [];
+let follow = 0; // Index among players and squirrels of the actor a spectator follows
+let socket = null;
+let attempts = 0;
+let left = false;
+
+function connect() {
+  const scheme = location.protocol === "https:" ? "wss://" : "ws://";
+  socket = new WebSocket(scheme + location.host + "/play");
+  socket.onopen = () => {
+    attempts = 0;
+    if (spectating) {
+      send({ type: "spectate" });
+    } else {
+      send({ type: "join", name: params.get("name") || "", token: token });
+    }
+  };
+  socket.onmessage = (event) => receive(JSON.parse(event.data));
+  socket.onclose = () => {
+    if (left) {
+      return;
+    }
+    if (attempts >= reconnectAttempts) {
+      addMessage("Lost connection. Reload the page to try again.");
+      return;
+    }
+    attempts++;
+    addMessage("Reconnecting (" + attempts + "/" + reconnectAttempts + ")");
+    setTimeout(connect, reconnectDelay);
+  };
+}
+
+function send(command) {
+  if (socket && socket.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify(command));
+  }
+}
+
+function receive(message) {
+  if (message.id) {
+    id = message.id;
+    token = message.token;
+    sessionStorage.setItem("token", token);
+  }
+  if (message.error) {
+    addMessage("Server: " + message.error);
+  }
+  if (message.state) {
+    applyState(message.state);
+  }
+  draw();
+}
+
+// Changes the tiles the state has, and replaces everything else.
+function applyState(next) {
+  if (next.full) {
+    world = { width: next.width, height: next.height, tiles: new Map() };
+  }
+  for (const tile of next.tiles) {
+    const key = tile.x + "," + tile.y;
+    if (tile.tile === "") {
+      world.tiles.delete(key);
+    } else {
+      world.tiles.set(key, tile);
+    }
+  }
+  state = { players: next.players || [], squirrels: next.squirrels || [], tick: next.tick };
+  for (const text of next.messages || []) {
+    addMessage(text);
+  }
+}
+
+function addMessage(text) {
+  messages.push(text);
+  messages = messages.slice(-messageLimit);
+  draw();
+}
+
+// Returns the actor the camera follows, and whether only its vision is shown. Players see what their player sees,
+// spectators see everything around whoever they follow.
+function viewer() {
+  if (!spectating) {
+    return [state.players.find((player) => player.id === id), true];
+  }
+  const actors = state.players.concat(state.squirrels.map((squirrel) => Object.assign({ name: "Squirrel " + (squirrel.id + 1) }, squirrel)));
+  if (actors.length === 0) {
+    return [{ name: "nobody", x: Math.floor(world.width / 2), y: Math.floor(world.height / 2) }, false];
+  }
+  follow = ((follow % actors.length) + actors.length) % actors.length;
+  return [actors[follow], false];
+}
+
+// Returns the glyph the terminal draws along the edges of the world, or null inside it.
+function borderGlyph(x, y) {
+  const left = x === 0, right = x === world.width - 1, top = y === 0, bottom = y === world.height - 1;
+  if (top && left) return "┌";
+  if (top && right) return "┐";
+  if (bottom && right) return "┘";
+  if (bottom && left) return "└";
+  if (top || bottom) return "─";
+  if (left || right) return "│";
+  return null;
+}
+
+// Returns the cells to draw, by "x,y": actors over the ground, and tiles drawn above actors, like leaves, over them.
+function cells() {
+  const cells = new Map();
+  const above = [];
+  for (const [key, tile] of world.tiles) {
+    const definition = config.tiles[tile.tile];
+    cells.set(key, definition);
+    if (definition.aboveActor) {
+      above.push([key, definition]);
+    }
+    if (tile.tree === config.canopy) {
+      for (const dx of [-1, 0, 1]) {
+        above.push([(tile.x + dx) + "," + (tile.y - 1), config.tiles[config.leaves]]);
+      }
+    }
+  }
+  state.players.forEach((player, i) => {
+    if (player.hp <= 0 && (state.players.length > 1 || spectating)) {
+      return; // Burned players leave a multiplayer game
+    }
+    const tile = config.players[Math.min(i, config.players.length - 1)];
+    cells.set(player.x + "," + player.y, config.tiles[tile]);
+  });
+  for (const squirrel of state.squirrels) {
+    cells.set(squirrel.x + "," + squirrel.y, config.tiles[config.squirrel]);
+  }
+  for (const [key, definition] of above) {
+    cells.set(key, definition);
+  }
+  return cells;
+}
+
+function draw() {
+  if (!config) {
+    return;
+  }
+  const ratio = window.devicePixelRatio || 1;
+  canvas.width = canvas.clientWidth * ratio;
+  canvas.height = canvas.clientHeight * ratio;
+  context.setTransform(ratio, 0, 0, ratio, 0, 0);
+  context.fillStyle = "#000";
+  context.fillRect(0, 0, canvas.clientWidth, canvas.clientHeight);
+  context.font = fontSize + "px monospace";
+  context.textBaseline = "top";
+  drawHud();
+
+  const [center, limited] = viewer();
+  if (!center) {
+    return;
+  }
+  const cellWidth = Math.ceil(context.measureText("█").width);
+  const columns = Math.floor(canvas.clientWidth / cellWidth);
+  const rows = Math.floor(canvas.clientHeight / lineHeight);
+  const content = cells();
+  for (let row = 0; row < rows; row++) {
+    for (let column = 0; column < columns; column++) {
+      const x = center.x - Math.floor(columns / 2) + column;
+      const y = center.y - Math.floor(rows / 2) + row;
+      if (x < 0 || y < 0 || x >= world.width || y >= world.height) {
+        continue;
+      }
+      if (limited && (Math.abs(x - center.x) > center.vision || Math.abs(y - center.y) > center.vision)) {
+        continue;
+      }
+      let glyph = borderGlyph(x, y);
+      let definition = { fg: "", bg: "" };
+      if (glyph === null) {
+        definition = content.get(x + "," + y);
+        if (!definition) {
+          continue;
+        }
+        glyph = definition.glyph;
+      }
+      if (definition.bg) {
+        context.fillStyle = definition.bg;
+        context.fillRect(column * cellWidth, row * lineHeight, cellWidth, lineHeight);
+      }
+      context.fillStyle = definition.fg || "#ccc";
+      context.fillText(glyph, column * cellWidth, row * lineHeight);
+    }
+  }
+}
+
+function drawHud() {
+  const lines = [];
+  if (spectating) {
+    lines.push("Watching: " + viewer()[0].name);
+  }
+  for (const player of state.players) {
+    const name = player.id === id ? player.name + " (you)" : player.name;
+    lines.push(name, "  HP: " + player.hp + "/" + player.maxHp, "  Score: " + player.score, "  Wood: " + player.wood + "  Seeds: " + player.seeds);
+  }
+  lines.push("Squirrels: " + state.squirrels.length, "Time: " + state.tick, "");
+  hud.textContent = lines.concat(messages).join("\n");
+}
+
+// Sends the action bound to the key. Escape leaves the game, and spectators follow the next actor with Tab.
+document.addEventListener("keydown", (event) => {
+  if (event.key === "Escape") {
+    left = true;
+    send({ type: "leave" });
+    socket.close();
+    sessionStorage.removeItem("token");
+    addMessage("Left the game. Reload the page to join again.");
+  } else if (spectating && event.key === "Tab") {
+    follow += event.shiftKey ? -1 : 1;
+    draw();
+  } else if (!spectating && config && config.keys[event.key]) {
+    send(Object.assign({ type: "action" }, config.keys[event.key]));
+  } else {
+    return;
+  }
+  event.preventDefault();
+});
+window.addEventListener("resize", draw);
+
+fetch("config")
+  .then((response) => response.json())
+  .then((received) => {
+    config = received;
+    connect();
+  });